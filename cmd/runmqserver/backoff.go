@@ -0,0 +1,101 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// backoffPolicy controls how a network sink retries after a failure.  The delay starts at initialDelay,
+// and doubles after each consecutive failure, up to maxDelay.
+type backoffPolicy struct {
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	// maxAttempts is the number of times to try sending each message or batch, before it is dropped
+	maxAttempts int
+}
+
+var defaultBackoffPolicy = backoffPolicy{
+	initialDelay: 500 * time.Millisecond,
+	maxDelay:     30 * time.Second,
+	maxAttempts:  3,
+}
+
+// sinkBackoff is the retry policy used by all network sinks
+var sinkBackoff = defaultBackoffPolicy
+
+// backoffSleep waits between attempts.  It is a variable to allow it to be replaced during testing.
+var backoffSleep = time.Sleep
+
+// delay returns the time to wait after the given number of consecutive failures
+func (b backoffPolicy) delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	d := b.initialDelay
+	for i := 1; i < failures; i++ {
+		d *= 2
+		if d >= b.maxDelay {
+			return b.maxDelay
+		}
+	}
+	if d > b.maxDelay {
+		return b.maxDelay
+	}
+	return d
+}
+
+// retry calls op until it succeeds, or maxAttempts have been made, waiting between attempts.  The error
+// from the last attempt is returned.
+func (b backoffPolicy) retry(op func(attempt int) error) error {
+	var err error
+	for attempt := 1; attempt <= b.maxAttempts; attempt++ {
+		err = op(attempt)
+		if err == nil {
+			return nil
+		}
+		if attempt < b.maxAttempts {
+			backoffSleep(b.delay(attempt))
+		}
+	}
+	return err
+}
+
+// configureSinkBackoff reads the retry policy for network sinks from MQ_LOGGING_SINK_RETRY_INITIAL_DELAY,
+// MQ_LOGGING_SINK_RETRY_MAX_DELAY and MQ_LOGGING_SINK_RETRY_MAX_ATTEMPTS
+func configureSinkBackoff() error {
+	sinkBackoff = defaultBackoffPolicy
+	var b backoffPolicy
+	var err error
+	b.initialDelay, err = getDurationEnv("MQ_LOGGING_SINK_RETRY_INITIAL_DELAY", defaultBackoffPolicy.initialDelay)
+	if err != nil {
+		return err
+	}
+	b.maxDelay, err = getDurationEnv("MQ_LOGGING_SINK_RETRY_MAX_DELAY", defaultBackoffPolicy.maxDelay)
+	if err != nil {
+		return err
+	}
+	if b.maxDelay < b.initialDelay {
+		return fmt.Errorf("invalid value for MQ_LOGGING_SINK_RETRY_MAX_DELAY: %v is less than the initial delay of %v", b.maxDelay, b.initialDelay)
+	}
+	b.maxAttempts, err = getPositiveIntEnv("MQ_LOGGING_SINK_RETRY_MAX_ATTEMPTS", defaultBackoffPolicy.maxAttempts)
+	if err != nil {
+		return err
+	}
+	sinkBackoff = b
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := backoffPolicy{initialDelay: time.Second, maxDelay: 10 * time.Second, maxAttempts: 3}
+	expected := []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for failures, e := range expected {
+		if d := b.delay(failures); d != e {
+			t.Errorf("Expected delay %v after %v failures; got %v", e, failures, d)
+		}
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	oldSleep := backoffSleep
+	defer func() { backoffSleep = oldSleep }()
+	var slept []time.Duration
+	backoffSleep = func(d time.Duration) { slept = append(slept, d) }
+	b := backoffPolicy{initialDelay: time.Second, maxDelay: 3 * time.Second, maxAttempts: 4}
+
+	// Gives up after the maximum number of attempts, returning the last error
+	attempts := 0
+	err := b.retry(func(attempt int) error {
+		attempts++
+		return errors.New("failed")
+	})
+	if err == nil || attempts != 4 {
+		t.Errorf("Expected an error after 4 attempts; got %v after %v", err, attempts)
+	}
+	expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if !reflect.DeepEqual(slept, expected) {
+		t.Errorf("Expected delays %v; got %v", expected, slept)
+	}
+
+	// Stops as soon as an attempt succeeds
+	slept = nil
+	attempts = 0
+	err = b.retry(func(attempt int) error {
+		attempts++
+		if attempt < 2 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 || len(slept) != 1 {
+		t.Errorf("Expected success on the second attempt; got %v after %v attempts, with delays %v", err, attempts, slept)
+	}
+}
+
+func TestConfigureSinkBackoff(t *testing.T) {
+	defer func() { sinkBackoff = defaultBackoffPolicy }()
+	t.Setenv("MQ_LOGGING_SINK_RETRY_INITIAL_DELAY", "100ms")
+	t.Setenv("MQ_LOGGING_SINK_RETRY_MAX_DELAY", "2s")
+	t.Setenv("MQ_LOGGING_SINK_RETRY_MAX_ATTEMPTS", "5")
+	err := configureSinkBackoff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := backoffPolicy{initialDelay: 100 * time.Millisecond, maxDelay: 2 * time.Second, maxAttempts: 5}
+	if sinkBackoff != expected {
+		t.Errorf("Expected %+v; got %+v", expected, sinkBackoff)
+	}
+	t.Setenv("MQ_LOGGING_SINK_RETRY_MAX_DELAY", "10ms")
+	err = configureSinkBackoff()
+	if err == nil {
+		t.Error("Expected an error for a maximum delay less than the initial delay")
+	}
+}
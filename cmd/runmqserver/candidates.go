@@ -0,0 +1,134 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// candidatePollInterval is how often a better candidate path is looked for, while mirroring a log
+// from one of a list of candidate paths
+var candidatePollInterval = 5 * time.Second
+
+// getCandidatePaths returns the paths to try for a log source, in order of preference, from
+// MQ_LOGGING_<SOURCE>_CANDIDATE_PATHS (for example MQ_LOGGING_QMGR_CANDIDATE_PATHS).  The default path
+// is always the last candidate, if it isn't listed.
+func getCandidatePaths(source string, defaultPath string) []string {
+	paths := make([]string, 0)
+	found := false
+	for _, p := range strings.Split(os.Getenv("MQ_LOGGING_"+strings.ToUpper(source)+"_CANDIDATE_PATHS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+			found = found || p == defaultPath
+		}
+	}
+	if !found {
+		paths = append(paths, defaultPath)
+	}
+	return paths
+}
+
+// isReadableFile returns true if a path is a file which can be opened for reading
+func isReadableFile(path string) bool {
+	// #nosec G304 - no harm, we open readonly and close it straight away
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// selectCandidate returns the index of the first of the paths which is a readable file, looking no
+// further than limit.  If none are readable, limit is returned.
+func selectCandidate(paths []string, limit int) int {
+	for i := 0; i < limit && i < len(paths); i++ {
+		if isReadableFile(paths[i]) {
+			return i
+		}
+	}
+	return limit
+}
+
+// mirrorLogCandidates starts mirroring a log, from the first of the candidate paths for the source which
+// can be read.  If none can be read, the default path is used, and mirrorLog waits for it.  While the
+// log is mirrored, the paths before the one in use are checked regularly, and if one can now be read,
+// mirroring moves to it.  A file which didn't exist when mirroring started is read from the start.
+func mirrorLogCandidates(ctx context.Context, wg *sync.WaitGroup, source string, defaultPath string, fromStart bool, mf mirrorFunc, isQMLog bool) (chan error, error) {
+	paths := getCandidatePaths(source, defaultPath)
+	if len(paths) == 1 {
+		return mirrorLog(ctx, wg, source, defaultPath, fromStart, mf, isQMLog)
+	}
+	existed := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		_, err := os.Stat(p)
+		existed[p] = err == nil
+	}
+	current := selectCandidate(paths, len(paths)-1)
+	log.Debugf("Mirroring %v log from candidate path %v", source, paths[current])
+	var innerWG sync.WaitGroup
+	innerCtx, cancel := context.WithCancel(ctx)
+	innerErrors, err := mirrorLog(innerCtx, &innerWG, source, paths[current], fromStart, mf, isQMLog)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	errorChannel := make(chan error, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			cancel()
+			innerWG.Wait()
+		}()
+		ticker := time.NewTicker(candidatePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-innerErrors:
+				errorChannel <- err
+				return
+			case <-ticker.C:
+				better := selectCandidate(paths, current)
+				if better == current {
+					continue
+				}
+				// Stop mirroring the current path, and wait for the last messages to be read from it
+				cancel()
+				innerWG.Wait()
+				previous := paths[current]
+				current = better
+				innerCtx, cancel = context.WithCancel(ctx)
+				innerErrors, err = mirrorLog(innerCtx, &innerWG, source, paths[current], !existed[paths[current]], mf, isQMLog)
+				if err != nil {
+					log.Error(err)
+					errorChannel <- err
+					return
+				}
+				emitEvent("INFO", "log_path_changed", fmt.Sprintf("Mirroring %v log from %v instead of %v", source, paths[current], previous), map[string]interface{}{"ibm_path": paths[current], "ibm_previousPath": previous})
+			}
+		}
+	}()
+	return errorChannel, nil
+}
@@ -0,0 +1,115 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetCandidatePaths(t *testing.T) {
+	var tests = []struct {
+		value    string
+		expected []string
+	}{
+		{"", []string{"/default.json"}},
+		{"/ro/a.json, /rw/b.json", []string{"/ro/a.json", "/rw/b.json", "/default.json"}},
+		{"/default.json,/rw/b.json", []string{"/default.json", "/rw/b.json"}},
+	}
+	for _, table := range tests {
+		t.Run(table.value, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_QMGR_CANDIDATE_PATHS", table.value)
+			paths := getCandidatePaths("qmgr", "/default.json")
+			if !reflect.DeepEqual(paths, table.expected) {
+				t.Errorf("Expected %v; got %v", table.expected, paths)
+			}
+		})
+	}
+}
+
+func TestMirrorLogCandidatesFallback(t *testing.T) {
+	oldInterval := candidatePollInterval
+	candidatePollInterval = 100 * time.Millisecond
+	t.Cleanup(func() { candidatePollInterval = oldInterval })
+	dir := t.TempDir()
+	primary := filepath.Join(dir, "replica", "AMQERR01.json")
+	fallback := filepath.Join(dir, "AMQERR01.json")
+	err := os.WriteFile(fallback, []byte("{\"message\":\"A\"}\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MQ_LOGGING_QMGR_CANDIDATE_PATHS", primary+","+fallback)
+
+	var mutex sync.Mutex
+	var msgs []string
+	received := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string{}, msgs...)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	_, err = mirrorLogCandidates(ctx, &wg, "qmgr", fallback, true, func(msg string, isQMLog bool) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		msgs = append(msgs, msg)
+		return true
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor := func(n int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for len(received()) < n && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	// The preferred path doesn't exist yet, so the fallback is mirrored
+	waitFor(1)
+
+	// When the preferred path appears, mirroring moves to it, and reads it from the start
+	err = os.MkdirAll(filepath.Dir(primary), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(primary, []byte("{\"message\":\"B\"}\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(2)
+	// Messages written to the fallback are no longer mirrored
+	f, err := os.OpenFile(fallback, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteString("{\"message\":\"C\"}\n")
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	cancel()
+	wg.Wait()
+	expected := []string{"{\"message\":\"A\"}", "{\"message\":\"B\"}"}
+	if got := received(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v; got %v", expected, got)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ansiColors are the ANSI escape codes for the color names which can be used in MQ_LOGGING_COLORS
+var ansiColors = map[string]string{
+	"black":         "\x1b[30m",
+	"red":           "\x1b[31m",
+	"green":         "\x1b[32m",
+	"yellow":        "\x1b[33m",
+	"blue":          "\x1b[34m",
+	"magenta":       "\x1b[35m",
+	"cyan":          "\x1b[36m",
+	"white":         "\x1b[37m",
+	"brightblack":   "\x1b[90m",
+	"brightred":     "\x1b[91m",
+	"brightgreen":   "\x1b[92m",
+	"brightyellow":  "\x1b[93m",
+	"brightblue":    "\x1b[94m",
+	"brightmagenta": "\x1b[95m",
+	"brightcyan":    "\x1b[96m",
+	"brightwhite":   "\x1b[97m",
+}
+
+// ansiReset is the ANSI escape code which ends a color
+const ansiReset = "\x1b[0m"
+
+// defaultColors is the color of each severity, unless overridden by MQ_LOGGING_COLORS
+var defaultColors = map[logLevel]string{
+	levelFatal:   "brightred",
+	levelError:   "red",
+	levelWarning: "yellow",
+}
+
+// consoleColors holds the colors used for console output, or is nil if colors are disabled
+var consoleColors *colorMap
+
+// colorMap is the ANSI escape code to use for each severity, and for specific message IDs
+type colorMap struct {
+	severities map[logLevel]string
+	messageIDs map[string]string
+}
+
+// parseColorMap parses a comma-separated list of key=color pairs, where the key is a severity (for
+// example "error") or a message ID (for example "AMQ9999E").  The pairs override the default colors.
+func parseColorMap(value string) (*colorMap, error) {
+	c := &colorMap{
+		severities: make(map[logLevel]string),
+		messageIDs: make(map[string]string),
+	}
+	for level, name := range defaultColors {
+		c.severities[level] = ansiColors[name]
+	}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid value for MQ_LOGGING_COLORS: %v", pair)
+		}
+		key := strings.TrimSpace(parts[0])
+		code, ok := ansiColors[strings.ToLower(strings.TrimSpace(parts[1]))]
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid value for MQ_LOGGING_COLORS: %v", pair)
+		}
+		if level, err := parseLogLevel(key); err == nil {
+			c.severities[level] = code
+		} else {
+			c.messageIDs[strings.ToUpper(key)] = code
+		}
+	}
+	return c, nil
+}
+
+// colorize wraps a log line in the escape codes for its color, keeping the trailing new-line outside them.
+// A color for the message ID takes priority over a color for the severity.
+func (c *colorMap) colorize(obj map[string]interface{}, line string) string {
+	if obj == nil {
+		return line
+	}
+	r := newMQLogRecord(obj)
+	code, ok := c.messageIDs[r.MessageID()]
+	if !ok {
+		code, ok = c.severities[r.Severity()]
+	}
+	if !ok {
+		return line
+	}
+	return code + strings.TrimSuffix(line, "\n") + ansiReset + "\n"
+}
+
+// isTerminal returns true if a file is a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// getConsoleColors returns the colors to use for console output in basic format, or nil if colors are
+// disabled.  MQ_LOGGING_COLOR is "auto" to use colors only if the console is a terminal, "always" to
+// always use colors, or "never" (the default).
+func getConsoleColors(stream *os.File) (*colorMap, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_COLOR"))); mode {
+	case "", "never":
+		return nil, nil
+	case "auto":
+		if !isTerminal(stream) {
+			return nil, nil
+		}
+	case "always":
+	default:
+		return nil, fmt.Errorf("invalid value for MQ_LOGGING_COLOR: %v", mode)
+	}
+	return parseColorMap(os.Getenv("MQ_LOGGING_COLORS"))
+}
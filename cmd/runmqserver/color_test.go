@@ -0,0 +1,95 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+var consoleColorTests = []struct {
+	name     string
+	msg      string
+	expected string
+}{
+	{"Error", "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"ibm_messageId\":\"AMQ9209E\",\"message\":\"Closed\"}", "\x1b[31m2024-01-01T10:00:00.000Z Closed\x1b[0m\n"},
+	{"WarningOverridden", "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"loglevel\":\"WARNING\",\"message\":\"Careful\"}", "\x1b[36m2024-01-01T10:00:00.000Z Careful\x1b[0m\n"},
+	{"Critical", "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"ibm_messageId\":\"AMQ7017S\",\"message\":\"Log not available\"}", "\x1b[91m2024-01-01T10:00:00.000Z Log not available\x1b[0m\n"},
+	{"Info", "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"loglevel\":\"INFO\",\"message\":\"Hello\"}", "2024-01-01T10:00:00.000Z Hello\n"},
+	{"NotJSON", "Plain text", "Plain text\n"},
+}
+
+func TestConsoleColors(t *testing.T) {
+	oldLog, oldColors := log, consoleColors
+	defer func() { log, consoleColors = oldLog, oldColors }()
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "basic")
+	t.Setenv("MQ_LOGGING_COLOR", "always")
+	t.Setenv("MQ_LOGGING_COLORS", "warning=cyan, amq7017s=brightred")
+	mf, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range consoleColorTests {
+		t.Run(table.name, func(t *testing.T) {
+			buf := captureConsole(t)
+			mf(table.msg, false)
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestConsoleColorsNotBasic(t *testing.T) {
+	oldLog, oldColors := log, consoleColors
+	defer func() { log, consoleColors = oldLog, oldColors }()
+	t.Setenv("MQ_LOGGING_COLOR", "always")
+	for _, format := range []string{"json", "syslog", "ecs", "gelf"} {
+		t.Run(format, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", format)
+			mf, err := configureLogger("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := captureConsole(t)
+			mf(consoleColorTests[0].msg, false)
+			mf("Plain text", false)
+			if strings.Contains(buf.String(), "\x1b[") {
+				t.Errorf("Expected no colors in %v format; got %q", format, buf.String())
+			}
+		})
+	}
+}
+
+func TestConsoleColorsOnlyOnTerminal(t *testing.T) {
+	t.Setenv("MQ_LOGGING_COLOR", "auto")
+	f, err := os.CreateTemp(t.TempDir(), "console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c, err := getConsoleColors(f)
+	if err != nil || c != nil {
+		t.Errorf("Expected no colors when not writing to a terminal; got %v, %v", c, err)
+	}
+	t.Setenv("MQ_LOGGING_COLORS", "error=purple")
+	t.Setenv("MQ_LOGGING_COLOR", "always")
+	_, err = getConsoleColors(f)
+	if err == nil {
+		t.Error("Expected an error for an invalid color")
+	}
+}
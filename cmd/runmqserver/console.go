@@ -0,0 +1,205 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// consoleFlushInterval is how often buffered console output is flushed
+const consoleFlushInterval = time.Second
+
+// console is where mirrored log lines are written
+var console = newConsoleWriter(os.Stdout, 0, nil)
+
+// consoleWriter writes mirrored log lines to the console, optionally buffering them
+type consoleWriter struct {
+	mutex sync.Mutex
+	out   io.Writer
+	// buf is used to buffer output, or nil if output is not buffered
+	buf *bufio.Writer
+	// flushIDs holds message IDs which cause buffered output to be flushed immediately
+	flushIDs map[string]bool
+	// crlf is true if lines should be terminated with a carriage return and line feed
+	crlf bool
+	// done stops the goroutine which periodically flushes buffered output
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// newConsoleWriter creates a new console writer.  If bufferSize is zero, output is not buffered.
+func newConsoleWriter(out io.Writer, bufferSize int, flushIDs map[string]bool) *consoleWriter {
+	c := &consoleWriter{
+		out:      out,
+		flushIDs: flushIDs,
+		done:     make(chan struct{}),
+	}
+	if bufferSize > 0 {
+		c.buf = bufio.NewWriterSize(out, bufferSize)
+	}
+	return c
+}
+
+// WriteLine writes a log line, which should include its trailing new-line.  If the message ID is
+// one which requires immediate output, any buffered output is flushed.  A line may hold several
+// new-line terminated lines, which are all given the configured terminator.
+func (c *consoleWriter) WriteLine(line string, messageID string) {
+	if c.crlf {
+		line = strings.ReplaceAll(line, "\n", "\r\n")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.buf == nil {
+		// #nosec G104 - there's nowhere to report a failure to write to the console
+		io.WriteString(c.out, line)
+		return
+	}
+	// #nosec G104 - errors are reported when flushing
+	c.buf.WriteString(line)
+	if messageID != "" && c.flushIDs[messageID] {
+		c.flushLocked()
+	}
+}
+
+// Flush writes any buffered output
+func (c *consoleWriter) Flush() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.flushLocked()
+}
+
+// startFlushing starts a goroutine which flushes buffered output at an interval, until the writer is closed
+func (c *consoleWriter) startFlushing(interval time.Duration) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.Flush()
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops flushing buffered output periodically, and writes anything which is buffered.  Lines
+// written after the writer is closed are not buffered.
+func (c *consoleWriter) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+	})
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.flushLocked()
+	c.buf = nil
+}
+
+func (c *consoleWriter) flushLocked() {
+	if c.buf == nil {
+		return
+	}
+	err := c.buf.Flush()
+	if err != nil {
+		log.Debugf("Unable to flush console output: %v", err)
+	}
+}
+
+// getFlushIDs returns the set of message IDs in MQ_LOGGING_FLUSH_ON_ID
+func getFlushIDs() map[string]bool {
+	ids := make(map[string]bool)
+	for _, id := range strings.Split(strings.ToUpper(os.Getenv("MQ_LOGGING_FLUSH_ON_ID")), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// getNewlineCRLF returns true if MQ_LOGGING_NEWLINE asks for lines to end with CRLF
+func getNewlineCRLF() (bool, error) {
+	switch newline := strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_NEWLINE"))); newline {
+	case "", "lf":
+		return false, nil
+	case "crlf":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid value for MQ_LOGGING_NEWLINE: %v", newline)
+	}
+}
+
+// getConsoleStream returns the stream which mirrored log lines are written to, as set by MQ_LOGGING_CONSOLE_STREAM
+func getConsoleStream() (*os.File, error) {
+	switch stream := strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_CONSOLE_STREAM"))); stream {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	default:
+		return nil, fmt.Errorf("invalid value for MQ_LOGGING_CONSOLE_STREAM: %v", stream)
+	}
+}
+
+// configureConsole sets up the console writer, with buffering if MQ_LOGGING_CONSOLE_BUFFER_SIZE is set,
+// writing to the stream selected by MQ_LOGGING_CONSOLE_STREAM.  Any severities sent to their own file
+// descriptor by MQ_LOGGING_SEVERITY_FDS are set up too.
+func configureConsole() error {
+	bufferSize, err := getPositiveIntEnv("MQ_LOGGING_CONSOLE_BUFFER_SIZE", 0)
+	if err != nil {
+		return err
+	}
+	crlf, err := getNewlineCRLF()
+	if err != nil {
+		return err
+	}
+	stream, err := getConsoleStream()
+	if err != nil {
+		return err
+	}
+	consoleColors, err = getConsoleColors(stream)
+	if err != nil {
+		return err
+	}
+	streams, err := getSeverityStreams()
+	if err != nil {
+		return err
+	}
+	for _, w := range streams {
+		w.crlf = crlf
+	}
+	severityStreams = streams
+	// Write anything buffered for the old console, before it is replaced
+	console.Close()
+	console = newConsoleWriter(stream, bufferSize, getFlushIDs())
+	console.crlf = crlf
+	if bufferSize > 0 {
+		// Make sure buffered output doesn't sit in the buffer for too long
+		console.startFlushing(consoleFlushInterval)
+	}
+	return nil
+}
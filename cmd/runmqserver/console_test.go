@@ -0,0 +1,140 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConsoleWriterUnbuffered(t *testing.T) {
+	var out bytes.Buffer
+	c := newConsoleWriter(&out, 0, nil)
+	c.WriteLine("A\n", "")
+	if out.String() != "A\n" {
+		t.Errorf("Expected unbuffered output to be written immediately, got %q", out.String())
+	}
+}
+
+func TestConsoleWriterFlushOnID(t *testing.T) {
+	var out bytes.Buffer
+	c := newConsoleWriter(&out, 4096, map[string]bool{"AMQ6119S": true})
+	c.WriteLine("AMQ5051I: The queue manager task 'AUTOCONFIG' has started.\n", "AMQ5051I")
+	if out.Len() != 0 {
+		t.Fatalf("Expected output to be buffered, got %q", out.String())
+	}
+	c.WriteLine("AMQ6119S: An internal IBM MQ error has occurred.\n", "AMQ6119S")
+	expected := "AMQ5051I: The queue manager task 'AUTOCONFIG' has started.\nAMQ6119S: An internal IBM MQ error has occurred.\n"
+	if out.String() != expected {
+		t.Errorf("Expected buffer to be flushed after matching ID, got %q", out.String())
+	}
+	c.WriteLine("AMQ5051I: The queue manager task 'AUTOCONFIG' has started.\n", "AMQ5051I")
+	c.Flush()
+	expected += "AMQ5051I: The queue manager task 'AUTOCONFIG' has started.\n"
+	if out.String() != expected {
+		t.Errorf("Expected remaining output after explicit flush, got %q", out.String())
+	}
+}
+
+func TestConsoleWriterClose(t *testing.T) {
+	var out bytes.Buffer
+	c := newConsoleWriter(&out, 4096, nil)
+	c.startFlushing(time.Hour)
+	c.WriteLine("A\n", "")
+	// Closing stops the flushing goroutine, and writes the buffered output
+	c.Close()
+	if out.String() != "A\n" {
+		t.Fatalf("Expected buffered output to be written when closed, got %q", out.String())
+	}
+	c.WriteLine("B\n", "")
+	if out.String() != "A\nB\n" {
+		t.Errorf("Expected output to be written immediately after closing, got %q", out.String())
+	}
+	// Closing again does nothing
+	c.Close()
+}
+
+func TestGetFlushIDs(t *testing.T) {
+	t.Setenv("MQ_LOGGING_FLUSH_ON_ID", "amq6119s, AMQ8003I,,")
+	ids := getFlushIDs()
+	if len(ids) != 2 || !ids["AMQ6119S"] || !ids["AMQ8003I"] {
+		t.Errorf("Expected flush IDs AMQ6119S and AMQ8003I, got %v", ids)
+	}
+}
+
+func TestConsoleWriterCRLF(t *testing.T) {
+	var out bytes.Buffer
+	c := newConsoleWriter(&out, 0, nil)
+	c.crlf = true
+	c.WriteLine("A\n", "")
+	c.WriteLine("2024-01-01T10:00:00.000Z B\n# raw: {\"message\":\"B\"}\n", "")
+	expected := "A\r\n2024-01-01T10:00:00.000Z B\r\n# raw: {\"message\":\"B\"}\r\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+var newlineTests = []struct {
+	value    string
+	expected bool
+	valid    bool
+}{
+	{"", false, true},
+	{"lf", false, true},
+	{"CRLF", true, true},
+	{"cr", false, false},
+}
+
+func TestGetNewlineCRLF(t *testing.T) {
+	for _, table := range newlineTests {
+		t.Setenv("MQ_LOGGING_NEWLINE", table.value)
+		crlf, err := getNewlineCRLF()
+		if (err == nil) != table.valid {
+			t.Errorf("getNewlineCRLF() with MQ_LOGGING_NEWLINE=%v - expected valid=%v, got error %v", table.value, table.valid, err)
+		}
+		if crlf != table.expected {
+			t.Errorf("getNewlineCRLF() with MQ_LOGGING_NEWLINE=%v - expected %v, got %v", table.value, table.expected, crlf)
+		}
+	}
+}
+
+var consoleStreamTests = []struct {
+	value    string
+	expected *os.File
+	valid    bool
+}{
+	{"", os.Stdout, true},
+	{"stdout", os.Stdout, true},
+	{"STDERR", os.Stderr, true},
+	{"stdin", nil, false},
+}
+
+func TestConfigureConsoleStream(t *testing.T) {
+	oldConsole := console
+	defer func() { console = oldConsole }()
+	for _, table := range consoleStreamTests {
+		t.Setenv("MQ_LOGGING_CONSOLE_STREAM", table.value)
+		err := configureConsole()
+		if (err == nil) != table.valid {
+			t.Errorf("configureConsole() with MQ_LOGGING_CONSOLE_STREAM=%v - expected valid=%v, got error %v", table.value, table.valid, err)
+		}
+		if err == nil && console.out != table.expected {
+			t.Errorf("configureConsole() with MQ_LOGGING_CONSOLE_STREAM=%v - expected output to %v, got %v", table.value, table.expected.Name(), console.out)
+		}
+	}
+}
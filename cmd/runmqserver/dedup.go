@@ -0,0 +1,272 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// consoleDedup collapses repeated log messages, or is nil if MQ_LOGGING_CONSOLE_DEDUP isn't enabled
+var consoleDedup *lineDedup
+
+// lineDedup tracks the last log message mirrored, and how many times it has been repeated since, so
+// that repeated messages can be replaced with a periodic summary.  The mirror functions for each log
+// source share it, so it is guarded by a mutex.
+type lineDedup struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	// key identifies the last message mirrored, from its message ID and text
+	key string
+	id  string
+	// count is the number of repeats suppressed since the last summary
+	count int
+	last  time.Time
+	// done stops the goroutine which periodically emits the summary
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func newLineDedup(interval time.Duration, start time.Time) *lineDedup {
+	return &lineDedup{
+		interval: interval,
+		last:     start,
+		done:     make(chan struct{}),
+	}
+}
+
+// check returns true if a message is the same as the last one, and so should be suppressed.  If the
+// message is different, a summary of any repeats of the last message is emitted first.
+func (d *lineDedup) check(id string, text string, now time.Time) bool {
+	key := id + "\x00" + text
+	d.mutex.Lock()
+	if key == d.key {
+		d.count++
+		d.mutex.Unlock()
+		return true
+	}
+	lastID, count := d.id, d.count
+	d.key, d.id, d.count, d.last = key, id, 0, now
+	d.mutex.Unlock()
+	emitRepeatSummary(lastID, count)
+	return false
+}
+
+// maybeFlush emits a summary of the repeats of the last message, if the interval has passed since the
+// last summary.  Nothing is emitted if the message hasn't been repeated.
+func (d *lineDedup) maybeFlush(now time.Time) {
+	d.mutex.Lock()
+	if now.Sub(d.last) < d.interval {
+		d.mutex.Unlock()
+		return
+	}
+	id, count := d.id, d.count
+	d.count, d.last = 0, now
+	d.mutex.Unlock()
+	emitRepeatSummary(id, count)
+}
+
+// start starts a goroutine which emits a summary of the repeats at the end of each interval, until the
+// dedup is closed
+func (d *lineDedup) start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.maybeFlush(timeNow())
+			case <-d.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops emitting the summary periodically, and emits a summary of any repeats which haven't been
+// reported
+func (d *lineDedup) Close() {
+	d.closeOnce.Do(func() {
+		close(d.done)
+		d.wg.Wait()
+		d.mutex.Lock()
+		id, count := d.id, d.count
+		d.count = 0
+		d.mutex.Unlock()
+		emitRepeatSummary(id, count)
+	})
+}
+
+// emitRepeatSummary emits a "message_repeated" event, in the same format as the other mirrored
+// messages, if a message was repeated
+func emitRepeatSummary(id string, count int) {
+	if count == 0 {
+		return
+	}
+	subject := "Last message"
+	if id != "" {
+		subject = id
+	}
+	fields := map[string]interface{}{"ibm_repeatCount": count}
+	if id != "" {
+		fields["ibm_repeatedMessageId"] = id
+	}
+	emitEvent("INFO", "message_repeated", fmt.Sprintf("%v repeated %v times", subject, count), fields)
+}
+
+// isDuplicateRecord returns true if a log message should be suppressed, because it repeats the last
+// message.  The obj parameter is the parsed JSON log message, or nil if the message wasn't JSON.
+func isDuplicateRecord(obj map[string]interface{}, msg string) bool {
+	if consoleDedup == nil {
+		return false
+	}
+	if obj == nil {
+		return consoleDedup.check("", msg, timeNow())
+	}
+	r := newMQLogRecord(obj)
+	return consoleDedup.check(r.MessageID(), r.Message(), timeNow())
+}
+
+// configureDedup enables the collapsing of repeated log messages, if MQ_LOGGING_CONSOLE_DEDUP is set.
+// A summary of the repeats is emitted when a different message is mirrored, or every
+// MQ_LOGGING_CONSOLE_DEDUP_INTERVAL (5 seconds by default) while the message is being repeated.
+func configureDedup() error {
+	closeDedup()
+	consoleDedup = nil
+	dedup := os.Getenv("MQ_LOGGING_CONSOLE_DEDUP")
+	if dedup != "true" && dedup != "1" {
+		return nil
+	}
+	interval, err := getDurationEnv("MQ_LOGGING_CONSOLE_DEDUP_INTERVAL", 5*time.Second)
+	if err != nil {
+		return err
+	}
+	consoleDedup = newLineDedup(interval, timeNow())
+	consoleDedup.start()
+	return nil
+}
+
+// closeDedup stops collapsing repeated log messages, emitting a summary of any repeats which haven't been reported
+func closeDedup() {
+	if consoleDedup != nil {
+		consoleDedup.Close()
+	}
+}
+
+// repeatEscalation escalates the severity of repeated log messages, or is nil if
+// MQ_LOGGING_ESCALATE_THRESHOLD isn't set
+var repeatEscalation *escalation
+
+// escalation counts how many times each message ID has been seen within a sliding window, so that a
+// message which is repeated more than the threshold can be escalated, for example during a retry loop.
+// The mirror functions for each log source share it, so it is guarded by a mutex.
+type escalation struct {
+	mutex     sync.Mutex
+	threshold int
+	window    time.Duration
+	level     logLevel
+	// seen holds the times each message ID was seen within the window, up to one more than the threshold
+	seen map[string][]time.Time
+}
+
+func newEscalation(threshold int, window time.Duration, level logLevel) *escalation {
+	return &escalation{
+		threshold: threshold,
+		window:    window,
+		level:     level,
+		seen:      make(map[string][]time.Time),
+	}
+}
+
+// record counts a message ID, and returns true if it has been seen more than the threshold within the window
+func (e *escalation) record(id string, now time.Time) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	times := e.seen[id]
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= e.window {
+		i++
+	}
+	times = append(times[i:], now)
+	if len(times) > e.threshold+1 {
+		times = times[len(times)-e.threshold-1:]
+	}
+	e.seen[id] = times
+	return len(times) > e.threshold
+}
+
+// wrap returns a mirrorFunc which escalates JSON log messages which have been repeated too often.  The
+// severity of an escalated message is raised to the escalation level, and a note is added to its text.
+// The note doesn't include the count, so that repeats can still be collapsed by MQ_LOGGING_CONSOLE_DEDUP.
+func (e *escalation) wrap(mf mirrorFunc) mirrorFunc {
+	note := fmt.Sprintf(" (escalated to %v: repeated more than %v times in %v)", e.level, e.threshold, e.window)
+	return func(msg string, isQMLog bool) bool {
+		obj, err := processLogMessage(trimRecordPrefix(msg))
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		r := newMQLogRecord(obj)
+		id := r.MessageID()
+		if id == "" || !e.record(id, timeNow()) || r.Severity() >= e.level {
+			return mf(msg, isQMLog)
+		}
+		// MQ's severity field holds a single letter, while the log level is a word
+		if _, ok := obj["severity"]; ok {
+			obj["severity"] = e.level.mqSeverity()
+		}
+		obj["loglevel"] = strings.ToUpper(e.level.String())
+		obj["message"] = r.Message() + note
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		return mf(string(b), isQMLog)
+	}
+}
+
+// configureEscalation enables the escalation of repeated log messages, if MQ_LOGGING_ESCALATE_THRESHOLD
+// is set.  A message ID which is seen more than the threshold within MQ_LOGGING_ESCALATE_WINDOW (one minute
+// by default) is escalated to MQ_LOGGING_ESCALATE_LEVEL ("warning" by default).
+func configureEscalation() error {
+	repeatEscalation = nil
+	if strings.TrimSpace(os.Getenv("MQ_LOGGING_ESCALATE_THRESHOLD")) == "" {
+		return nil
+	}
+	threshold, err := getPositiveIntEnv("MQ_LOGGING_ESCALATE_THRESHOLD", 0)
+	if err != nil {
+		return err
+	}
+	window, err := getDurationEnv("MQ_LOGGING_ESCALATE_WINDOW", time.Minute)
+	if err != nil {
+		return err
+	}
+	level := levelWarning
+	if l := os.Getenv("MQ_LOGGING_ESCALATE_LEVEL"); l != "" {
+		level, err = parseLogLevel(l)
+		if err != nil {
+			return fmt.Errorf("invalid value for MQ_LOGGING_ESCALATE_LEVEL: %v", err)
+		}
+	}
+	repeatEscalation = newEscalation(threshold, window, level)
+	return nil
+}
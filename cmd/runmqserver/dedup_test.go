@@ -0,0 +1,209 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	oldTimeNow, oldJSON, oldDedup := timeNow, eventsJSON, consoleDedup
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow, eventsJSON, consoleDedup = oldTimeNow, oldJSON, oldDedup })
+	repeated := "{\"ibm_datetime\":\"2024-03-01T12:00:00.000Z\",\"loglevel\":\"ERROR\",\"ibm_messageId\":\"AMQ9999E\",\"message\":\"AMQ9999E: Channel ended abnormally.\"}"
+	other := "{\"ibm_datetime\":\"2024-03-01T12:00:01.000Z\",\"loglevel\":\"INFO\",\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: Started\"}"
+	var tests = []struct {
+		format  string
+		summary string
+	}{
+		{"json", "{\"ibm_datetime\":\"2024-03-01T12:00:00.000Z\",\"ibm_event\":\"message_repeated\",\"ibm_repeatCount\":2,\"ibm_repeatedMessageId\":\"AMQ9999E\",\"loglevel\":\"INFO\",\"message\":\"AMQ9999E repeated 2 times\",\"type\":\"mq_containerlog\"}\n"},
+		{"basic", "2024-03-01T12:00:00.000Z AMQ9999E repeated 2 times\n"},
+	}
+	for _, table := range tests {
+		t.Run(table.format, func(t *testing.T) {
+			eventsJSON = table.format == "json"
+			consoleDedup = newLineDedup(5*time.Second, now)
+			mf := newMirrorFunc(table.format, mirrorOptions{})
+			buf := captureConsole(t)
+			for i, expected := range []bool{true, false, false} {
+				if mirrored := mf(repeated, true); mirrored != expected {
+					t.Errorf("Expected mirrored to be %v for repeat %v; got %v", expected, i, mirrored)
+				}
+			}
+			first := buf.String()
+			mf(other, true)
+			if lines := strings.SplitAfter(buf.String(), "\n"); len(lines) != 4 || lines[0] != first || lines[1] != table.summary {
+				t.Errorf("Expected the message, then %q, then the next message; got %q", table.summary, buf.String())
+			}
+		})
+	}
+}
+
+func TestDedupPeriodicSummary(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	oldTimeNow, oldJSON, oldDedup := timeNow, eventsJSON, consoleDedup
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow, eventsJSON, consoleDedup = oldTimeNow, oldJSON, oldDedup })
+	eventsJSON = false
+	consoleDedup = newLineDedup(5*time.Second, now)
+	mf := newMirrorFunc("basic", mirrorOptions{})
+	buf := captureConsole(t)
+	for i := 0; i < 4; i++ {
+		mf("Not JSON", false)
+	}
+	consoleDedup.maybeFlush(now.Add(4 * time.Second))
+	if strings.Contains(buf.String(), "repeated") {
+		t.Errorf("Expected no summary before the interval; got %q", buf.String())
+	}
+	consoleDedup.maybeFlush(now.Add(5 * time.Second))
+	if !strings.HasSuffix(buf.String(), "Last message repeated 3 times\n") {
+		t.Errorf("Expected a summary after the interval; got %q", buf.String())
+	}
+	// Nothing is emitted if there were no more repeats
+	buf.Reset()
+	consoleDedup.maybeFlush(now.Add(10 * time.Second))
+	if buf.Len() != 0 {
+		t.Errorf("Expected no summary without repeats; got %q", buf.String())
+	}
+}
+
+func TestDedupClose(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	oldTimeNow, oldJSON, oldDedup := timeNow, eventsJSON, consoleDedup
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow, eventsJSON, consoleDedup = oldTimeNow, oldJSON, oldDedup })
+	eventsJSON = false
+	consoleDedup = newLineDedup(time.Hour, now)
+	consoleDedup.start()
+	mf := newMirrorFunc("basic", mirrorOptions{})
+	buf := captureConsole(t)
+	for i := 0; i < 3; i++ {
+		mf("Not JSON", false)
+	}
+	// Closing emits the pending summary, rather than waiting for the end of the interval
+	closeDedup()
+	if !strings.HasSuffix(buf.String(), "Last message repeated 2 times\n") {
+		t.Errorf("Expected a summary when closed; got %q", buf.String())
+	}
+	buf.Reset()
+	closeDedup()
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be emitted when closed again; got %q", buf.String())
+	}
+}
+
+func TestDedupConcurrent(t *testing.T) {
+	oldJSON, oldDedup := eventsJSON, consoleDedup
+	t.Cleanup(func() { eventsJSON, consoleDedup = oldJSON, oldDedup })
+	eventsJSON = false
+	consoleDedup = newLineDedup(time.Hour, timeNow())
+	mf := newMirrorFunc("basic", mirrorOptions{})
+	buf := captureConsole(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				mf("Retrying", false)
+			}
+		}()
+	}
+	wg.Wait()
+	mf("Done", false)
+	expected := fmt.Sprintf("Retrying\n%v Last message repeated 399 times\nDone\n", timeNow().Format(eventTimestampFormat))
+	if out := buf.String(); !strings.HasPrefix(out, "Retrying\n") || !strings.HasSuffix(out, "Last message repeated 399 times\nDone\n") {
+		t.Errorf("Expected %q; got %q", expected, out)
+	}
+}
+
+func TestEscalation(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	oldTimeNow := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = oldTimeNow })
+	t.Setenv("MQ_LOGGING_ESCALATE_THRESHOLD", "3")
+	err := configureEscalation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repeatEscalation = nil })
+	var msgs []string
+	mf := repeatEscalation.wrap(func(msg string, isQMLog bool) bool {
+		msgs = append(msgs, msg)
+		return true
+	})
+	info := "{\"ibm_messageId\":\"AMQ9002I\",\"loglevel\":\"INFO\",\"message\":\"AMQ9002I: Channel started.\"}"
+	escalated := "{\"ibm_messageId\":\"AMQ9002I\",\"loglevel\":\"WARNING\",\"message\":\"AMQ9002I: Channel started. (escalated to warning: repeated more than 3 times in 1m0s)\"}"
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Second)
+		mf(info, true)
+	}
+	expected := []string{info, info, info, escalated, escalated}
+	if !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("Expected %v; got %v", expected, msgs)
+	}
+
+	// The severity field of an MQ message keeps its single letter form
+	msgs = nil
+	now = now.Add(time.Minute)
+	mq := "{\"ibm_messageId\":\"AMQ9003I\",\"loglevel\":\"INFO\",\"message\":\"AMQ9003I: Channel stopped.\",\"severity\":\"I\"}"
+	mqEscalated := "{\"ibm_messageId\":\"AMQ9003I\",\"loglevel\":\"WARNING\",\"message\":\"AMQ9003I: Channel stopped. (escalated to warning: repeated more than 3 times in 1m0s)\",\"severity\":\"W\"}"
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Second)
+		mf(mq, true)
+	}
+	if expected := []string{mq, mq, mq, mqEscalated}; !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("Expected %v; got %v", expected, msgs)
+	}
+
+	// Once the earlier messages are outside the window, the message isn't escalated
+	msgs = nil
+	now = now.Add(time.Minute)
+	mf(info, true)
+	// Messages which are already at the escalation level, and other lines, are never changed
+	for i := 0; i < 5; i++ {
+		mf("{\"ibm_messageId\":\"AMQ9999E\",\"loglevel\":\"ERROR\",\"message\":\"AMQ9999E: Channel ended.\"}", true)
+		mf("Not JSON", true)
+	}
+	for _, msg := range msgs {
+		if strings.Contains(msg, "escalated") {
+			t.Errorf("Expected no escalation; got %v", msg)
+		}
+	}
+}
+
+func TestConfigureEscalationInvalid(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"MQ_LOGGING_ESCALATE_THRESHOLD": "0"},
+		{"MQ_LOGGING_ESCALATE_THRESHOLD": "10", "MQ_LOGGING_ESCALATE_WINDOW": "soon"},
+		{"MQ_LOGGING_ESCALATE_THRESHOLD": "10", "MQ_LOGGING_ESCALATE_LEVEL": "urgent"},
+	} {
+		for _, name := range []string{"MQ_LOGGING_ESCALATE_THRESHOLD", "MQ_LOGGING_ESCALATE_WINDOW", "MQ_LOGGING_ESCALATE_LEVEL"} {
+			t.Setenv(name, env[name])
+		}
+		if err := configureEscalation(); err == nil {
+			t.Errorf("Expected an error for %v", env)
+		}
+	}
+	repeatEscalation = nil
+}
@@ -0,0 +1,176 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// excludeDigest counts the messages dropped by MQ_LOGGING_CONSOLE_EXCLUDE_ID, or is nil if the digest is disabled
+var excludeDigest *exclusionDigest
+
+// exclusionDigest counts excluded messages by ID, and periodically reports the counts, so that
+// operators can see what their exclude list is hiding
+type exclusionDigest struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	last     time.Time
+	counts   map[string]int
+	// done stops the goroutine which periodically emits the digest
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func newExclusionDigest(interval time.Duration, start time.Time) *exclusionDigest {
+	return &exclusionDigest{
+		interval: interval,
+		last:     start,
+		counts:   make(map[string]int),
+		done:     make(chan struct{}),
+	}
+}
+
+// start starts a goroutine which emits the digest at the end of each interval, until the digest is closed
+func (d *exclusionDigest) start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.maybeEmit(timeNow())
+			case <-d.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops emitting the digest periodically, and emits a final digest of any messages excluded
+// since the last one
+func (d *exclusionDigest) Close() {
+	d.closeOnce.Do(func() {
+		close(d.done)
+		d.wg.Wait()
+		d.emit(timeNow())
+	})
+}
+
+// record counts a message which has been excluded
+func (d *exclusionDigest) record(id string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.counts[id]++
+}
+
+// maybeEmit emits a digest of the excluded messages, if the interval has passed since the last one.
+// Nothing is emitted if no messages were excluded.
+func (d *exclusionDigest) maybeEmit(now time.Time) {
+	d.mutex.Lock()
+	due := now.Sub(d.last) >= d.interval
+	d.mutex.Unlock()
+	if due {
+		d.emit(now)
+	}
+}
+
+// emit emits a digest of the messages excluded since the last one, if there were any
+func (d *exclusionDigest) emit(now time.Time) {
+	d.mutex.Lock()
+	counts := d.counts
+	d.counts = make(map[string]int)
+	d.last = now
+	d.mutex.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(counts))
+	total := 0
+	for id, n := range counts {
+		ids = append(ids, id)
+		total += n
+	}
+	sort.Strings(ids)
+	summary := make([]string, 0, len(ids))
+	fields := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		summary = append(summary, fmt.Sprintf("%v(%v)", id, counts[id]))
+		fields[id] = counts[id]
+	}
+	emitEvent("INFO", "excluded_digest", fmt.Sprintf("Excluded %v log messages in the last %v: %v", total, d.interval, strings.Join(summary, ", ")), map[string]interface{}{"ibm_excludedCounts": fields})
+}
+
+// activeExcludeRules holds the exclude rules which have matched a message, so that the first match of
+// each rule is only reported once
+var activeExcludeRules = struct {
+	sync.Mutex
+	ids map[string]bool
+}{ids: make(map[string]bool)}
+
+// reportExcludeRuleActive emits an "exclude_rule_active" event, the first time a rule matches a
+// message, so that a rule which is working can be told apart from one which never matches
+func reportExcludeRuleActive(id string) {
+	activeExcludeRules.Lock()
+	seen := activeExcludeRules.ids[id]
+	activeExcludeRules.ids[id] = true
+	activeExcludeRules.Unlock()
+	if !seen {
+		emitEvent("DEBUG", "exclude_rule_active", fmt.Sprintf("Exclude rule %v is active", id), map[string]interface{}{"ibm_excludeRule": id})
+	}
+}
+
+// recordExcluded counts a message dropped by MQ_LOGGING_CONSOLE_EXCLUDE_ID or MQ_LOGGING_CONSOLE_EXCLUDE_REGEX,
+// if the digest is enabled.
+// If debug is enabled, the first match of each rule is also reported.
+func recordExcluded(id string) {
+	if excludeDigest != nil {
+		excludeDigest.record(id)
+	}
+	if getDebug() {
+		reportExcludeRuleActive(id)
+	}
+}
+
+// configureExcludeDigest enables the digest of excluded messages, if MQ_LOGGING_EXCLUDE_DIGEST_INTERVAL is set
+func configureExcludeDigest() error {
+	closeExcludeDigest()
+	excludeDigest = nil
+	// The rules may have changed, so report each one again the first time it matches
+	activeExcludeRules.Lock()
+	activeExcludeRules.ids = make(map[string]bool)
+	activeExcludeRules.Unlock()
+	interval, err := getDurationEnv("MQ_LOGGING_EXCLUDE_DIGEST_INTERVAL", 0)
+	if err != nil || interval == 0 {
+		return err
+	}
+	excludeDigest = newExclusionDigest(interval, timeNow())
+	excludeDigest.start()
+	return nil
+}
+
+// closeExcludeDigest stops the digest of excluded messages, emitting any counts which haven't been reported
+func closeExcludeDigest() {
+	if excludeDigest != nil {
+		excludeDigest.Close()
+	}
+}
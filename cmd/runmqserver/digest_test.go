@@ -0,0 +1,124 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExclusionDigest(t *testing.T) {
+	oldNow, oldJSON := timeNow, eventsJSON
+	defer func() { timeNow, eventsJSON = oldNow, oldJSON }()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	eventsJSON = true
+	buf := captureConsole(t)
+
+	d := newExclusionDigest(time.Minute, now)
+	d.record("AMQ5051I")
+	d.record("AMQ5051I")
+	d.record("AMQ9209E")
+
+	// Nothing is emitted until the interval has passed
+	now = now.Add(59 * time.Second)
+	d.maybeEmit(now)
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no digest before the interval has passed; got %v", buf.String())
+	}
+	now = now.Add(time.Second)
+	d.maybeEmit(now)
+	out := buf.String()
+	for _, e := range []string{"\"ibm_event\":\"excluded_digest\"", "\"ibm_excludedCounts\":{\"AMQ5051I\":2,\"AMQ9209E\":1}", "Excluded 3 log messages in the last 1m0s: AMQ5051I(2), AMQ9209E(1)"} {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected digest to contain %v; got %v", e, out)
+		}
+	}
+
+	// The counts are reset, and an empty digest isn't emitted
+	buf.Reset()
+	now = now.Add(time.Minute)
+	d.maybeEmit(now)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no digest when nothing was excluded; got %v", buf.String())
+	}
+}
+
+func TestExclusionDigestClose(t *testing.T) {
+	oldNow, oldJSON := timeNow, eventsJSON
+	defer func() { timeNow, eventsJSON = oldNow, oldJSON }()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	eventsJSON = true
+	buf := captureConsole(t)
+
+	d := newExclusionDigest(time.Hour, now)
+	d.start()
+	d.record("AMQ5051I")
+	// Closing emits the counts straight away, rather than waiting for the end of the interval
+	d.Close()
+	if !strings.Contains(buf.String(), "\"ibm_excludedCounts\":{\"AMQ5051I\":1}") {
+		t.Errorf("Expected a final digest when closed; got %v", buf.String())
+	}
+	// Closing again doesn't emit anything
+	buf.Reset()
+	d.Close()
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be emitted when closed again; got %v", buf.String())
+	}
+}
+
+func TestExcludeRuleActive(t *testing.T) {
+	oldJSON := eventsJSON
+	defer func() { eventsJSON = oldJSON }()
+	eventsJSON = true
+	t.Setenv("DEBUG", "true")
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID", "AMQ5051I,AMQ9999E,AMQ7777W")
+	err := configureExcludeDigest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := captureConsole(t)
+	mf := newJSONMirrorFunc(mirrorOptions{})
+	for _, id := range []string{"AMQ5051I", "AMQ9999E", "AMQ5051I", "AMQ5051I"} {
+		mf("{\"ibm_messageId\":\""+id+"\",\"message\":\""+id+": Test\"}", false)
+	}
+	out := buf.String()
+	for _, id := range []string{"AMQ5051I", "AMQ9999E"} {
+		if n := strings.Count(out, "\"ibm_excludeRule\":\""+id+"\""); n != 1 {
+			t.Errorf("Expected one exclude_rule_active event for %v; got %v in %q", id, n, out)
+		}
+	}
+	// A rule which never matches is not reported
+	if strings.Contains(out, "AMQ7777W") {
+		t.Errorf("Expected no event for an unused rule; got %q", out)
+	}
+}
+
+func TestExcludeRuleActiveNeedsDebug(t *testing.T) {
+	t.Setenv("DEBUG", "false")
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID", "AMQ5051I")
+	err := configureExcludeDigest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := captureConsole(t)
+	newJSONMirrorFunc(mirrorOptions{})("{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: Test\"}", false)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output without debug; got %q", buf.String())
+	}
+}
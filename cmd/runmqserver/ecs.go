@@ -0,0 +1,113 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ecsVersion is the version of the Elastic Common Schema used for the "ecs" format
+const ecsVersion = "1.6.0"
+
+// ecsFields are the MQ and Liberty fields which have an equivalent ECS field.  Any other fields are
+// kept as labels.
+var ecsFields = map[string]string{
+	"ibm_datetime":  "@timestamp",
+	"loglevel":      "log.level",
+	"message":       "message",
+	"host":          "host.name",
+	"ibm_messageId": "event.code",
+}
+
+// ecsLevel returns the ECS "log.level" for an MQ or Liberty log level.  Liberty's trace levels are mapped
+// to "debug" and "trace", rather than to a number as in basic format.
+func ecsLevel(level string) string {
+	switch strings.ToUpper(level) {
+	case "AUDIT", "INFO", "SYSTEMOUT":
+		return "info"
+	case "WARNING", "WARN":
+		return "warn"
+	case "ERROR", "SEVERE", "SYSTEMERR":
+		return "error"
+	case "FATAL":
+		return "fatal"
+	case "EVENT", "ENTRY", "EXIT", "FINE", "DEBUG":
+		return "debug"
+	case "FINER", "FINEST", "TRACE":
+		return "trace"
+	}
+	return strings.ToLower(level)
+}
+
+// ecsLabel returns a field value as an ECS label, which is always a string
+func ecsLabel(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	// #nosec G104 - a value parsed from JSON can always be marshalled
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// toECS converts a parsed JSON log message to the Elastic Common Schema.  The message is expected to
+// have a timestamp, so the current time is used if it doesn't have one.
+func toECS(obj map[string]interface{}) map[string]interface{} {
+	r := newMQLogRecord(obj)
+	out := map[string]interface{}{
+		"ecs.version": ecsVersion,
+		"message":     r.Message(),
+	}
+	if dt := r.Datetime(); !dt.IsZero() {
+		out["@timestamp"] = dt.UTC().Format(eventTimestampFormat)
+	} else if text := r.DatetimeText(); text != "" {
+		out["@timestamp"] = text
+	} else {
+		out["@timestamp"] = timeNow().UTC().Format(eventTimestampFormat)
+	}
+	if level := r.Field("loglevel"); level != "" {
+		out["log.level"] = ecsLevel(level)
+	}
+	if host := r.Field("host"); host != "" {
+		out["host.name"] = host
+	}
+	if id := r.MessageID(); id != "" {
+		out["event.code"] = id
+	}
+	labels := make(map[string]interface{})
+	for k, v := range obj {
+		if _, ok := ecsFields[k]; ok {
+			continue
+		}
+		labels[k] = ecsLabel(v)
+	}
+	if len(labels) > 0 {
+		out["labels"] = labels
+	}
+	return out
+}
+
+// formatECSLine converts a JSON log line to the Elastic Common Schema.  If the line can't be parsed, it is
+// wrapped as the message.
+func formatECSLine(line string) string {
+	obj, err := processLogMessage(line)
+	if err != nil {
+		obj = map[string]interface{}{"message": line}
+	}
+	// #nosec G104 - the labels are all strings, so the message can always be marshalled
+	b, _ := json.Marshal(toECS(obj))
+	return string(b)
+}
@@ -0,0 +1,78 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestECSFormat(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	oldTimeNow := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = oldTimeNow })
+	var tests = []struct {
+		name     string
+		msg      string
+		expected string
+	}{
+		{
+			name:     "MQ",
+			msg:      "{\"ibm_datetime\":\"2024-02-29T10:15:00.123Z\",\"loglevel\":\"WARNING\",\"host\":\"mq1\",\"ibm_messageId\":\"AMQ9209E\",\"message\":\"AMQ9209E: Connection closed.\",\"ibm_processId\":1234,\"ibm_serverName\":\"QM1\"}",
+			expected: "{\"@timestamp\":\"2024-02-29T10:15:00.123Z\",\"ecs.version\":\"1.6.0\",\"event.code\":\"AMQ9209E\",\"host.name\":\"mq1\",\"labels\":{\"ibm_processId\":\"1234\",\"ibm_serverName\":\"QM1\"},\"log.level\":\"warn\",\"message\":\"AMQ9209E: Connection closed.\"}\n",
+		},
+		{
+			name:     "Liberty message",
+			msg:      "{\"type\":\"liberty_message\",\"ibm_datetime\":\"2024-02-29T10:15:00.123+0000\",\"loglevel\":\"AUDIT\",\"ibm_messageId\":\"CWWKF0011I\",\"message\":\"CWWKF0011I: Ready\"}",
+			expected: "{\"@timestamp\":\"2024-02-29T10:15:00.123Z\",\"ecs.version\":\"1.6.0\",\"event.code\":\"CWWKF0011I\",\"labels\":{\"type\":\"liberty_message\"},\"log.level\":\"info\",\"message\":\"CWWKF0011I: Ready\"}\n",
+		},
+		{
+			name:     "Liberty trace",
+			msg:      "{\"type\":\"liberty_trace\",\"ibm_datetime\":\"2024-02-29T10:15:00.123+0000\",\"loglevel\":\"FINEST\",\"message\":\"Entering method\"}",
+			expected: "{\"@timestamp\":\"2024-02-29T10:15:00.123Z\",\"ecs.version\":\"1.6.0\",\"labels\":{\"type\":\"liberty_trace\"},\"log.level\":\"trace\",\"message\":\"Entering method\"}\n",
+		},
+		{
+			name:     "Not JSON",
+			msg:      "Plain text \"message\"",
+			expected: "{\"@timestamp\":\"2024-03-01T12:00:00.000Z\",\"ecs.version\":\"1.6.0\",\"message\":\"Plain text \\\"message\\\"\"}\n",
+		},
+	}
+	for _, table := range tests {
+		t.Run(table.name, func(t *testing.T) {
+			buf := captureConsole(t)
+			newMirrorFunc("ecs", mirrorOptions{})(table.msg, false)
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestGetLogFormatECS(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "ecs")
+	if f := getLogFormat(); f != "ecs" {
+		t.Errorf("Expected ecs; got %v", f)
+	}
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json;web:ecs")
+	overrides, err := getLogFormatOverrides()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overrides["web"] != "ecs" {
+		t.Errorf("Expected web to use ecs; got %v", overrides["web"])
+	}
+}
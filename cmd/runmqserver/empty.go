@@ -0,0 +1,108 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// emptyRecordMode controls what happens to log records which are an empty JSON object
+type emptyRecordMode string
+
+const (
+	// emptyRecordDrop doesn't mirror empty records
+	emptyRecordDrop emptyRecordMode = "drop"
+	// emptyRecordMark replaces empty records with an "empty_record" event
+	emptyRecordMark emptyRecordMode = "mark"
+)
+
+// getEmptyRecordMode returns the value of MQ_LOGGING_EMPTY_RECORD, which defaults to "drop"
+func getEmptyRecordMode() (emptyRecordMode, error) {
+	mode := emptyRecordMode(strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_EMPTY_RECORD"))))
+	switch mode {
+	case "":
+		return emptyRecordDrop, nil
+	case emptyRecordDrop, emptyRecordMark:
+		return mode, nil
+	}
+	return emptyRecordDrop, fmt.Errorf("invalid value for MQ_LOGGING_EMPTY_RECORD: %v", mode)
+}
+
+// mirrorEmptyRecord handles a log record which is an empty JSON object, returning true if anything was mirrored
+func mirrorEmptyRecord(mode emptyRecordMode) bool {
+	if mode != emptyRecordMark {
+		log.Debug("Dropped empty log record")
+		return false
+	}
+	emitEvent("WARNING", "empty_record", "Empty log record", nil)
+	return true
+}
+
+// missingMessageMode controls what happens to log records in basic format which have no "message" field
+type missingMessageMode string
+
+const (
+	// missingMessageKeep formats the record as usual, with an empty message
+	missingMessageKeep missingMessageMode = "keep"
+	// missingMessageSkip doesn't mirror the record
+	missingMessageSkip missingMessageMode = "skip"
+	// missingMessagePlaceholder formats the record with a placeholder in place of the message
+	missingMessagePlaceholder missingMessageMode = "placeholder"
+)
+
+// defaultMissingMessagePlaceholder is used in place of a missing message, unless
+// MQ_LOGGING_MISSING_MESSAGE_PLACEHOLDER is set
+const defaultMissingMessagePlaceholder = "(no message)"
+
+// getMissingMessageMode returns the value of MQ_LOGGING_MISSING_MESSAGE, which defaults to "keep", and
+// the placeholder to use
+func getMissingMessageMode() (missingMessageMode, string, error) {
+	placeholder := os.Getenv("MQ_LOGGING_MISSING_MESSAGE_PLACEHOLDER")
+	if placeholder == "" {
+		placeholder = defaultMissingMessagePlaceholder
+	}
+	mode := missingMessageMode(strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_MISSING_MESSAGE"))))
+	switch mode {
+	case "":
+		return missingMessageKeep, placeholder, nil
+	case missingMessageKeep, missingMessageSkip, missingMessagePlaceholder:
+		return mode, placeholder, nil
+	}
+	return missingMessageKeep, placeholder, fmt.Errorf("invalid value for MQ_LOGGING_MISSING_MESSAGE: %v", mode)
+}
+
+// handleMissingMessage checks a parsed log record for a "message" field.  If it has none, the record is
+// dropped (returning false), or a copy with the placeholder as its message is returned, depending on the mode.
+func handleMissingMessage(obj map[string]interface{}, mode missingMessageMode, placeholder string) (map[string]interface{}, bool) {
+	if m, ok := obj["message"]; ok && m != nil {
+		return obj, true
+	}
+	switch mode {
+	case missingMessageSkip:
+		log.Debug("Dropped log record with no message")
+		return obj, false
+	case missingMessagePlaceholder:
+		copied := make(map[string]interface{}, len(obj)+1)
+		for k, v := range obj {
+			copied[k] = v
+		}
+		copied["message"] = placeholder
+		return copied, true
+	}
+	return obj, true
+}
@@ -0,0 +1,105 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+var emptyRecordTests = []struct {
+	format   string
+	mode     string
+	expected string
+}{
+	{"json", "", ""},
+	{"basic", "drop", ""},
+	{"json", "mark", "{\"ibm_datetime\":\"2024-01-01T12:00:00.000Z\",\"ibm_event\":\"empty_record\",\"loglevel\":\"WARNING\",\"message\":\"Empty log record\",\"type\":\"mq_containerlog\"}\n"},
+	{"basic", "mark", "2024-01-01T12:00:00.000Z Empty log record\n"},
+}
+
+func TestEmptyRecord(t *testing.T) {
+	oldLog, oldJSON, oldNow := log, eventsJSON, timeNow
+	defer func() { log, eventsJSON, timeNow = oldLog, oldJSON, oldNow }()
+	timeNow = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	for _, table := range emptyRecordTests {
+		t.Run(table.format+"/"+table.mode, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", table.format)
+			t.Setenv("MQ_LOGGING_EMPTY_RECORD", table.mode)
+			mf, err := configureLogger("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := captureConsole(t)
+			mirrored := mf("{}", false)
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+			if mirrored != (table.expected != "") {
+				t.Errorf("Expected mirrored=%v; got %v", table.expected != "", mirrored)
+			}
+		})
+	}
+}
+
+func TestEmptyRecordModeInvalid(t *testing.T) {
+	t.Setenv("MQ_LOGGING_EMPTY_RECORD", "keep")
+	_, err := getEmptyRecordMode()
+	if err == nil {
+		t.Error("Expected an error for an invalid mode")
+	}
+}
+
+var missingMessageTests = []struct {
+	mode        string
+	placeholder string
+	expected    string
+}{
+	{"", "", "2024-01-01T12:00:00.000Z \n"},
+	{"keep", "", "2024-01-01T12:00:00.000Z \n"},
+	{"skip", "", ""},
+	{"placeholder", "", "2024-01-01T12:00:00.000Z (no message)\n"},
+	{"placeholder", "<missing>", "2024-01-01T12:00:00.000Z <missing>\n"},
+}
+
+func TestMissingMessage(t *testing.T) {
+	for _, table := range missingMessageTests {
+		t.Run(table.mode+"/"+table.placeholder, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_MISSING_MESSAGE", table.mode)
+			t.Setenv("MQ_LOGGING_MISSING_MESSAGE_PLACEHOLDER", table.placeholder)
+			opts, err := getMirrorOptions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := captureConsole(t)
+			mirrored := newBasicMirrorFunc(opts)("{\"ibm_datetime\":\"2024-01-01T12:00:00.000Z\",\"loglevel\":\"INFO\"}", false)
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+			if mirrored != (table.expected != "") {
+				t.Errorf("Expected mirrored=%v; got %v", table.expected != "", mirrored)
+			}
+		})
+	}
+}
+
+func TestMissingMessageModeInvalid(t *testing.T) {
+	t.Setenv("MQ_LOGGING_MISSING_MESSAGE", "drop")
+	_, _, err := getMissingMessageMode()
+	if err == nil {
+		t.Error("Expected an error for an invalid mode")
+	}
+}
@@ -0,0 +1,92 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+)
+
+// englishMessages are the English text of common MQ messages, keyed by message ID.  Inserts are
+// written as "{CommentInsert1}", using the names returned by MQLogRecord.Inserts.
+var englishMessages = map[string]string{
+	"AMQ5022I": "The channel initiator has started. ProcessId({ArithInsert1}).",
+	"AMQ5024I": "The command server has started. ProcessId({ArithInsert1}).",
+	"AMQ5026I": "The listener '{CommentInsert1}' has started. ProcessId({ArithInsert1}).",
+	"AMQ5041I": "The queue manager task '{CommentInsert1}' has ended.",
+	"AMQ5051I": "The queue manager task '{CommentInsert1}' has started.",
+	"AMQ5806I": "IBM MQ Publish/Subscribe broker started for queue manager {CommentInsert1}.",
+	"AMQ8003I": "IBM MQ queue manager '{CommentInsert1}' started using V{CommentInsert2}.",
+	"AMQ8004I": "IBM MQ queue manager '{CommentInsert1}' ended.",
+	"AMQ9209E": "Connection to host '{CommentInsert1}' for channel '{CommentInsert2}' closed.",
+}
+
+// insertPlaceholder matches an insert in the text of englishMessages
+var insertPlaceholder = regexp.MustCompile(`\{([A-Za-z]+Insert[0-9]+)\}`)
+
+// getEnglishMessages returns true if the text of known MQ messages should be replaced with English
+func getEnglishMessages() bool {
+	enabled := os.Getenv("MQ_LOGGING_ENGLISH_MESSAGES")
+	return enabled == "true" || enabled == "1"
+}
+
+// englishMessage returns the English text of a message, including the message ID, or false if the
+// message ID is not known, or the message doesn't have all the inserts needed
+func englishMessage(r MQLogRecord) (string, bool) {
+	id := r.MessageID()
+	text, ok := englishMessages[id]
+	if !ok {
+		return "", false
+	}
+	inserts := r.Inserts()
+	complete := true
+	text = insertPlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+		v, ok := inserts[insertPlaceholder.FindStringSubmatch(placeholder)[1]]
+		if !ok {
+			complete = false
+		}
+		return v
+	})
+	if !complete {
+		return "", false
+	}
+	return id + ": " + text, true
+}
+
+// translateToEnglish wraps a mirrorFunc, so that JSON log messages with a known message ID have their
+// text replaced with English, whatever the locale they were written in.  The original text is kept in
+// an "ibm_localizedMessage" field.  Other messages are passed through untouched.
+func translateToEnglish(mf mirrorFunc) mirrorFunc {
+	return func(msg string, isQMLog bool) bool {
+		obj, err := processLogMessage(trimRecordPrefix(msg))
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		r := newMQLogRecord(obj)
+		text, ok := englishMessage(r)
+		if !ok || text == r.Message() {
+			return mf(msg, isQMLog)
+		}
+		obj["ibm_localizedMessage"] = obj["message"]
+		obj["message"] = text
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		return mf(string(b), isQMLog)
+	}
+}
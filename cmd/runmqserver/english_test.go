@@ -0,0 +1,68 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+)
+
+var translateToEnglishTests = []struct {
+	name     string
+	msg      string
+	expected string
+}{
+	{
+		"German",
+		"{\"ibm_commentInsert1\":\"LOGGER-IO\",\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: Die WS-Manager-Task 'LOGGER-IO' wurde gestartet.\"}",
+		"{\"ibm_commentInsert1\":\"LOGGER-IO\",\"ibm_localizedMessage\":\"AMQ5051I: Die WS-Manager-Task 'LOGGER-IO' wurde gestartet.\",\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: The queue manager task 'LOGGER-IO' has started.\"}",
+	},
+	{
+		"AlreadyEnglish",
+		"{\"ibm_commentInsert1\":\"LOGGER-IO\",\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: The queue manager task 'LOGGER-IO' has started.\"}",
+		"{\"ibm_commentInsert1\":\"LOGGER-IO\",\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: The queue manager task 'LOGGER-IO' has started.\"}",
+	},
+	{
+		"UnknownID",
+		"{\"ibm_messageId\":\"AMQ1234E\",\"message\":\"AMQ1234E: Unbekannt.\"}",
+		"{\"ibm_messageId\":\"AMQ1234E\",\"message\":\"AMQ1234E: Unbekannt.\"}",
+	},
+	{
+		"MissingInsert",
+		"{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: Die WS-Manager-Task wurde gestartet.\"}",
+		"{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: Die WS-Manager-Task wurde gestartet.\"}",
+	},
+	{
+		"NotJSON",
+		"Nicht JSON",
+		"Nicht JSON",
+	},
+}
+
+func TestTranslateToEnglish(t *testing.T) {
+	for _, table := range translateToEnglishTests {
+		t.Run(table.name, func(t *testing.T) {
+			var got string
+			mf := translateToEnglish(func(msg string, isQMLog bool) bool {
+				got = msg
+				return true
+			})
+			mf(table.msg, false)
+			if got != table.expected {
+				t.Errorf("Expected %v; got %v", table.expected, got)
+			}
+		})
+	}
+}
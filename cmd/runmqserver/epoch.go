@@ -0,0 +1,80 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// epochFile holds the number of times the container has started, on the data volume
+var epochFile = "/var/mqm/errors/.runmqserver-epoch"
+
+// processEpoch is the epoch of this process, once it has been worked out, and processEpochMode is how it was worked out.
+// The epoch is only worked out once, so that the counter isn't incremented if the logger is configured again.
+var processEpoch, processEpochMode string
+
+// nextEpochCount increments the start count in the epoch file, and returns the new count.  A missing
+// or unreadable file is treated as a count of zero.
+func nextEpochCount() (int, error) {
+	count := 0
+	// #nosec G304 - the file is only read, and its contents are checked
+	b, err := os.ReadFile(epochFile)
+	if err == nil {
+		count, err = strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil {
+			log.Debugf("Ignoring invalid epoch file %v: %v", epochFile, err)
+			count = 0
+		}
+	}
+	count++
+	tmp := epochFile + ".tmp"
+	// #nosec G306 - its a read by owner/s group, and pose no harm.
+	err = os.WriteFile(tmp, []byte(strconv.Itoa(count)), 0660)
+	if err == nil {
+		err = os.Rename(tmp, epochFile)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to write epoch file %v: %v", epochFile, err)
+	}
+	return count, nil
+}
+
+// getLogEpoch returns the identifier of this container incarnation, which is added to each JSON log message
+// as "ibm_epoch".  MQ_LOGGING_EPOCH is "start" for the time the process started, or "counter" for a
+// count of starts, persisted on the data volume.  An empty string is returned if it isn't set.
+func getLogEpoch() (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_EPOCH")))
+	if mode == "" || mode == processEpochMode {
+		return processEpoch, nil
+	}
+	switch mode {
+	case "start":
+		processEpoch = timeNow().UTC().Format(eventTimestampFormat)
+	case "counter":
+		count, err := nextEpochCount()
+		if err != nil {
+			return "", err
+		}
+		processEpoch = strconv.Itoa(count)
+	default:
+		return "", fmt.Errorf("invalid value for MQ_LOGGING_EPOCH: %v", mode)
+	}
+	processEpochMode = mode
+	return processEpoch, nil
+}
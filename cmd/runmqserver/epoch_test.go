@@ -0,0 +1,71 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// simulateRestart forgets the epoch of this process, as if the container had restarted
+func simulateRestart() {
+	processEpoch, processEpochMode = "", ""
+}
+
+var logEpochTests = []struct {
+	mode     string
+	expected []string
+}{
+	{"counter", []string{"1", "2", "3"}},
+	{"start", []string{"2024-01-01T12:00:00.000Z", "2024-01-01T12:01:00.000Z", "2024-01-01T12:02:00.000Z"}},
+}
+
+func TestLogEpochRestarts(t *testing.T) {
+	oldLog, oldFile, oldNow := log, epochFile, timeNow
+	defer func() {
+		log, epochFile, timeNow = oldLog, oldFile, oldNow
+		simulateRestart()
+	}()
+	for _, table := range logEpochTests {
+		t.Run(table.mode, func(t *testing.T) {
+			epochFile = filepath.Join(t.TempDir(), "epoch")
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			timeNow = func() time.Time { return now }
+			t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json")
+			t.Setenv("MQ_LOGGING_EPOCH", table.mode)
+			for _, expected := range table.expected {
+				simulateRestart()
+				mf, err := configureLogger("test")
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Configuring the logger again in the same process keeps the same epoch
+				mf, err = configureLogger("test")
+				if err != nil {
+					t.Fatal(err)
+				}
+				buf := captureConsole(t)
+				mf("{\"message\":\"Hello\"}", false)
+				expectedLine := "{\"ibm_epoch\":\"" + expected + "\",\"message\":\"Hello\"}\n"
+				if buf.String() != expectedLine {
+					t.Errorf("Expected %q; got %q", expectedLine, buf.String())
+				}
+				now = now.Add(time.Minute)
+			}
+		})
+	}
+}
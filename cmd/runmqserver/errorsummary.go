@@ -0,0 +1,115 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// errorSummary counts the error messages seen, or is nil if the summary is disabled
+var errorSummary *errorCounter
+
+// errorCount is the number of times an error message ID has been seen
+type errorCount struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+// errorCounter counts error messages by ID, so that the most common can be reported when the container stops
+type errorCounter struct {
+	mutex  sync.Mutex
+	top    int
+	counts map[string]int
+}
+
+func newErrorCounter(top int) *errorCounter {
+	return &errorCounter{
+		top:    top,
+		counts: make(map[string]int),
+	}
+}
+
+// record counts a message, if it is an error with a message ID
+func (c *errorCounter) record(obj map[string]interface{}) {
+	r := newMQLogRecord(obj)
+	id := r.MessageID()
+	if id == "" || r.Severity() < levelError {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.counts[id]++
+}
+
+// ranked returns the most common error message IDs, most common first.  IDs with the same count are sorted by ID.
+func (c *errorCounter) ranked() []errorCount {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ranked := make([]errorCount, 0, len(c.counts))
+	for id, n := range c.counts {
+		ranked = append(ranked, errorCount{ID: id, Count: n})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	if len(ranked) > c.top {
+		ranked = ranked[:c.top]
+	}
+	return ranked
+}
+
+// recordErrorMessage counts a message seen in the logs, if the error summary is enabled
+func recordErrorMessage(obj map[string]interface{}) {
+	if errorSummary != nil {
+		errorSummary.record(obj)
+	}
+}
+
+// emitErrorSummary emits an "error_summary" event, listing the most common error message IDs seen,
+// if the error summary is enabled
+func emitErrorSummary() {
+	if errorSummary == nil {
+		return
+	}
+	ranked := errorSummary.ranked()
+	summary := make([]string, 0, len(ranked))
+	for _, c := range ranked {
+		summary = append(summary, fmt.Sprintf("%v(%v)", c.ID, c.Count))
+	}
+	message := "No error messages were seen"
+	if len(ranked) > 0 {
+		message = fmt.Sprintf("Most common error messages: %v", strings.Join(summary, ", "))
+	}
+	emitEvent("INFO", "error_summary", message, map[string]interface{}{"ibm_errorCounts": ranked})
+}
+
+// configureErrorSummary enables the error summary, if MQ_LOGGING_ERROR_SUMMARY_TOP is set to the
+// number of message IDs to list
+func configureErrorSummary() error {
+	errorSummary = nil
+	top, err := getPositiveIntEnv("MQ_LOGGING_ERROR_SUMMARY_TOP", 0)
+	if err != nil || top == 0 {
+		return err
+	}
+	errorSummary = newErrorCounter(top)
+	return nil
+}
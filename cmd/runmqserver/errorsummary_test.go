@@ -0,0 +1,66 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorCounterRanked(t *testing.T) {
+	c := newErrorCounter(3)
+	seen := []struct {
+		id    string
+		level string
+		times int
+	}{
+		{"AMQ9209E", "ERROR", 2},
+		{"AMQ6119S", "SEVERE", 5},
+		{"AMQ9999E", "ERROR", 2},
+		{"AMQ7234E", "ERROR", 1},
+		{"AMQ5051I", "INFO", 10},
+		{"", "ERROR", 10},
+	}
+	for _, s := range seen {
+		for i := 0; i < s.times; i++ {
+			c.record(map[string]interface{}{"ibm_messageId": s.id, "loglevel": s.level})
+		}
+	}
+	expected := []errorCount{{"AMQ6119S", 5}, {"AMQ9209E", 2}, {"AMQ9999E", 2}}
+	if !reflect.DeepEqual(c.ranked(), expected) {
+		t.Errorf("Expected %v; got %v", expected, c.ranked())
+	}
+}
+
+func TestEmitErrorSummary(t *testing.T) {
+	oldSummary, oldJSON, oldNow := errorSummary, eventsJSON, timeNow
+	defer func() { errorSummary, eventsJSON, timeNow = oldSummary, oldJSON, oldNow }()
+	timeNow = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	eventsJSON = true
+	t.Setenv("MQ_LOGGING_ERROR_SUMMARY_TOP", "2")
+	err := configureErrorSummary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordErrorMessage(map[string]interface{}{"ibm_messageId": "AMQ9209E", "loglevel": "ERROR"})
+	buf := captureConsole(t)
+	emitErrorSummary()
+	if !strings.Contains(buf.String(), "\"ibm_errorCounts\":[{\"id\":\"AMQ9209E\",\"count\":1}]") {
+		t.Errorf("Expected a summary of error messages; got %v", buf.String())
+	}
+}
@@ -0,0 +1,100 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+)
+
+// eventTimestampFormat matches the format used by MQ messages (includes milliseconds)
+const eventTimestampFormat string = "2006-01-02T15:04:05.000Z07:00"
+
+// eventsJSON is true if events should be emitted in JSON format, to match the mirrored logs
+var eventsJSON = false
+
+// emitEvent writes a record describing something which has happened to the log mirroring itself,
+// into the mirrored log stream.  The event parameter is a short, stable identifier for the type of
+// event, and fields holds any additional information to include in JSON format.
+func emitEvent(level string, event string, message string, fields map[string]interface{}) {
+	obj := map[string]interface{}{
+		"ibm_datetime": timeNow().Format(eventTimestampFormat),
+		"loglevel":     level,
+		"type":         "mq_containerlog",
+		"ibm_event":    event,
+		"message":      message,
+	}
+	for k, v := range fields {
+		obj[k] = v
+	}
+	if eventsJSON {
+		b, err := json.Marshal(obj)
+		if err != nil {
+			log.Debugf("Unable to encode %v event: %v", event, err)
+			return
+		}
+		emitMirroredLine("", obj, string(b)+"\n")
+		return
+	}
+	emitMirroredLine("", obj, formatBasic(obj))
+}
+
+// readyEventOnce makes sure the ready event is only emitted once
+var readyEventOnce sync.Once
+
+// getReadyMessageIDs returns the message IDs which indicate the queue manager is ready, or nil if
+// the ready event is disabled.  The event is enabled with MQ_LOGGING_READY_EVENT, and the message
+// IDs can be set using MQ_LOGGING_READY_ID.
+func getReadyMessageIDs() []string {
+	enabled := os.Getenv("MQ_LOGGING_READY_EVENT")
+	if enabled != "true" && enabled != "1" {
+		return nil
+	}
+	ids := make([]string, 0)
+	for _, id := range strings.Split(strings.ToUpper(os.Getenv("MQ_LOGGING_READY_ID")), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		ids = append(ids, queueManagerStartedMessageID)
+	}
+	return ids
+}
+
+// isReadyMessage returns true if the log message has one of the ready message IDs
+func isReadyMessage(obj map[string]interface{}, readyIDs []string) bool {
+	id := newMQLogRecord(obj).MessageID()
+	if id == "" {
+		return false
+	}
+	for _, ready := range readyIDs {
+		if id == ready {
+			return true
+		}
+	}
+	return false
+}
+
+// emitReadyEvent emits the "mq_ready" event, the first time it is called
+func emitReadyEvent(messageID string) {
+	readyEventOnce.Do(func() {
+		emitEvent("INFO", "mq_ready", "Queue manager is ready", map[string]interface{}{"ibm_readyMessageId": messageID})
+	})
+}
@@ -0,0 +1,65 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadyEvent(t *testing.T) {
+	defer func() { readyEventOnce = sync.Once{} }()
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json")
+	t.Setenv("MQ_LOGGING_READY_EVENT", "true")
+	t.Setenv("MQ_LOGGING_READY_ID", "AMQ5026I")
+	oldLog := log
+	defer func() { log = oldLog }()
+	mf, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := captureConsole(t)
+	mf("{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: Not ready\"}", false)
+	if strings.Contains(buf.String(), "mq_ready") {
+		t.Fatalf("Expected no ready event before the ready message; got %v", buf.String())
+	}
+	mf("{\"ibm_messageId\":\"AMQ5026I\",\"message\":\"AMQ5026I: The listener has started\"}", false)
+	mf("{\"ibm_messageId\":\"AMQ5026I\",\"message\":\"AMQ5026I: The listener has started\"}", false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 lines; got %v", lines)
+	}
+	if !strings.Contains(lines[2], "\"ibm_event\":\"mq_ready\"") || !strings.Contains(lines[2], "\"ibm_readyMessageId\":\"AMQ5026I\"") {
+		t.Errorf("Expected ready event after the ready message; got %v", lines[2])
+	}
+	if strings.Contains(lines[3], "mq_ready") {
+		t.Errorf("Expected ready event to be emitted only once; got %v", lines[3])
+	}
+}
+
+func TestReadyEventDisabled(t *testing.T) {
+	t.Setenv("MQ_LOGGING_READY_EVENT", "")
+	if getReadyMessageIDs() != nil {
+		t.Error("Expected ready event to be disabled by default")
+	}
+	t.Setenv("MQ_LOGGING_READY_EVENT", "true")
+	t.Setenv("MQ_LOGGING_READY_ID", "")
+	ids := getReadyMessageIDs()
+	if len(ids) != 1 || ids[0] != queueManagerStartedMessageID {
+		t.Errorf("Expected default ready message ID %v; got %v", queueManagerStartedMessageID, ids)
+	}
+}
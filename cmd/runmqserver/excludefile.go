@@ -0,0 +1,126 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// excludeFileIDs holds the message IDs read from MQ_LOGGING_CONSOLE_EXCLUDE_FILE, as a []string.  It is
+// replaced as a whole when the file changes, so that the mirror functions can read it without locking.
+var excludeFileIDs atomic.Value
+
+// excludeFilePollInterval is how often the exclude file is checked for changes, when it is being watched
+var excludeFilePollInterval = 2 * time.Second
+
+// stopExcludeFileWatch stops the goroutine watching the exclude file, or is nil if the file isn't being watched
+var stopExcludeFileWatch chan struct{}
+
+// excludeFileWatchWG waits for the goroutine watching the exclude file to finish
+var excludeFileWatchWG sync.WaitGroup
+
+// loadExcludeFile reads a file of message IDs to exclude, with one ID per line.  Empty lines, and
+// lines starting with "#", are ignored.
+func loadExcludeFile(path string) ([]string, error) {
+	// #nosec G304 - the path is provided by the operator, and the file is only read
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0)
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids = append(ids, strings.ToUpper(line))
+	}
+	return ids, nil
+}
+
+// getExcludeIDs returns the message IDs to exclude from MQ_LOGGING_CONSOLE_EXCLUDE_ID, followed by any
+// from MQ_LOGGING_CONSOLE_EXCLUDE_FILE
+func getExcludeIDs() []string {
+	ids := strings.Split(strings.ToUpper(os.Getenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID")), ",")
+	if fileIDs, ok := excludeFileIDs.Load().([]string); ok {
+		ids = append(ids, fileIDs...)
+	}
+	return ids
+}
+
+// watchExcludeFile polls the exclude file, and replaces the excluded message IDs when its contents change.
+// If the file can't be read, the previous IDs are kept.
+func watchExcludeFile(path string, stop chan struct{}) {
+	ticker := time.NewTicker(excludeFilePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ids, err := loadExcludeFile(path)
+			if err != nil {
+				log.Debugf("Unable to reload exclude file %v: %v", path, err)
+				continue
+			}
+			if old, _ := excludeFileIDs.Load().([]string); !reflect.DeepEqual(old, ids) {
+				excludeFileIDs.Store(ids)
+				log.Printf("Reloaded %v excluded message IDs from %v", len(ids), path)
+			}
+		}
+	}
+}
+
+// configureExcludeFile reads the message IDs to exclude from MQ_LOGGING_CONSOLE_EXCLUDE_FILE, if it is set.
+// If MQ_LOGGING_CONSOLE_EXCLUDE_FILE_WATCH is set, the file is re-read whenever it changes.
+func configureExcludeFile() error {
+	closeExcludeFileWatch()
+	excludeFileIDs.Store([]string{})
+	path := strings.TrimSpace(os.Getenv("MQ_LOGGING_CONSOLE_EXCLUDE_FILE"))
+	if path == "" {
+		return nil
+	}
+	ids, err := loadExcludeFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read MQ_LOGGING_CONSOLE_EXCLUDE_FILE: %v", err)
+	}
+	excludeFileIDs.Store(ids)
+	watch := os.Getenv("MQ_LOGGING_CONSOLE_EXCLUDE_FILE_WATCH")
+	if watch == "true" || watch == "1" {
+		stopExcludeFileWatch = make(chan struct{})
+		excludeFileWatchWG.Add(1)
+		go func(stop chan struct{}) {
+			defer excludeFileWatchWG.Done()
+			watchExcludeFile(path, stop)
+		}(stopExcludeFileWatch)
+	}
+	return nil
+}
+
+// closeExcludeFileWatch stops watching the exclude file for changes, if it is being watched
+func closeExcludeFileWatch() {
+	if stopExcludeFileWatch != nil {
+		close(stopExcludeFileWatch)
+		stopExcludeFileWatch = nil
+		excludeFileWatchWG.Wait()
+	}
+}
@@ -0,0 +1,99 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadExcludeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exclude.txt")
+	os.WriteFile(path, []byte("# Noisy messages\namq5051i\n\n  AMQ5041I  \n"), 0600)
+	ids, err := loadExcludeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"AMQ5051I", "AMQ5041I"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected %v; got %v", expected, ids)
+	}
+}
+
+func TestExcludeFileWatch(t *testing.T) {
+	oldInterval := excludeFilePollInterval
+	defer func() {
+		excludeFilePollInterval = oldInterval
+		os.Unsetenv("MQ_LOGGING_CONSOLE_EXCLUDE_FILE")
+		configureExcludeFile()
+	}()
+	excludeFilePollInterval = 50 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "exclude.txt")
+	os.WriteFile(path, []byte("AMQ5051I\n"), 0600)
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID", "")
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_FILE", path)
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_FILE_WATCH", "true")
+	err := configureExcludeFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := "{\"ibm_messageId\":\"AMQ5051I\"}"
+	ended := "{\"ibm_messageId\":\"AMQ5041I\"}"
+	if excludedMsgId(started, getExcludeIDs()) != "AMQ5051I" || excludedMsgId(ended, getExcludeIDs()) != "" {
+		t.Fatalf("Expected only AMQ5051I to be excluded; got %v", getExcludeIDs())
+	}
+	os.WriteFile(path, []byte("AMQ5041I\n"), 0600)
+	deadline := time.Now().Add(2 * time.Second)
+	for excludedMsgId(ended, getExcludeIDs()) == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if excludedMsgId(started, getExcludeIDs()) != "" || excludedMsgId(ended, getExcludeIDs()) != "AMQ5041I" {
+		t.Errorf("Expected only AMQ5041I to be excluded after the file changed; got %v", getExcludeIDs())
+	}
+}
+
+func TestExcludeFileWatchDrained(t *testing.T) {
+	oldInterval := excludeFilePollInterval
+	defer func() {
+		excludeFilePollInterval = oldInterval
+		loggingDrained = false
+		os.Unsetenv("MQ_LOGGING_CONSOLE_EXCLUDE_FILE")
+		configureExcludeFile()
+	}()
+	excludeFilePollInterval = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "exclude.txt")
+	os.WriteFile(path, []byte("AMQ5051I\n"), 0600)
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID", "")
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_FILE", path)
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_FILE_WATCH", "true")
+	err := configureExcludeFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	drainLogging()
+	if stopExcludeFileWatch != nil {
+		t.Error("Expected the exclude file to stop being watched when logging is drained")
+	}
+	// The file is no longer re-read once logging has been drained
+	os.WriteFile(path, []byte("AMQ5041I\n"), 0600)
+	time.Sleep(50 * time.Millisecond)
+	if excludedMsgId("{\"ibm_messageId\":\"AMQ5041I\"}", getExcludeIDs()) != "" {
+		t.Errorf("Expected the exclude file not to be re-read after logging was drained; got %v", getExcludeIDs())
+	}
+}
@@ -0,0 +1,64 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// getExcludeRegexps compiles the regular expressions in MQ_LOGGING_CONSOLE_EXCLUDE_REGEX, which are
+// separated by commas or new lines.  An expression which can't be compiled is skipped, with a warning.
+func getExcludeRegexps() []*regexp.Regexp {
+	res := make([]*regexp.Regexp, 0)
+	for _, s := range strings.FieldsFunc(os.Getenv("MQ_LOGGING_CONSOLE_EXCLUDE_REGEX"), func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			log.Printf("Warning: ignoring invalid regular expression in MQ_LOGGING_CONSOLE_EXCLUDE_REGEX: %v", err)
+			continue
+		}
+		res = append(res, re)
+	}
+	return res
+}
+
+// excludedRegexp returns the first regular expression which matches the given log line, or an empty
+// string if none match
+func excludedRegexp(msg string, res []*regexp.Regexp) string {
+	for _, re := range res {
+		if re.MatchString(msg) {
+			return re.String()
+		}
+	}
+	return ""
+}
+
+// excludedRule returns the message ID in MQ_LOGGING_CONSOLE_EXCLUDE_ID or the regular expression in
+// MQ_LOGGING_CONSOLE_EXCLUDE_REGEX which excludes the given log line, or an empty string if it isn't
+// excluded
+func excludedRule(msg string, opts mirrorOptions) string {
+	if id := excludedMsgId(msg, getExcludeIDs()); id != "" {
+		return id
+	}
+	return excludedRegexp(msg, opts.excludeRegexps)
+}
@@ -0,0 +1,62 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGetExcludeRegexps(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_REGEX", "^\\{.*\"ibm_messageId\":\"AMQ6287I\", AMQ5(\n[invalid\n,")
+	buf := captureLog(t)
+	res := getExcludeRegexps()
+	if len(res) != 1 || res[0].String() != "^\\{.*\"ibm_messageId\":\"AMQ6287I\"" {
+		t.Errorf("Expected one regular expression; got %v", res)
+	}
+	if n := strings.Count(buf.String(), "Warning: ignoring invalid regular expression"); n != 2 {
+		t.Errorf("Expected a warning for each invalid regular expression; got %q", buf.String())
+	}
+}
+
+func TestExcludeRegexp(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID", "AMQ5041I")
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_REGEX", "\"ibm_messageId\":\"AMQ6287I\"\n^Skip")
+	opts := mirrorOptions{excludeRegexps: getExcludeRegexps()}
+	var tests = []struct {
+		msg      string
+		mirrored bool
+	}{
+		{"{\"ibm_messageId\":\"AMQ6287I\",\"message\":\"AMQ6287I: MQ V9.3\"}", false},
+		// The ID is only excluded by the regular expression where it is the message ID
+		{"{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: Related to AMQ6287I\"}", true},
+		{"{\"ibm_messageId\":\"AMQ5041I\",\"message\":\"AMQ5041I: Ended\"}", false},
+		{"Skip this line", false},
+		{"Don't skip this line", true},
+	}
+	for _, format := range []string{"json", "basic"} {
+		for i, table := range tests {
+			t.Run(fmt.Sprintf("%v-%v", format, i), func(t *testing.T) {
+				buf := captureConsole(t)
+				mirrored := newMirrorFunc(format, opts)(table.msg, true)
+				if mirrored != table.mirrored || (buf.Len() > 0) != table.mirrored {
+					t.Errorf("Expected mirrored to be %v for %v; got %v with %q", table.mirrored, table.msg, mirrored, buf.String())
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// fatalMessageOnce makes sure termination is only requested once, however many fatal messages are seen
+var fatalMessageOnce sync.Once
+
+// fatalMessageReason is the reason for termination, if a fatal message has been seen
+var fatalMessageReason string
+var fatalMessageMutex sync.Mutex
+
+// getFatalMessageReason returns the reason for termination, or an empty string if no fatal message has been seen
+func getFatalMessageReason() string {
+	fatalMessageMutex.Lock()
+	defer fatalMessageMutex.Unlock()
+	return fatalMessageReason
+}
+
+// requestTermination asks the process to shut down, in the same way as if the container was stopped.
+// It is a variable to allow it to be replaced during testing.
+var requestTermination = func() {
+	// #nosec G104 - signalling our own process can't reasonably fail
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+}
+
+// getFatalMessageIDs returns the message IDs (or prefixes of message IDs) in MQ_LOGGING_FATAL_ID,
+// which cause the container to terminate when they are logged
+func getFatalMessageIDs() []string {
+	ids := make([]string, 0)
+	for _, id := range strings.Split(strings.ToUpper(os.Getenv("MQ_LOGGING_FATAL_ID")), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// checkFatalMessage terminates the container if the log message has one of the fatal message IDs.
+// Returns true if the message was fatal.
+func checkFatalMessage(obj map[string]interface{}, fatalIDs []string) bool {
+	if len(fatalIDs) == 0 {
+		return false
+	}
+	r := newMQLogRecord(obj)
+	id := r.MessageID()
+	if id == "" {
+		return false
+	}
+	for _, fatal := range fatalIDs {
+		if strings.HasPrefix(id, fatal) {
+			terminateWithReason(fmt.Sprintf("Terminating because fatal message %v was logged: %v", id, r.Message()))
+			return true
+		}
+	}
+	return false
+}
+
+// terminateWithReason logs the reason for termination, and asks the process to shut down.
+// Only the first reason is used, if termination is requested more than once.
+func terminateWithReason(reason string) {
+	fatalMessageOnce.Do(func() {
+		fatalMessageMutex.Lock()
+		fatalMessageReason = reason
+		fatalMessageMutex.Unlock()
+		logTermination(reason)
+		requestTermination()
+	})
+}
@@ -0,0 +1,69 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCheckFatalMessage(t *testing.T) {
+	captureLog(t)
+	oldPath, oldRequest := terminationLogPath, requestTermination
+	defer func() {
+		terminationLogPath, requestTermination = oldPath, oldRequest
+		fatalMessageOnce = sync.Once{}
+		fatalMessageReason = ""
+	}()
+	terminationLogPath = filepath.Join(t.TempDir(), "termination-log")
+	requested := 0
+	requestTermination = func() { requested++ }
+	t.Setenv("MQ_LOGGING_FATAL_ID", "amq5008, AMQ9999E")
+	ids := getFatalMessageIDs()
+
+	if checkFatalMessage(map[string]interface{}{"ibm_messageId": "AMQ5051I", "message": "Started"}, ids) {
+		t.Error("Expected AMQ5051I not to be fatal")
+	}
+	if requested != 0 {
+		t.Errorf("Expected no termination request; got %v", requested)
+	}
+	obj := map[string]interface{}{"ibm_messageId": "AMQ5008E", "message": "An essential IBM MQ process 1234 (amqzxma0) cannot be found"}
+	if !checkFatalMessage(obj, ids) {
+		t.Error("Expected AMQ5008E to be fatal")
+	}
+	// A second fatal message shouldn't request termination again
+	checkFatalMessage(obj, ids)
+	if requested != 1 {
+		t.Errorf("Expected one termination request; got %v", requested)
+	}
+	b, err := os.ReadFile(terminationLogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "AMQ5008E") {
+		t.Errorf("Expected termination log to contain the fatal message ID; got %v", string(b))
+	}
+}
+
+func TestCheckFatalMessageDisabled(t *testing.T) {
+	t.Setenv("MQ_LOGGING_FATAL_ID", "")
+	if checkFatalMessage(map[string]interface{}{"ibm_messageId": "AMQ5008E"}, getFatalMessageIDs()) {
+		t.Error("Expected no message to be fatal by default")
+	}
+}
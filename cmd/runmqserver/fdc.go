@@ -0,0 +1,191 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fdcDir is the directory where MQ writes FDC files
+var fdcDir = "/var/mqm/errors"
+
+// fdcPollInterval is how often the FDC directory is checked for new files
+var fdcPollInterval = 2 * time.Second
+
+// fdcProbeAttempts is the number of times to read a new FDC file, waiting for its probe ID to be written,
+// before emitting the event without one
+const fdcProbeAttempts = 3
+
+// defaultFDCDiagnosticsInterval is the minimum time between collecting diagnostics for new FDC files
+const defaultFDCDiagnosticsInterval = 5 * time.Minute
+
+// collectDiagnostics logs diagnostic information.  It is a variable to allow it to be replaced during testing.
+var collectDiagnostics = logDiagnostics
+
+// fdcProbeID matches the probe ID in the header of an FDC file
+var fdcProbeID = regexp.MustCompile(`Probe Id\s*:-\s*(\S+)`)
+
+// getFDCEventEnabled returns true if an event should be emitted whenever a new FDC file is created
+func getFDCEventEnabled() bool {
+	enabled := os.Getenv("MQ_LOGGING_FDC_EVENT")
+	return enabled == "true" || enabled == "1"
+}
+
+// readFDCProbeID returns the probe ID from the header of an FDC file, or an empty string if the header
+// hasn't been written yet
+func readFDCProbeID(path string) (string, error) {
+	// #nosec G304 - the path is in the MQ errors directory, and the file is only read
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	// The probe ID is near the start of the header, so there's no need to read the whole file
+	for i := 0; i < 50 && scanner.Scan(); i++ {
+		m := fdcProbeID.FindStringSubmatch(scanner.Text())
+		if m != nil {
+			return m[1], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// listFDCs returns the names of the FDC files in a directory
+func listFDCs(dir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".FDC") {
+			names[e.Name()] = true
+		}
+	}
+	return names, nil
+}
+
+// diagnosticsCooldown makes sure that diagnostics are collected at most once in each interval, however
+// often they are triggered, so that a burst of FDC files doesn't flood the console
+type diagnosticsCooldown struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	last     time.Time
+}
+
+// trigger collects diagnostics, unless they have already been collected within the interval.  It
+// returns true if they were collected.
+func (c *diagnosticsCooldown) trigger() bool {
+	c.mutex.Lock()
+	now := timeNow()
+	if !c.last.IsZero() && now.Sub(c.last) < c.interval {
+		c.mutex.Unlock()
+		return false
+	}
+	c.last = now
+	c.mutex.Unlock()
+	collectDiagnostics()
+	return true
+}
+
+// getFDCDiagnostics returns the cooldown for collecting diagnostics when an FDC file is created, or nil
+// if MQ_LOGGING_FDC_DIAGNOSTICS isn't enabled.  The interval is set with MQ_LOGGING_FDC_DIAGNOSTICS_INTERVAL.
+func getFDCDiagnostics() (*diagnosticsCooldown, error) {
+	enabled := os.Getenv("MQ_LOGGING_FDC_DIAGNOSTICS")
+	if enabled != "true" && enabled != "1" {
+		return nil, nil
+	}
+	interval, err := getDurationEnv("MQ_LOGGING_FDC_DIAGNOSTICS_INTERVAL", defaultFDCDiagnosticsInterval)
+	if err != nil {
+		return nil, err
+	}
+	return &diagnosticsCooldown{interval: interval}, nil
+}
+
+// emitFDCEvent emits an "fdc_created" event for an FDC file
+func emitFDCEvent(name string, probeID string) {
+	fields := map[string]interface{}{"ibm_fdcFile": name}
+	msg := fmt.Sprintf("FDC file %v created", name)
+	if probeID != "" {
+		fields["ibm_probeId"] = probeID
+		msg = fmt.Sprintf("FDC file %v created, with probe ID %v", name, probeID)
+	}
+	emitEvent("ERROR", "fdc_created", msg, fields)
+}
+
+// watchFDCs polls the FDC directory until the context is cancelled, and emits an event for each FDC file
+// created after it started.  Files which already exist when it starts are ignored.  If enabled,
+// diagnostics are also collected, subject to a cooldown.
+func watchFDCs(ctx context.Context, wg *sync.WaitGroup, dir string) error {
+	diagnostics, err := getFDCDiagnostics()
+	if err != nil {
+		return err
+	}
+	seen, err := listFDCs(dir)
+	if err != nil {
+		log.Debugf("Unable to list FDC files in %v: %v", dir, err)
+		seen = make(map[string]bool)
+	}
+	// Number of times each new file has been read without finding a probe ID
+	pending := make(map[string]int)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(fdcPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				names, err := listFDCs(dir)
+				if err != nil {
+					log.Debugf("Unable to list FDC files in %v: %v", dir, err)
+					continue
+				}
+				for name := range names {
+					if seen[name] {
+						continue
+					}
+					probeID, err := readFDCProbeID(filepath.Join(dir, name))
+					if err != nil {
+						log.Debugf("Unable to read FDC file %v: %v", name, err)
+					}
+					if probeID == "" && pending[name] < fdcProbeAttempts-1 {
+						pending[name]++
+						continue
+					}
+					delete(pending, name)
+					seen[name] = true
+					emitFDCEvent(name, probeID)
+					if diagnostics != nil {
+						diagnostics.trigger()
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
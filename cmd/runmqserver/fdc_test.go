@@ -0,0 +1,102 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testFDCHeader = `+-----------------------------------------------------------------------------+
+|                                                                             |
+| IBM MQ First Failure Symptom Report                                         |
+| ===================================                                         |
+|                                                                             |
+| Date/Time         :- Mon January 01 2024 12:00:00 UTC                       |
+| Host Name         :- mqhost                                                 |
+| Probe Id          :- XC130031                                               |
+| Component         :- xehExceptionHandler                                    |
++-----------------------------------------------------------------------------+
+`
+
+func TestWatchFDCs(t *testing.T) {
+	oldInterval, oldJSON := fdcPollInterval, eventsJSON
+	defer func() { fdcPollInterval, eventsJSON = oldInterval, oldJSON }()
+	fdcPollInterval = 50 * time.Millisecond
+	eventsJSON = true
+	buf := captureConsole(t)
+	dir := t.TempDir()
+	// An FDC which already exists isn't reported
+	os.WriteFile(filepath.Join(dir, "AMQ100.0.FDC"), []byte(testFDCHeader), 0600)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	err := watchFDCs(ctx, &wg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "AMQ200.0.FDC"), []byte(testFDCHeader), 0600)
+	os.WriteFile(filepath.Join(dir, "AMQERR01.LOG"), []byte("Not an FDC\n"), 0600)
+	time.Sleep(500 * time.Millisecond)
+	cancel()
+	wg.Wait()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one event; got %v", buf.String())
+	}
+	var obj map[string]interface{}
+	err = json.Unmarshal([]byte(lines[0]), &obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj["ibm_event"] != "fdc_created" || obj["ibm_fdcFile"] != "AMQ200.0.FDC" || obj["ibm_probeId"] != "XC130031" {
+		t.Errorf("Expected an event for the new FDC; got %v", lines[0])
+	}
+}
+
+func TestFDCDiagnosticsCooldown(t *testing.T) {
+	oldCollect, oldNow := collectDiagnostics, timeNow
+	defer func() { collectDiagnostics, timeNow = oldCollect, oldNow }()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	collected := 0
+	collectDiagnostics = func() { collected++ }
+	t.Setenv("MQ_LOGGING_FDC_DIAGNOSTICS", "true")
+	t.Setenv("MQ_LOGGING_FDC_DIAGNOSTICS_INTERVAL", "1m")
+	c, err := getFDCDiagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A burst of FDCs only collects diagnostics once
+	for i := 0; i < 10; i++ {
+		c.trigger()
+		now = now.Add(time.Second)
+	}
+	if collected != 1 {
+		t.Errorf("Expected diagnostics to be collected once; got %v", collected)
+	}
+	// Once the interval has passed, diagnostics are collected again
+	now = now.Add(time.Minute)
+	c.trigger()
+	if collected != 2 {
+		t.Errorf("Expected diagnostics to be collected again after the interval; got %v", collected)
+	}
+}
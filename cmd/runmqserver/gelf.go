@@ -0,0 +1,113 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// gelfVersion is the version of the Graylog Extended Log Format used for the "gelf" format
+const gelfVersion = "1.1"
+
+// gelfFields are the MQ and Liberty fields which are used for a GELF field, rather than being kept as an
+// additional field
+var gelfFields = map[string]bool{
+	"ibm_datetime": true,
+	"loglevel":     true,
+	"message":      true,
+	"host":         true,
+}
+
+// gelfInvalidKeyChars matches the characters which GELF doesn't allow in the name of an additional field
+var gelfInvalidKeyChars = regexp.MustCompile(`[^\w.\-]`)
+
+// gelfKey returns the name of the GELF additional field for a field, which must start with an underscore.
+// GELF reserves "_id", so an "id" field is kept as "__id".
+func gelfKey(field string) string {
+	key := "_" + gelfInvalidKeyChars.ReplaceAllString(field, "_")
+	if key == "_id" {
+		return "__id"
+	}
+	return key
+}
+
+// gelfValue returns a field value as a GELF additional field, which must be a string or a number
+func gelfValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string, float64:
+		return v
+	}
+	return ecsLabel(v)
+}
+
+// gelfHost returns the host for a GELF message, which is required
+func gelfHost(r MQLogRecord) string {
+	if host := r.Field("host"); host != "" {
+		return host
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "unknown"
+}
+
+// toGELF converts a parsed JSON log message to GELF.  The first line of the message is used as the short
+// message, and the whole message is only included as the full message if it has more than one line.
+func toGELF(obj map[string]interface{}) map[string]interface{} {
+	r := newMQLogRecord(obj)
+	message := r.Message()
+	short := strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+	if short == "" {
+		// The short message is required, and can't be empty
+		short = "-"
+	}
+	dt := r.Datetime()
+	if dt.IsZero() {
+		dt = timeNow()
+	}
+	out := map[string]interface{}{
+		"version":       gelfVersion,
+		"host":          gelfHost(r),
+		"short_message": short,
+		"timestamp":     float64(dt.UnixMilli()) / 1000,
+		"level":         severityNumber(r.Severity(), severityNumberSyslog),
+	}
+	if short != message && message != "" {
+		out["full_message"] = message
+	}
+	for k, v := range obj {
+		if gelfFields[k] {
+			continue
+		}
+		out[gelfKey(k)] = gelfValue(v)
+	}
+	return out
+}
+
+// formatGELFLine converts a JSON log line to GELF.  If the line can't be parsed, it is wrapped as the
+// message.
+func formatGELFLine(line string) string {
+	obj, err := processLogMessage(line)
+	if err != nil {
+		obj = map[string]interface{}{"message": line}
+	}
+	// #nosec G104 - the fields are all strings or numbers, so the message can always be marshalled
+	b, _ := json.Marshal(toGELF(obj))
+	return string(b)
+}
@@ -0,0 +1,97 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGELFFormat(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	oldTimeNow := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = oldTimeNow })
+	var tests = []struct {
+		name     string
+		msg      string
+		expected string
+	}{
+		{
+			name:     "MQ",
+			msg:      "{\"ibm_datetime\":\"2024-02-29T10:15:00.123Z\",\"loglevel\":\"WARNING\",\"host\":\"mq1\",\"ibm_messageId\":\"AMQ9209E\",\"message\":\"AMQ9209E: Connection closed.\",\"ibm_processId\":1234,\"ibm_serverName\":\"QM1\"}",
+			expected: "{\"_ibm_messageId\":\"AMQ9209E\",\"_ibm_processId\":1234,\"_ibm_serverName\":\"QM1\",\"host\":\"mq1\",\"level\":4,\"short_message\":\"AMQ9209E: Connection closed.\",\"timestamp\":1709201700.123,\"version\":\"1.1\"}\n",
+		},
+		{
+			name:     "Multi-line message",
+			msg:      "{\"ibm_datetime\":\"2024-02-29T10:15:00.123Z\",\"loglevel\":\"ERROR\",\"host\":\"mq1\",\"message\":\"AMQ6119S: An internal error occurred.\\nEXPLANATION: ...\",\"id\":\"x\",\"ibm inserts\":[\"a\"]}",
+			expected: "{\"__id\":\"x\",\"_ibm_inserts\":\"[\\\"a\\\"]\",\"full_message\":\"AMQ6119S: An internal error occurred.\\nEXPLANATION: ...\",\"host\":\"mq1\",\"level\":3,\"short_message\":\"AMQ6119S: An internal error occurred.\",\"timestamp\":1709201700.123,\"version\":\"1.1\"}\n",
+		},
+	}
+	for _, table := range tests {
+		t.Run(table.name, func(t *testing.T) {
+			buf := captureConsole(t)
+			newMirrorFunc("gelf", mirrorOptions{})(table.msg, false)
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestGELFFormatNotJSON(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	oldTimeNow := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = oldTimeNow })
+	buf := captureConsole(t)
+	newMirrorFunc("gelf", mirrorOptions{})("Plain text \"message\"", false)
+	var obj map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &obj); err != nil {
+		t.Fatalf("Expected valid JSON; got %q: %v", buf.String(), err)
+	}
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "unknown"
+	}
+	expected := map[string]interface{}{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": "Plain text \"message\"",
+		"timestamp":     float64(now.Unix()),
+		"level":         float64(6),
+	}
+	if len(obj) != len(expected) {
+		t.Errorf("Expected %v; got %v", expected, obj)
+	}
+	for k, v := range expected {
+		if obj[k] != v {
+			t.Errorf("Expected %v to be %v; got %v", k, v, obj[k])
+		}
+	}
+}
+
+func TestGELFShortMessageNotEmpty(t *testing.T) {
+	out := toGELF(map[string]interface{}{"message": "", "host": "mq1"})
+	if out["short_message"] != "-" {
+		t.Errorf("Expected a placeholder short message; got %q", out["short_message"])
+	}
+	if _, ok := out["full_message"]; ok {
+		t.Errorf("Expected no full message; got %q", out["full_message"])
+	}
+}
@@ -0,0 +1,135 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// globPollInterval is how often new files matching a glob pattern are looked for, while mirroring the
+// files which match it
+var globPollInterval = 5 * time.Second
+
+// rotatedErrorLogPattern matches the names of the error logs which MQ rotates the live log into, such as
+// AMQERR02.json and AMQERR03.json
+var rotatedErrorLogPattern = regexp.MustCompile(`^AMQERR(0[2-9]|[1-9][0-9])\.(json|LOG)$`)
+
+// isRotatedErrorLog returns true if a path is an error log which MQ rotates the live log into.  Each of
+// these files is replaced by the previous one on every rotation, so following them would mirror the
+// messages from the live log again.
+func isRotatedErrorLog(path string) bool {
+	return rotatedErrorLogPattern.MatchString(filepath.Base(path))
+}
+
+// getErrorLogGlob returns the glob pattern in MQ_LOGGING_QMGR_ERROR_LOG_GLOB, for the queue manager error
+// logs to mirror instead of the queue manager's AMQERR01.json, or an empty string if it isn't set
+func getErrorLogGlob() (string, error) {
+	pattern := strings.TrimSpace(os.Getenv("MQ_LOGGING_QMGR_ERROR_LOG_GLOB"))
+	if pattern == "" {
+		return "", nil
+	}
+	// Glob ignores I/O errors, so the only error it returns is for a bad pattern
+	_, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid value for MQ_LOGGING_QMGR_ERROR_LOG_GLOB: %v", pattern)
+	}
+	return pattern, nil
+}
+
+// mirrorLogGlob starts mirroring each of the files which match a glob pattern, with one goroutine for each
+// file.  The files are checked for regularly, and any new file which matches is mirrored from the start.
+// Error logs which MQ rotates the live log into are skipped, because the live log is followed through
+// rotation, so a pattern such as "AMQERR0*.json" only mirrors AMQERR01.json.  An error from mirroring any
+// of the files is sent to the returned channel.
+func mirrorLogGlob(ctx context.Context, wg *sync.WaitGroup, source string, pattern string, fromStart bool, mf mirrorFunc, isQMLog bool) (chan error, error) {
+	errorChannel := make(chan error, 1)
+	mirrored := make(map[string]bool)
+	// mirror starts mirroring any files which match the pattern, and aren't already being mirrored
+	mirror := func(fromStart bool) ([]string, error) {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		added := make([]string, 0)
+		for _, path := range matches {
+			if mirrored[path] {
+				continue
+			}
+			if isRotatedErrorLog(path) {
+				log.Debugf("Not mirroring %v, because it holds messages rotated from the live log", path)
+				mirrored[path] = true
+				continue
+			}
+			errs, err := mirrorLog(ctx, wg, source, path, fromStart, mf, isQMLog)
+			if err != nil {
+				return added, err
+			}
+			mirrored[path] = true
+			added = append(added, path)
+			// Merge the errors from each file into the one channel
+			go func() {
+				select {
+				case err := <-errs:
+					select {
+					case errorChannel <- err:
+					case <-ctx.Done():
+					}
+				case <-ctx.Done():
+				}
+			}()
+		}
+		return added, nil
+	}
+	added, err := mirror(fromStart)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Mirroring %v log from %v files matching %v", source, len(added), pattern)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(globPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// The file didn't exist when mirroring started, so read it from the start
+				added, err := mirror(true)
+				for _, path := range added {
+					emitEvent("INFO", "log_path_added", fmt.Sprintf("Mirroring %v log from new file %v", source, path), map[string]interface{}{"ibm_path": path})
+				}
+				if err != nil {
+					log.Error(err)
+					select {
+					case errorChannel <- err:
+					case <-ctx.Done():
+					}
+					return
+				}
+			}
+		}
+	}()
+	return errorChannel, nil
+}
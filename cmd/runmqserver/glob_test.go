@@ -0,0 +1,174 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMirrorLogGlob(t *testing.T) {
+	oldInterval := globPollInterval
+	globPollInterval = 100 * time.Millisecond
+	t.Cleanup(func() { globPollInterval = oldInterval })
+	dir := t.TempDir()
+	for _, name := range []string{"QM1", "QM2"} {
+		os.MkdirAll(filepath.Join(dir, name, "errors"), 0700)
+		os.WriteFile(filepath.Join(dir, name, "errors", "AMQERR01.json"), []byte("{\"message\":\""+name+"\"}\n"), 0600)
+	}
+	// Files which don't match the pattern aren't mirrored
+	os.WriteFile(filepath.Join(dir, "QM1", "errors", "AMQERR02.json"), []byte("{\"message\":\"Old\"}\n"), 0600)
+	var mutex sync.Mutex
+	var msgs []string
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	_, err := mirrorLogGlob(ctx, &wg, "qmgr", filepath.Join(dir, "*", "errors", "AMQERR01.json"), true, func(msg string, isQMLog bool) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		msgs = append(msgs, msg)
+		return true
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForMessages := func(n int) []string {
+		for i := 0; i < 50; i++ {
+			mutex.Lock()
+			got := append([]string{}, msgs...)
+			mutex.Unlock()
+			if len(got) >= n {
+				sort.Strings(got)
+				return got
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for %v messages; got %v", n, msgs)
+		return nil
+	}
+	waitForMessages(2)
+
+	// A new file which matches the pattern is picked up
+	os.MkdirAll(filepath.Join(dir, "QM3", "errors"), 0700)
+	os.WriteFile(filepath.Join(dir, "QM3", "errors", "AMQERR01.json"), []byte("{\"message\":\"QM3\"}\n"), 0600)
+	got := waitForMessages(3)
+	expected := []string{"{\"message\":\"QM1\"}", "{\"message\":\"QM2\"}", "{\"message\":\"QM3\"}"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v; got %v", expected, got)
+	}
+}
+
+func TestGetErrorLogGlob(t *testing.T) {
+	t.Setenv("MQ_LOGGING_QMGR_ERROR_LOG_GLOB", "/var/mqm/qmgrs/*/errors/AMQERR01.json")
+	if pattern, err := getErrorLogGlob(); err != nil || pattern != "/var/mqm/qmgrs/*/errors/AMQERR01.json" {
+		t.Errorf("Expected the pattern; got %v, %v", pattern, err)
+	}
+	t.Setenv("MQ_LOGGING_QMGR_ERROR_LOG_GLOB", "/var/mqm/qmgrs/[/errors")
+	if _, err := getErrorLogGlob(); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestMirrorLogGlobRotation(t *testing.T) {
+	oldInterval := globPollInterval
+	globPollInterval = 100 * time.Millisecond
+	t.Cleanup(func() { globPollInterval = oldInterval })
+	dir := t.TempDir()
+	write := func(name string, data string) {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("AMQERR01.json", "{\"message\":\"A\"}\n")
+	write("AMQERR02.json", "{\"message\":\"Old\"}\n")
+	var mutex sync.Mutex
+	var msgs []string
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	_, err := mirrorLogGlob(ctx, &wg, "qmgr", filepath.Join(dir, "AMQERR0*.json"), true, func(msg string, isQMLog bool) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		msgs = append(msgs, msg)
+		return true
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForMessages := func(n int) []string {
+		for i := 0; i < 50; i++ {
+			mutex.Lock()
+			got := append([]string{}, msgs...)
+			mutex.Unlock()
+			if len(got) >= n {
+				return got
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for %v messages; got %v", n, msgs)
+		return nil
+	}
+	waitForMessages(1)
+
+	// Rotate the logs in the same way as MQ, so that AMQERR01.json becomes AMQERR02.json, and so on
+	os.Rename(filepath.Join(dir, "AMQERR02.json"), filepath.Join(dir, "AMQERR03.json"))
+	os.Rename(filepath.Join(dir, "AMQERR01.json"), filepath.Join(dir, "AMQERR02.json"))
+	write("AMQERR01.json", "{\"message\":\"B\"}\n")
+	waitForMessages(2)
+	// Allow time for any rotated file to be picked up, and mirrored again
+	time.Sleep(5 * globPollInterval)
+	mutex.Lock()
+	got := append([]string{}, msgs...)
+	mutex.Unlock()
+	expected := []string{"{\"message\":\"A\"}", "{\"message\":\"B\"}"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v; got %v", expected, got)
+	}
+}
+
+func TestIsRotatedErrorLog(t *testing.T) {
+	var tests = []struct {
+		path     string
+		expected bool
+	}{
+		{"/var/mqm/qmgrs/QM1/errors/AMQERR01.json", false},
+		{"/var/mqm/qmgrs/QM1/errors/AMQERR02.json", true},
+		{"/var/mqm/qmgrs/QM1/errors/AMQERR03.json", true},
+		{"/var/mqm/qmgrs/QM1/errors/AMQERR02.LOG", true},
+		{"/var/mqm/errors/AMQERR01.LOG", false},
+		{"/var/mqm/qmgrs/QM1/errors/other.json", false},
+	}
+	for _, table := range tests {
+		t.Run(table.path, func(t *testing.T) {
+			if got := isRotatedErrorLog(table.path); got != table.expected {
+				t.Errorf("Expected %v; got %v", table.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// sinkHealth is the state of a destination for mirrored log messages, as reported in the heartbeat
+type sinkHealth struct {
+	// Sink names the type of sink, and where it sends messages
+	Sink string `json:"sink"`
+	// Connected is false if the sink is currently unable to send messages
+	Connected bool `json:"connected"`
+	// Backlog is the number of messages queued to be sent
+	Backlog int `json:"backlog"`
+	// Dropped is the number of messages which couldn't be sent
+	Dropped   uint64 `json:"dropped"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+// healthReporter is implemented by sinks which can report their health
+type healthReporter interface {
+	health() sinkHealth
+}
+
+// getSinkHealth returns the health of each sink which can report it
+func getSinkHealth() []sinkHealth {
+	health := make([]sinkHealth, 0)
+	for _, s := range sinks {
+		if r, ok := s.(healthReporter); ok {
+			health = append(health, r.health())
+		}
+	}
+	for _, d := range declaredSinks {
+		if r, ok := d.sink.(healthReporter); ok {
+			health = append(health, r.health())
+		}
+	}
+	return health
+}
+
+// emitHeartbeat emits a "heartbeat" event, including the health of each sink
+func emitHeartbeat() {
+	health := getSinkHealth()
+	failing := 0
+	for _, h := range health {
+		if !h.Connected {
+			failing++
+		}
+	}
+	level := "INFO"
+	if failing > 0 {
+		level = "WARNING"
+	}
+	emitEvent(level, "heartbeat", fmt.Sprintf("Log mirroring is running, with %v of %v sinks failing", failing, len(health)), map[string]interface{}{"ibm_sinks": health})
+}
+
+// startHeartbeat emits a heartbeat at the interval set by MQ_LOGGING_HEARTBEAT_INTERVAL, until the
+// context is cancelled.  Nothing is emitted if the interval isn't set.
+func startHeartbeat(ctx context.Context, wg *sync.WaitGroup) error {
+	interval, err := getDurationEnv("MQ_LOGGING_HEARTBEAT_INTERVAL", 0)
+	if err != nil || interval == 0 {
+		return err
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				emitHeartbeat()
+			}
+		}
+	}()
+	return nil
+}
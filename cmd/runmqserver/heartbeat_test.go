@@ -0,0 +1,81 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatSinkHealth(t *testing.T) {
+	oldSinks, oldDeclared, oldBackoff, oldJSON := sinks, declaredSinks, sinkBackoff, eventsJSON
+	defer func() {
+		sinks, declaredSinks, sinkBackoff, eventsJSON = oldSinks, oldDeclared, oldBackoff, oldJSON
+	}()
+	eventsJSON = true
+	sinkBackoff = backoffPolicy{initialDelay: time.Millisecond, maxDelay: time.Millisecond, maxAttempts: 1}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	h := newHTTPSink(server.URL, 1, time.Hour, 10)
+	defer h.Close()
+	f, err := newFileSink(filepath.Join(t.TempDir(), "mirror.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sinks = []logSink{h}
+	// The heartbeat itself is written to the console, which doesn't report its health
+	declaredSinks = []*declaredSink{
+		{kind: "console", format: "json", globalFormat: "json", sink: consoleSink{}},
+		{kind: "file", format: "json", globalFormat: "json", sink: f},
+	}
+
+	// Wait for the batch to fail
+	h.Write("{\"message\":\"A\"}\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for h.health().Connected && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	buf := captureConsole(t)
+	emitHeartbeat()
+	var event struct {
+		Loglevel string       `json:"loglevel"`
+		Event    string       `json:"ibm_event"`
+		Sinks    []sinkHealth `json:"ibm_sinks"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &event)
+	if err != nil {
+		t.Fatalf("Unable to parse heartbeat %q: %v", buf.String(), err)
+	}
+	if event.Event != "heartbeat" || event.Loglevel != "WARNING" {
+		t.Errorf("Expected a heartbeat warning; got %q", buf.String())
+	}
+	expected := []sinkHealth{
+		{Sink: "http " + server.URL, Connected: false, LastError: "unexpected HTTP status: 503 Service Unavailable"},
+		{Sink: "file " + f.path, Connected: true},
+	}
+	if !reflect.DeepEqual(event.Sinks, expected) {
+		t.Errorf("Expected sinks %+v; got %+v", expected, event.Sinks)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHTTPSinkBatchSize     = 100
+	defaultHTTPSinkFlushInterval = 5 * time.Second
+	defaultHTTPSinkQueueSize     = 10000
+)
+
+// httpSink sends mirrored log messages to an HTTP endpoint, in batches of new-line delimited records.
+// Messages are queued, so that a slow endpoint doesn't block the mirroring of logs.  If the queue is
+// full, messages are dropped.
+type httpSink struct {
+	url           string
+	client        *http.Client
+	batchSize     int
+	flushInterval time.Duration
+	queue         chan string
+	done          chan struct{}
+	wg            sync.WaitGroup
+	closeOnce     sync.Once
+	dropped       uint64
+	backoff       backoffPolicy
+	// statusMutex protects the result of the last attempt to send a batch
+	statusMutex sync.Mutex
+	failing     bool
+	lastError   string
+}
+
+// newHTTPSink creates a new HTTP sink, and starts a goroutine to send batches to the endpoint
+func newHTTPSink(url string, batchSize int, flushInterval time.Duration, queueSize int) *httpSink {
+	h := &httpSink{
+		url:           url,
+		client:        &http.Client{Timeout: 30 * time.Second},
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		queue:         make(chan string, queueSize),
+		done:          make(chan struct{}),
+		backoff:       sinkBackoff,
+	}
+	h.wg.Add(1)
+	go h.run()
+	return h
+}
+
+// Write queues a log message to be sent, without blocking
+func (h *httpSink) Write(line string) {
+	select {
+	case h.queue <- strings.TrimSuffix(line, "\n"):
+	default:
+		atomic.AddUint64(&h.dropped, 1)
+	}
+}
+
+// Close sends any queued messages, and stops the sink
+func (h *httpSink) Close() error {
+	h.closeOnce.Do(func() {
+		close(h.done)
+		h.wg.Wait()
+	})
+	if dropped := atomic.LoadUint64(&h.dropped); dropped > 0 {
+		log.Printf("Dropped %v log messages which could not be queued for %v", dropped, h.url)
+	}
+	return nil
+}
+
+func (h *httpSink) run() {
+	defer h.wg.Done()
+	ticker := time.NewTicker(h.flushInterval)
+	defer ticker.Stop()
+	batch := make([]string, 0, h.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := h.post(batch)
+		h.setStatus(err)
+		if err != nil {
+			log.Printf("Unable to send %v log messages to %v: %v", len(batch), h.url, err)
+		}
+		batch = make([]string, 0, h.batchSize)
+	}
+	for {
+		select {
+		case line := <-h.queue:
+			batch = append(batch, line)
+			if len(batch) >= h.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-h.done:
+			// Drain anything left in the queue before finishing
+			for {
+				select {
+				case line := <-h.queue:
+					batch = append(batch, line)
+					if len(batch) >= h.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// setStatus records the result of sending a batch, for reporting the health of the sink
+func (h *httpSink) setStatus(err error) {
+	h.statusMutex.Lock()
+	defer h.statusMutex.Unlock()
+	h.failing = err != nil
+	if err != nil {
+		h.lastError = err.Error()
+	}
+}
+
+// health returns the state of the sink.  It is connected unless the last batch couldn't be sent.
+func (h *httpSink) health() sinkHealth {
+	h.statusMutex.Lock()
+	defer h.statusMutex.Unlock()
+	return sinkHealth{
+		Sink:      "http " + h.url,
+		Connected: !h.failing,
+		Backlog:   len(h.queue),
+		Dropped:   atomic.LoadUint64(&h.dropped),
+		LastError: h.lastError,
+	}
+}
+
+// post sends a batch of log messages to the endpoint, retrying on failure
+func (h *httpSink) post(batch []string) error {
+	body := []byte(strings.Join(batch, "\n") + "\n")
+	return h.backoff.retry(func(attempt int) error {
+		err := h.postOnce(body)
+		if err != nil {
+			log.Debugf("Attempt %v to send log messages to %v failed: %v", attempt, h.url, err)
+		}
+		return err
+	})
+}
+
+func (h *httpSink) postOnce(body []byte) error {
+	resp, err := h.client.Post(h.url, "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	// #nosec G104 - nothing useful can be done if closing the body fails
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected HTTP status: %v", resp.Status)
+	}
+	return nil
+}
+
+// getPositiveIntEnv returns the value of an environment variable as a positive integer, or the default if not set
+func getPositiveIntEnv(name string, def int) (int, error) {
+	s := strings.TrimSpace(os.Getenv(name))
+	if s == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i <= 0 {
+		return 0, fmt.Errorf("invalid value for %v: %v", name, s)
+	}
+	return i, nil
+}
+
+// getDurationEnv returns the value of an environment variable as a positive duration (e.g. "5s"), or the default if not set
+func getDurationEnv(name string, def time.Duration) (time.Duration, error) {
+	s := strings.TrimSpace(os.Getenv(name))
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid value for %v: %v", name, s)
+	}
+	return d, nil
+}
+
+// configureHTTPSink creates an HTTP sink, if MQ_LOGGING_HTTP_URL is set
+func configureHTTPSink() (logSink, error) {
+	url := strings.TrimSpace(os.Getenv("MQ_LOGGING_HTTP_URL"))
+	if url == "" {
+		return nil, nil
+	}
+	batchSize, err := getPositiveIntEnv("MQ_LOGGING_HTTP_BATCH_SIZE", defaultHTTPSinkBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	flushInterval, err := getDurationEnv("MQ_LOGGING_HTTP_FLUSH_INTERVAL", defaultHTTPSinkFlushInterval)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Sending mirrored log messages to %v", url)
+	return newHTTPSink(url, batchSize, flushInterval, defaultHTTPSinkQueueSize), nil
+}
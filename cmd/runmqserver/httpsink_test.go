@@ -0,0 +1,113 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRecorder is an HTTP handler which records the body of each request
+type batchRecorder struct {
+	mutex    sync.Mutex
+	batches  []string
+	failures int
+}
+
+func (b *batchRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.failures > 0 {
+		b.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	b.batches = append(b.batches, string(body))
+}
+
+func (b *batchRecorder) get() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]string{}, b.batches...)
+}
+
+func TestHTTPSinkBatches(t *testing.T) {
+	rec := &batchRecorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	h := newHTTPSink(server.URL, 2, time.Hour, 10)
+	for _, m := range []string{"A", "B", "C", "D", "E"} {
+		h.Write("{\"message\":\"" + m + "\"}\n")
+	}
+	h.Close()
+	batches := rec.get()
+	expected := []string{
+		"{\"message\":\"A\"}\n{\"message\":\"B\"}\n",
+		"{\"message\":\"C\"}\n{\"message\":\"D\"}\n",
+		"{\"message\":\"E\"}\n",
+	}
+	if strings.Join(batches, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected batches %q, got %q", expected, batches)
+	}
+}
+
+func TestHTTPSinkFlushInterval(t *testing.T) {
+	rec := &batchRecorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	h := newHTTPSink(server.URL, 100, 50*time.Millisecond, 10)
+	defer h.Close()
+	h.Write("A\n")
+	for i := 0; i < 50 && len(rec.get()) == 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	batches := rec.get()
+	if len(batches) != 1 || batches[0] != "A\n" {
+		t.Errorf("Expected partial batch to be sent after flush interval, got %q", batches)
+	}
+}
+
+func TestHTTPSinkRetry(t *testing.T) {
+	oldBackoff := sinkBackoff
+	defer func() { sinkBackoff = oldBackoff }()
+	sinkBackoff = backoffPolicy{initialDelay: time.Millisecond, maxDelay: time.Millisecond, maxAttempts: 3}
+	rec := &batchRecorder{failures: 2}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	h := newHTTPSink(server.URL, 1, time.Hour, 10)
+	h.Write("A\n")
+	h.Close()
+	batches := rec.get()
+	if len(batches) != 1 || batches[0] != "A\n" {
+		t.Errorf("Expected batch to be delivered after retries, got %q", batches)
+	}
+}
+
+func TestHTTPSinkQueueFull(t *testing.T) {
+	// Use a sink which has no goroutine reading from the queue
+	h := &httpSink{queue: make(chan string, 1)}
+	h.Write("A\n")
+	h.Write("B\n")
+	if h.dropped != 1 {
+		t.Errorf("Expected 1 dropped message, got %v", h.dropped)
+	}
+}
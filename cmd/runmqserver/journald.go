@@ -0,0 +1,145 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// defaultJournaldSocket is the well-known location of the systemd-journald native protocol socket
+const defaultJournaldSocket string = "/run/systemd/journal/socket"
+
+// journal is the journald sink used for mirrored log messages, or nil if journald is not in use
+var journal *journaldSink
+
+// journaldSink sends log messages to systemd-journald using its native protocol
+type journaldSink struct {
+	conn       *net.UnixConn
+	identifier string
+}
+
+// newJournaldSink connects to the journald socket at the specified path
+func newJournaldSink(path string, identifier string) (*journaldSink, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journaldSink{
+		conn:       conn,
+		identifier: identifier,
+	}, nil
+}
+
+// getJournaldEnabled returns true if mirrored logs should be sent to journald
+func getJournaldEnabled() bool {
+	enabled := os.Getenv("MQ_LOGGING_JOURNALD")
+	return enabled == "true" || enabled == "1"
+}
+
+// getJournaldSocket returns the path to the journald socket, allowing it to be overridden
+func getJournaldSocket() string {
+	path := strings.TrimSpace(os.Getenv("MQ_LOGGING_JOURNALD_SOCKET"))
+	if path == "" {
+		return defaultJournaldSocket
+	}
+	return path
+}
+
+// journaldPriority maps the severity of a log message onto a syslog priority, as used by journald.
+// Liberty AUDIT messages are informational, but are given the "notice" priority, so that they stand out.
+func journaldPriority(obj map[string]interface{}) int {
+	if level, ok := obj["loglevel"].(string); ok && strings.EqualFold(strings.TrimSpace(level), "AUDIT") {
+		return 5
+	}
+	switch normalizeSeverity(obj) {
+	case levelFatal:
+		return 2
+	case levelError:
+		return 3
+	case levelWarning:
+		return 4
+	case levelDebug:
+		return 7
+	}
+	return 6
+}
+
+// appendJournaldField adds a single field to a native protocol datagram.  Values containing
+// new-lines must be sent using the binary form, which is prefixed with the length of the value.
+func appendJournaldField(buf *bytes.Buffer, key string, value string) {
+	if strings.Contains(value, "\n") {
+		buf.WriteString(key)
+		buf.WriteByte('\n')
+		// #nosec G104 - writes to a bytes.Buffer always succeed
+		binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	fmt.Fprintf(buf, "%s=%s\n", key, value)
+}
+
+// send writes a single log message to journald.  The obj parameter is the parsed JSON log
+// message, and may be nil if the message wasn't JSON.
+func (j *journaldSink) send(obj map[string]interface{}, msg string) error {
+	var buf bytes.Buffer
+	appendJournaldField(&buf, "MESSAGE", msg)
+	appendJournaldField(&buf, "PRIORITY", fmt.Sprint(journaldPriority(obj)))
+	appendJournaldField(&buf, "SYSLOG_IDENTIFIER", j.identifier)
+	if id := newMQLogRecord(obj).MessageID(); id != "" {
+		appendJournaldField(&buf, "MQ_MESSAGE_ID", id)
+	}
+	_, err := j.conn.Write(buf.Bytes())
+	return err
+}
+
+// Close closes the connection to journald
+func (j *journaldSink) Close() error {
+	return j.conn.Close()
+}
+
+// configureJournald sets up the journald sink, if it has been requested.  If the journald
+// socket isn't available, mirrored logs continue to be written to stdout.
+func configureJournald() {
+	closeJournald()
+	if !getJournaldEnabled() {
+		return
+	}
+	path := getJournaldSocket()
+	j, err := newJournaldSink(path, "runmqserver")
+	if err != nil {
+		log.Printf("Unable to connect to journald socket %v, logs will be written to stdout: %v", path, err)
+		return
+	}
+	journal = j
+}
+
+// closeJournald stops sending mirrored log messages to journald, if it is in use
+func closeJournald() {
+	if journal == nil {
+		return
+	}
+	err := journal.Close()
+	if err != nil {
+		log.Debugf("Error closing journald socket: %v", err)
+	}
+	journal = nil
+}
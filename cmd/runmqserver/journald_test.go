@@ -0,0 +1,155 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// listenJournald creates a mock journald socket, returning its path
+func listenJournald(t *testing.T) (string, *net.UnixConn) {
+	path := filepath.Join(t.TempDir(), "journal.socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return path, conn
+}
+
+func TestJournaldSend(t *testing.T) {
+	path, conn := listenJournald(t)
+	j, err := newJournaldSink(path, "runmqserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := map[string]interface{}{
+		"ibm_messageId": "AMQ6125E",
+		"loglevel":      "ERROR",
+	}
+	err = j.send(obj, "AMQ6125E: An internal IBM MQ error has occurred.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	expected := []string{
+		"MESSAGE=AMQ6125E: An internal IBM MQ error has occurred.\n",
+		"PRIORITY=3\n",
+		"SYSLOG_IDENTIFIER=runmqserver\n",
+		"MQ_MESSAGE_ID=AMQ6125E\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(got, e) {
+			t.Errorf("Expected datagram to contain %q, got %q", e, got)
+		}
+	}
+}
+
+func TestJournaldSendMultiLine(t *testing.T) {
+	path, conn := listenJournald(t)
+	j, err := newJournaldSink(path, "runmqserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = j.send(nil, "line1\nline2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "MESSAGE\n\x0b\x00\x00\x00\x00\x00\x00\x00line1\nline2\n"
+	if !strings.HasPrefix(string(buf[:n]), expected) {
+		t.Errorf("Expected datagram to start with %q, got %q", expected, string(buf[:n]))
+	}
+}
+
+var journaldPriorityTests = []struct {
+	level    string
+	expected int
+}{
+	{"ERROR", 3},
+	{"SEVERE", 3},
+	{"WARNING", 4},
+	{"AUDIT", 5},
+	{"INFO", 6},
+	{"FINE", 7},
+	{"", 6},
+}
+
+func TestJournaldPriority(t *testing.T) {
+	for _, table := range journaldPriorityTests {
+		p := journaldPriority(map[string]interface{}{"loglevel": table.level})
+		if p != table.expected {
+			t.Errorf("journaldPriority() with loglevel=%v - expected %v, got %v", table.level, table.expected, p)
+		}
+	}
+}
+
+func TestConfigureJournaldFallback(t *testing.T) {
+	t.Setenv("MQ_LOGGING_JOURNALD", "true")
+	t.Setenv("MQ_LOGGING_JOURNALD_SOCKET", filepath.Join(t.TempDir(), "missing.socket"))
+	configureJournald()
+	if journal != nil {
+		t.Errorf("Expected journald sink to be disabled when the socket is missing")
+	}
+}
+
+func TestConfigureJournaldReplaced(t *testing.T) {
+	defer func() { loggingDrained = false }()
+	path, _ := listenJournald(t)
+	t.Setenv("MQ_LOGGING_JOURNALD", "true")
+	t.Setenv("MQ_LOGGING_JOURNALD_SOCKET", path)
+	configureJournald()
+	if journal == nil {
+		t.Fatal("Expected journald sink to be enabled")
+	}
+	// A sink which was configured before is no longer used once journald is disabled
+	t.Setenv("MQ_LOGGING_JOURNALD", "false")
+	configureJournald()
+	if journal != nil {
+		t.Error("Expected journald sink to be disabled")
+	}
+	// Nor when the socket can't be connected to
+	t.Setenv("MQ_LOGGING_JOURNALD", "true")
+	configureJournald()
+	t.Setenv("MQ_LOGGING_JOURNALD_SOCKET", filepath.Join(t.TempDir(), "missing.socket"))
+	configureJournald()
+	if journal != nil {
+		t.Error("Expected journald sink to be disabled when the socket is missing")
+	}
+	// Draining logging closes the sink
+	t.Setenv("MQ_LOGGING_JOURNALD_SOCKET", path)
+	configureJournald()
+	j := journal
+	drainLogging()
+	if journal != nil {
+		t.Error("Expected journald sink to be closed when logging is drained")
+	}
+	if err := j.send(nil, "Hello"); err == nil {
+		t.Error("Expected the journald connection to be closed")
+	}
+}
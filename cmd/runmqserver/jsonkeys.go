@@ -0,0 +1,130 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// keyStyle is a transformation applied to the field names of JSON log messages
+type keyStyle string
+
+const (
+	keyStyleLowercase   keyStyle = "lowercase"
+	keyStyleStripPrefix keyStyle = "strip_prefix"
+	keyStyleSnakeCase   keyStyle = "snake_case"
+)
+
+// keyCollisionWarnings records the key collisions which have already been reported, so each is only reported once
+var keyCollisionWarnings sync.Map
+
+// getKeyStyles parses MQ_LOGGING_JSON_KEY_STYLE, which is a comma-separated list of key styles
+func getKeyStyles() ([]keyStyle, error) {
+	styles := make([]keyStyle, 0)
+	s := strings.TrimSpace(os.Getenv("MQ_LOGGING_JSON_KEY_STYLE"))
+	if s == "" {
+		return styles, nil
+	}
+	for _, style := range strings.Split(strings.ToLower(s), ",") {
+		switch keyStyle(strings.TrimSpace(style)) {
+		case keyStyleLowercase, keyStyleStripPrefix, keyStyleSnakeCase:
+			styles = append(styles, keyStyle(strings.TrimSpace(style)))
+		default:
+			return nil, fmt.Errorf("invalid value for MQ_LOGGING_JSON_KEY_STYLE: %v", style)
+		}
+	}
+	return styles, nil
+}
+
+// toSnakeCase converts a camelCase name to snake_case, for example "ibm_messageId" to "ibm_message_id"
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// normalizeKey applies the key styles to a single field name.  The styles are always applied in
+// a fixed order (strip prefix, then snake case, then lower case), regardless of the configured order.
+func normalizeKey(key string, styles []keyStyle) string {
+	has := func(style keyStyle) bool {
+		for _, s := range styles {
+			if s == style {
+				return true
+			}
+		}
+		return false
+	}
+	if has(keyStyleStripPrefix) && strings.HasPrefix(key, "ibm_") && len(key) > len("ibm_") {
+		key = strings.TrimPrefix(key, "ibm_")
+	}
+	if has(keyStyleSnakeCase) {
+		key = toSnakeCase(key)
+	}
+	if has(keyStyleLowercase) {
+		key = strings.ToLower(key)
+	}
+	return key
+}
+
+// normalizeKeys returns a copy of a parsed JSON log message, with the key styles applied to each
+// field name.  Fields whose names are already normalized keep them.  If two fields would end up with
+// the same name, the field which sorts first keeps the normalized name, the other keeps its original
+// name, and a warning is logged once.
+func normalizeKeys(obj map[string]interface{}, styles []keyStyle) map[string]interface{} {
+	if len(styles) == 0 {
+		return obj
+	}
+	out := make(map[string]interface{}, len(obj))
+	keys := make([]string, 0, len(obj))
+	for k, v := range obj {
+		if normalizeKey(k, styles) == k {
+			out[k] = v
+		} else {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		nk := normalizeKey(k, styles)
+		if _, exists := out[nk]; exists {
+			if _, reported := keyCollisionWarnings.LoadOrStore(k+"\x00"+nk, true); !reported {
+				log.Printf("Field %v cannot be renamed to %v, as the name is already in use", k, nk)
+			}
+			if _, exists := out[k]; exists {
+				// Only possible if another field was renamed to this field's original name
+				continue
+			}
+			nk = k
+		}
+		out[nk] = obj[k]
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var normalizeKeyTests = []struct {
+	key      string
+	styles   []keyStyle
+	expected string
+}{
+	{"ibm_messageId", []keyStyle{keyStyleLowercase}, "ibm_messageid"},
+	{"ibm_messageId", []keyStyle{keyStyleStripPrefix}, "messageId"},
+	{"ibm_messageId", []keyStyle{keyStyleSnakeCase}, "ibm_message_id"},
+	{"ibm_arithInsert1", []keyStyle{keyStyleSnakeCase}, "ibm_arith_insert1"},
+	{"ibm_messageId", []keyStyle{keyStyleLowercase, keyStyleStripPrefix, keyStyleSnakeCase}, "message_id"},
+	{"ibm_", []keyStyle{keyStyleStripPrefix}, "ibm_"},
+	{"host", []keyStyle{keyStyleStripPrefix, keyStyleSnakeCase}, "host"},
+}
+
+func TestNormalizeKey(t *testing.T) {
+	for _, table := range normalizeKeyTests {
+		out := normalizeKey(table.key, table.styles)
+		if out != table.expected {
+			t.Errorf("normalizeKey(%v, %v) - expected %v, got %v", table.key, table.styles, table.expected, out)
+		}
+	}
+}
+
+func TestNormalizeKeysCollision(t *testing.T) {
+	buf := captureLog(t)
+	obj := map[string]interface{}{
+		"host":     "a",
+		"ibm_host": "b",
+		"message":  "Hello",
+	}
+	out := normalizeKeys(obj, []keyStyle{keyStyleStripPrefix})
+	if out["host"] != "a" || out["ibm_host"] != "b" || out["message"] != "Hello" || len(out) != 3 {
+		t.Errorf("Expected colliding field to keep its original name, got %v", out)
+	}
+	normalizeKeys(obj, []keyStyle{keyStyleStripPrefix})
+	if strings.Count(buf.String(), "cannot be renamed") != 1 {
+		t.Errorf("Expected a single collision warning, got %v", buf.String())
+	}
+}
+
+func TestNormalizeKeysCollisionOrder(t *testing.T) {
+	captureLog(t)
+	var tests = []struct {
+		obj      map[string]interface{}
+		styles   []keyStyle
+		expected map[string]interface{}
+	}{
+		{map[string]interface{}{"Host": "a", "host": "b"}, []keyStyle{keyStyleLowercase}, map[string]interface{}{"Host": "a", "host": "b"}},
+		{map[string]interface{}{"ibm_messageId": "a", "ibm_message_id": "b"}, []keyStyle{keyStyleSnakeCase}, map[string]interface{}{"ibm_messageId": "a", "ibm_message_id": "b"}},
+		{map[string]interface{}{"HOST": "a", "Host": "b"}, []keyStyle{keyStyleLowercase}, map[string]interface{}{"host": "a", "Host": "b"}},
+	}
+	for _, table := range tests {
+		out := normalizeKeys(table.obj, table.styles)
+		if !reflect.DeepEqual(out, table.expected) {
+			t.Errorf("normalizeKeys(%v, %v) - expected %v, got %v", table.obj, table.styles, table.expected, out)
+		}
+	}
+}
+
+func TestGetKeyStyles(t *testing.T) {
+	t.Setenv("MQ_LOGGING_JSON_KEY_STYLE", "Lowercase, strip_prefix")
+	styles, err := getKeyStyles()
+	if err != nil || len(styles) != 2 || styles[0] != keyStyleLowercase || styles[1] != keyStyleStripPrefix {
+		t.Errorf("Expected lowercase and strip_prefix styles, got %v (%v)", styles, err)
+	}
+	t.Setenv("MQ_LOGGING_JSON_KEY_STYLE", "kebab_case")
+	_, err = getKeyStyles()
+	if err == nil {
+		t.Errorf("Expected error for invalid key style")
+	}
+}
@@ -0,0 +1,91 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// defaultLagPeriod is how long mirroring must be behind before a warning is emitted
+const defaultLagPeriod = 30 * time.Second
+
+// lagMonitor detects when the mirroring of a log file has fallen behind the writing of it
+type lagMonitor struct {
+	path      string
+	threshold int64
+	period    time.Duration
+	// since is when the lag first exceeded the threshold, or the zero time if it is below the threshold
+	since  time.Time
+	warned bool
+}
+
+// newLagMonitor creates a lag monitor for a file, if MQ_LOGGING_LAG_THRESHOLD is set.  The lag must
+// exceed the threshold (in bytes) for the period in MQ_LOGGING_LAG_PERIOD before a warning is emitted.
+func newLagMonitor(path string) (*lagMonitor, error) {
+	threshold, err := getPositiveIntEnv("MQ_LOGGING_LAG_THRESHOLD", 0)
+	if err != nil || threshold == 0 {
+		return nil, err
+	}
+	period, err := getDurationEnv("MQ_LOGGING_LAG_PERIOD", defaultLagPeriod)
+	if err != nil {
+		return nil, err
+	}
+	return &lagMonitor{path: path, threshold: int64(threshold), period: period}, nil
+}
+
+// observe records the lag at a point in time, and returns true if a warning should be emitted.
+// Only one warning is emitted each time the lag exceeds the threshold.
+func (m *lagMonitor) observe(now time.Time, lag int64) bool {
+	if lag <= m.threshold {
+		m.since = time.Time{}
+		m.warned = false
+		return false
+	}
+	if m.since.IsZero() {
+		m.since = now
+	}
+	if !m.warned && now.Sub(m.since) >= m.period {
+		m.warned = true
+		return true
+	}
+	return false
+}
+
+// check measures how far behind the mirroring of a file is, by comparing the size of the file with the
+// position reached, and emits a warning if it has been too far behind for too long
+func (m *lagMonitor) check(f *os.File) {
+	if m == nil {
+		return
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	lag := fi.Size() - offset
+	if m.observe(timeNow(), lag) {
+		emitEvent("WARNING", "mirror_lagging", fmt.Sprintf("Mirroring of %v is %v bytes behind, for more than %v", m.path, lag, m.period), map[string]interface{}{
+			"ibm_path":     m.path,
+			"ibm_lagBytes": lag,
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLagMonitorObserve(t *testing.T) {
+	m := &lagMonitor{threshold: 1000, period: 10 * time.Second}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// The lag grows steadily, but a warning is only emitted once it has been over the threshold for the period
+	var warnings []int
+	for i := 0; i < 30; i++ {
+		if m.observe(start.Add(time.Duration(i)*time.Second), int64(i*200)) {
+			warnings = append(warnings, i)
+		}
+	}
+	// The lag first exceeds 1000 bytes after 6 seconds, so the warning is after 16 seconds
+	if len(warnings) != 1 || warnings[0] != 16 {
+		t.Errorf("Expected a single warning after 16 seconds; got %v", warnings)
+	}
+	// Once the lag recovers, another sustained lag causes another warning
+	m.observe(start.Add(31*time.Second), 0)
+	if m.observe(start.Add(32*time.Second), 5000) || !m.observe(start.Add(42*time.Second), 5000) {
+		t.Error("Expected a new warning after the lag recovered and grew again")
+	}
+}
+
+func TestLagMonitorCheck(t *testing.T) {
+	oldNow, oldJSON := timeNow, eventsJSON
+	defer func() { timeNow, eventsJSON = oldNow, oldJSON }()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	eventsJSON = true
+	buf := captureConsole(t)
+	t.Setenv("MQ_LOGGING_LAG_THRESHOLD", "10")
+	t.Setenv("MQ_LOGGING_LAG_PERIOD", "5s")
+
+	path := filepath.Join(t.TempDir(), "AMQERR01.json")
+	os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0600)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := newLagMonitor(path)
+	if err != nil || m == nil {
+		t.Fatalf("Expected a lag monitor; got %v, %v", m, err)
+	}
+	m.check(f)
+	now = now.Add(5 * time.Second)
+	m.check(f)
+	out := buf.String()
+	if !strings.Contains(out, "\"ibm_event\":\"mirror_lagging\"") || !strings.Contains(out, "\"ibm_lagBytes\":100") {
+		t.Errorf("Expected a lag warning; got %v", out)
+	}
+}
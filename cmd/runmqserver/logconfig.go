@@ -0,0 +1,45 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"sync"
+)
+
+// loggingLifecycle stops the logging destinations being drained more than once at the same time
+var loggingLifecycle sync.Mutex
+
+// loggingDrained is set once the logging destinations have been drained, when the container is stopping
+var loggingDrained bool
+
+// drainLogging writes any buffered or queued log messages, and closes the additional log destinations.
+// It is safe to call more than once, including at the same time from different goroutines.
+func drainLogging() {
+	loggingLifecycle.Lock()
+	defer loggingLifecycle.Unlock()
+	if loggingDrained {
+		return
+	}
+	loggingDrained = true
+	flushMerge()
+	closeDedup()
+	closeExcludeDigest()
+	closeExcludeFileWatch()
+	closeLogSinks()
+	closeJournald()
+	closeOutputQueue()
+	console.Close()
+}
@@ -0,0 +1,59 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestDrainLoggingConcurrently(t *testing.T) {
+	oldLog, oldJSON := log, eventsJSON
+	defer func() {
+		log, eventsJSON = oldLog, oldJSON
+		closeDeclaredSinks()
+		declaredSinks = nil
+		loggingDrained = false
+	}()
+	path := filepath.Join(t.TempDir(), "mirror.json")
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json")
+	t.Setenv("MQ_LOGGING_SINKS", "type=file,format=json,path="+path)
+	_, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	emitEvent("INFO", "test", "Hello", nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			drainLogging()
+		}()
+	}
+	wg.Wait()
+	// Draining again does nothing
+	drainLogging()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) == 0 {
+		t.Error("Expected the event to be written before the sink was closed")
+	}
+}
@@ -18,13 +18,18 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
 
 	"github.com/ibm-messaging/mq-container/internal/command"
 	"github.com/ibm-messaging/mq-container/pkg/logger"
@@ -36,17 +41,50 @@ var log *logger.Logger
 
 var collectDiagOnFail = false
 
+// timeNow returns the current time.  It is a variable to allow it to be replaced during testing.
+var timeNow = time.Now
+
+// webLogDeprecationOnce ensures the MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG deprecation message is only printed once
+var webLogDeprecationOnce sync.Once
+
 func logTerminationf(format string, args ...interface{}) {
 	logTermination(fmt.Sprintf(format, args...))
 }
 
+// terminationLogPath is the file used to record the reason for termination
+var terminationLogPath = "/run/termination-log"
+
+// terminationExitCode is the exit code the process intends to use, or nil if it isn't known
+var terminationExitCode *int
+
+// setTerminationExitCode records the exit code the process will use if it terminates, so that it
+// can be included in the termination information
+func setTerminationExitCode(code int) {
+	terminationExitCode = &code
+}
+
+// terminationRecord is the structured form of the termination information
+type terminationRecord struct {
+	Message  string `json:"message"`
+	ExitCode *int   `json:"exitCode"`
+}
+
 func logTermination(args ...interface{}) {
 	msg := fmt.Sprint(args...)
 	// Write the message to the termination log.  This is not the default place
 	// that Kubernetes will look for termination information.
 	log.Debugf("Writing termination message: %v", msg)
 	// #nosec G306 - its a read by owner/s group, and pose no harm.
-	err := os.WriteFile("/run/termination-log", []byte(msg), 0660)
+	err := os.WriteFile(terminationLogPath, []byte(msg), 0660)
+	if err != nil {
+		log.Debug(err)
+	}
+	// Also write a structured version, including the exit code, for post-mortem automation
+	b, err := json.Marshal(terminationRecord{Message: msg, ExitCode: terminationExitCode})
+	if err == nil {
+		// #nosec G306 - its a read by owner/s group, and pose no harm.
+		err = os.WriteFile(terminationLogPath+".json", b, 0660)
+	}
 	if err != nil {
 		log.Debug(err)
 	}
@@ -58,63 +96,204 @@ func logTermination(args ...interface{}) {
 }
 
 func getLogFormat() string {
-	logFormat := strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_CONSOLE_FORMAT")))
+	logFormat, _ := splitLogFormat(os.Getenv("MQ_LOGGING_CONSOLE_FORMAT"))
 	//old-style env var is used.
 	if logFormat == "" {
 		logFormat = strings.ToLower(strings.TrimSpace(os.Getenv("LOG_FORMAT")))
 	}
 
-	if logFormat != "" && (logFormat == "basic" || logFormat == "json") {
+	if logFormat != "" && isValidLogFormat(logFormat) {
 		return logFormat
 	} else {
-		//this is the case where value is either empty string or set to something other than "basic"/"json"
+		//this is the case where value is either empty string or set to something which isn't a valid format
 		logFormat = "basic"
 	}
 
 	return logFormat
 }
 
+// jsonConverters convert log messages in JSON format to the formats which are based on it, keyed by the
+// name of the format
+var jsonConverters = map[string]func(line string) string{
+	"ecs":    formatECSLine,
+	"gelf":   formatGELFLine,
+	"syslog": formatSyslogLine,
+}
+
+// isValidLogFormat returns true if a format can be used in MQ_LOGGING_CONSOLE_FORMAT
+func isValidLogFormat(format string) bool {
+	if format == "json" || format == "basic" {
+		return true
+	}
+	_, ok := jsonConverters[format]
+	return ok
+}
+
+// splitLogFormat splits a value of MQ_LOGGING_CONSOLE_FORMAT, such as "json;web:basic", into the
+// global format and the per-source overrides, which are returned as "source:format" tokens
+func splitLogFormat(value string) (string, []string) {
+	global := ""
+	overrides := make([]string, 0)
+	for _, token := range strings.Split(strings.ToLower(value), ";") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		if strings.Contains(token, ":") {
+			overrides = append(overrides, token)
+		} else if global == "" {
+			global = token
+		}
+	}
+	return global, overrides
+}
+
+// sourceMirrorFuncs holds the mirrorFuncs for sources with a different format to the global one, and for
+// the web server if there are declared sinks, so that its messages can be routed to them even if they
+// aren't JSON
+var sourceMirrorFuncs = map[string]mirrorFunc{}
+
+// getLogFormatOverrides returns the per-source formats set in MQ_LOGGING_CONSOLE_FORMAT, keyed by the
+// MQ_LOGGING_CONSOLE_SOURCE category (for example "web")
+func getLogFormatOverrides() (map[string]string, error) {
+	_, tokens := splitLogFormat(os.Getenv("MQ_LOGGING_CONSOLE_FORMAT"))
+	overrides := make(map[string]string)
+	for _, token := range tokens {
+		parts := strings.SplitN(token, ":", 2)
+		source, format := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if source != "qmgr" && source != "web" && source != "htpass" {
+			return nil, fmt.Errorf("invalid source in MQ_LOGGING_CONSOLE_FORMAT: %v", token)
+		}
+		if !isValidLogFormat(format) {
+			return nil, fmt.Errorf("invalid format in MQ_LOGGING_CONSOLE_FORMAT: %v", token)
+		}
+		overrides[source] = format
+	}
+	return overrides, nil
+}
+
+// mirrorFuncForSource returns the mirrorFunc to use for a log source, which is the default
+// mirrorFunc unless the source's format has been overridden
+func mirrorFuncForSource(source string, mf mirrorFunc) mirrorFunc {
+	if override, ok := sourceMirrorFuncs[logSourceCategory(source)]; ok {
+		return override
+	}
+	return mf
+}
+
+// logFormatForSource returns the format which log messages from a source are mirrored in, which is the
+// format in MQ_LOGGING_CONSOLE_FORMAT unless it has been overridden for the source
+func logFormatForSource(source string) string {
+	overrides, err := getLogFormatOverrides()
+	if err == nil {
+		if override, ok := overrides[logSourceCategory(source)]; ok {
+			return override
+		}
+	}
+	return getLogFormat()
+}
+
+// checkLogFormatConflict logs a message if the new and old-style log format environment variables
+// are both set, to different values, so that operators can discover stale configuration
+func checkLogFormatConflict() {
+	newFormat, _ := splitLogFormat(os.Getenv("MQ_LOGGING_CONSOLE_FORMAT"))
+	oldFormat := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_FORMAT")))
+	if newFormat == "" || oldFormat == "" || newFormat == oldFormat {
+		return
+	}
+	if isValidLogFormat(newFormat) {
+		log.Printf("Environment variables MQ_LOGGING_CONSOLE_FORMAT=%v and LOG_FORMAT=%v are both set. Using MQ_LOGGING_CONSOLE_FORMAT.", newFormat, oldFormat)
+	} else {
+		log.Printf("Warning: Environment variables MQ_LOGGING_CONSOLE_FORMAT=%v and LOG_FORMAT=%v are both set, but MQ_LOGGING_CONSOLE_FORMAT is not a valid log format. Using %v.", newFormat, oldFormat, getLogFormat())
+	}
+}
+
+// getBasicColumns returns true if MQ messages in basic format should be laid out in fixed-width columns
+func getBasicColumns() bool {
+	enabled := os.Getenv("MQ_LOGGING_BASIC_COLUMNS")
+	return enabled == "true" || enabled == "1"
+}
+
+// columnPlaceholder is used in place of a field which is missing, when formatting in columns
+const columnPlaceholder = "-"
+
+// formatBasicColumns formats an MQ message in fixed-width columns of date and time, severity, message ID and
+// text.  The severity and message ID columns line up with the thread ID and module columns of Liberty traces.
+func formatBasicColumns(r MQLogRecord, inserts []string) string {
+	column := func(value string) string {
+		if value == "" {
+			return columnPlaceholder
+		}
+		return value
+	}
+	datetime := strings.Replace(r.DatetimeText(), "+0000", "Z", 1)
+	id := r.MessageID()
+	// The message ID is in its own column, so doesn't need repeating in the text
+	message := strings.TrimPrefix(r.Message(), id+": ")
+	message = strings.ReplaceAll(message, "\n", "\\n")
+	if len(inserts) > 0 {
+		message = fmt.Sprintf("%s [%v]", message, strings.Join(inserts, ", "))
+	}
+	if getOmitTime() {
+		return fmt.Sprintf("%-8s %-13s %s\n", column(strings.ToUpper(r.Field("loglevel"))), column(id), column(message))
+	}
+	return fmt.Sprintf("%-24s %-8s %-13s %s\n", column(datetime), column(strings.ToUpper(r.Field("loglevel"))), column(id), column(message))
+}
+
+// getOmitTime returns true if the time should be left out of log messages in basic format, for example
+// because the log collector adds its own time
+func getOmitTime() bool {
+	omit := os.Getenv("MQ_LOGGING_CONSOLE_OMIT_TIME")
+	return omit == "true" || omit == "1"
+}
+
+// basicTimePrefix returns the time to start a log message with, in basic format, including the following
+// space, or an empty string if the time is omitted
+func basicTimePrefix(datetime string) string {
+	if getOmitTime() {
+		return ""
+	}
+	return datetime + " "
+}
+
 // formatBasic formats a log message parsed from JSON, as "basic" text
 func formatBasic(obj map[string]interface{}) string {
+	r := newMQLogRecord(obj)
 	// Emulate the MQ "MessageDetail=Extended" option, by appending inserts to the message
 	// This is important for certain messages, where key details are only available in the extended message content
-	inserts := make([]string, 0)
-	for k, v := range obj {
-		if strings.HasPrefix(k, "ibm_commentInsert") {
-			inserts = append(inserts, fmt.Sprintf("%s(%v)", strings.Replace(k, "ibm_comment", "Comment", 1), obj[k]))
-		} else if strings.HasPrefix(k, "ibm_arithInsert") {
-			if v.(float64) != 0 {
-				inserts = append(inserts, fmt.Sprintf("%s(%v)", strings.Replace(k, "ibm_arith", "Arith", 1), obj[k]))
-			}
-		}
+	values := r.Inserts()
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	// Sort by index, so that the inserts read in the order MQ intended
+	sortInsertNames(names)
+	inserts := make([]string, 0, len(names))
+	for _, k := range names {
+		inserts = append(inserts, fmt.Sprintf("%s(%v)", k, values[k]))
+	}
+	if getBasicColumns() && r.Field("type") != "liberty_trace" {
+		return formatBasicColumns(r, inserts)
 	}
-	sort.Strings(inserts)
 	if len(inserts) > 0 {
-		return fmt.Sprintf("%s %s [%v]\n", obj["ibm_datetime"], obj["message"], strings.Join(inserts, ", "))
+		return fmt.Sprintf("%s%s [%v]\n", basicTimePrefix(r.DatetimeText()), r.Message(), strings.Join(inserts, ", "))
 	}
 	// Convert time zone information from some logs (e.g. Liberty) for consistency
-	obj["ibm_datetime"] = strings.Replace(obj["ibm_datetime"].(string), "+0000", "Z", 1)
+	datetime := strings.Replace(r.DatetimeText(), "+0000", "Z", 1)
 	// Escape any new-line characters, so that we don't get multi-line messages messing up the output
-	obj["message"] = strings.ReplaceAll(obj["message"].(string), "\n", "\\n")
+	message := strings.ReplaceAll(r.Message(), "\n", "\\n")
 
-	if obj["type"] != nil && (obj["type"] == "liberty_trace") {
-		timeStamp := obj["ibm_datetime"]
-		threadID := ""
+	if r.Field("type") == "liberty_trace" {
+		timeStamp := basicTimePrefix(datetime)
 		srtModuleName := ""
 		logLevel := ""
-		ibmClassName := ""
 		srtIbmClassName := ""
-		ibmMethodName := ""
-		message := ""
 
-		if obj["loglevel"] != nil {
+		if logLevelTmp := r.Field("loglevel"); logLevelTmp != "" {
 			//threadID is captured below
-			if obj["ibm_threadId"] != nil {
-				threadID = obj["ibm_threadId"].(string)
-			}
+			threadID := r.Field("ibm_threadId")
 
 			//logLevel character to be mirrored in console web server logging is decided below
-			logLevelTmp := obj["loglevel"].(string)
 			switch logLevelTmp {
 			case "AUDIT":
 				logLevel = "A"
@@ -137,80 +316,657 @@ func formatBasic(obj map[string]interface{}) string {
 			}
 
 			//This is a 13 characters string present in extracted out of module node
-			if obj["module"] != nil {
-				srtModuleNameArr := strings.Split(obj["module"].(string), ".")
-				arrLen := len(srtModuleNameArr)
-				srtModuleName = srtModuleNameArr[arrLen-1]
+			if module := r.Field("module"); module != "" {
+				srtModuleNameArr := strings.Split(module, ".")
+				srtModuleName = srtModuleNameArr[len(srtModuleNameArr)-1]
 				if len(srtModuleName) > 13 {
 					srtModuleName = srtModuleName[0:13]
 				}
 			}
-			if obj["ibm_className"] != nil {
-				ibmClassName = obj["ibm_className"].(string)
-
+			ibmClassName := r.Field("ibm_className")
+			if ibmClassName != "" {
 				//A 13 character string is extracted from class name. This is required for FINE, FINER & FINEST log lines
 				ibmClassNameArr := strings.Split(ibmClassName, ".")
-				arrLen := len(ibmClassNameArr)
-				srtIbmClassName = ibmClassNameArr[arrLen-1]
+				srtIbmClassName = ibmClassNameArr[len(ibmClassNameArr)-1]
 				if len(srtModuleName) > 13 {
 					srtIbmClassName = srtIbmClassName[0:13]
 				}
 			}
-			if obj["ibm_methodName"] != nil {
-				ibmMethodName = obj["ibm_methodName"].(string)
-			}
-			if obj["message"] != nil {
-				message = obj["message"].(string)
-			}
+			ibmMethodName := r.Field("ibm_methodName")
 
 			//For AUDIT & INFO logging
 			if logLevel == "A" || logLevel == "I" {
-				return fmt.Sprintf("%s %s %-13s %s %s %s %s\n", timeStamp, threadID, srtModuleName, logLevel, ibmClassName, ibmMethodName, message)
+				return fmt.Sprintf("%s%s %-13s %s %s %s %s\n", timeStamp, threadID, srtModuleName, logLevel, ibmClassName, ibmMethodName, message)
 			}
 			//For EVENT logLevel
 			if logLevelTmp == "EVENT" {
-				return fmt.Sprintf("%s %s %-13s %s %s\n", timeStamp, threadID, srtModuleName, logLevel, message)
+				return fmt.Sprintf("%s%s %-13s %s %s\n", timeStamp, threadID, srtModuleName, logLevel, message)
 			}
 			//For ENTRY & EXIT
 			if logLevel == ">" || logLevel == "<" {
-				return fmt.Sprintf("%s %s %-13s %s %s %s\n", timeStamp, threadID, srtModuleName, logLevel, ibmMethodName, message)
+				return fmt.Sprintf("%s%s %-13s %s %s %s\n", timeStamp, threadID, srtModuleName, logLevel, ibmMethodName, message)
 			}
 			//For deeper log levels
 			if logLevelTmp == "FINE" || logLevel == "2" || logLevel == "3" {
-				return fmt.Sprintf("%s %s %-13s %s %s %s %s\n", timeStamp, threadID, srtIbmClassName, logLevel, ibmClassName, ibmMethodName, message)
+				return fmt.Sprintf("%s%s %-13s %s %s %s %s\n", timeStamp, threadID, srtIbmClassName, logLevel, ibmClassName, ibmMethodName, message)
 			}
 
 		}
 	}
-	return fmt.Sprintf("%s %s\n", obj["ibm_datetime"], obj["message"])
+	return fmt.Sprintf("%s%s\n", basicTimePrefix(datetime), message)
 }
 
 // mirrorSystemErrorLogs starts a goroutine to mirror the contents of the MQ system error logs
 func mirrorSystemErrorLogs(ctx context.Context, wg *sync.WaitGroup, mf mirrorFunc) (chan error, error) {
 	// Always use the JSON log as the source
-	return mirrorLog(ctx, wg, "/var/mqm/errors/AMQERR01.json", false, mf, false)
+	return mirrorLog(ctx, wg, "system", "/var/mqm/errors/AMQERR01.json", false, mirrorFuncForSource("system", mf), false)
+}
+
+// getQueueManager reads the queue manager configuration.  It is a variable to allow it to be replaced during testing.
+var getQueueManager = mqini.GetQueueManager
+
+// qmgrResolveTimeout is the maximum time to wait for the queue manager configuration to be read
+var qmgrResolveTimeout = 60 * time.Second
+
+// resolveQueueManager reads the queue manager configuration, giving up if it takes longer than
+// qmgrResolveTimeout.  Reading the configuration involves disk access, which can block if a volume
+// is slow to mount.
+func resolveQueueManager(parent context.Context, name string) (*mqini.QueueManager, error) {
+	ctx, cancel := context.WithTimeout(parent, qmgrResolveTimeout)
+	defer cancel()
+	type result struct {
+		qm  *mqini.QueueManager
+		err error
+	}
+	// Buffered, so that the goroutine can always complete, even after a timeout
+	resultChannel := make(chan result, 1)
+	// The goroutine may outlive this function, so it mustn't read the variable itself
+	get := getQueueManager
+	go func() {
+		qm, err := get(name)
+		resultChannel <- result{qm, err}
+	}()
+	select {
+	case r := <-resultChannel:
+		return r.qm, r.err
+	case <-ctx.Done():
+		if parent.Err() != nil {
+			return nil, fmt.Errorf("cancelled reading configuration for queue manager %v: %v", name, parent.Err())
+		}
+		return nil, fmt.Errorf("timed out after %v reading configuration for queue manager %v", qmgrResolveTimeout, name)
+	}
 }
 
 // mirrorQueueManagerErrorLogs starts a goroutine to mirror the contents of the MQ queue manager error logs
 func mirrorQueueManagerErrorLogs(ctx context.Context, wg *sync.WaitGroup, name string, fromStart bool, mf mirrorFunc) (chan error, error) {
+	pattern, err := getErrorLogGlob()
+	if err != nil {
+		return nil, err
+	}
+	if pattern != "" {
+		return mirrorLogGlob(ctx, wg, "qmgr", pattern, fromStart, mirrorFuncForSource("qmgr", mf), true)
+	}
 	// Always use the JSON log as the source
-	qm, err := mqini.GetQueueManager(name)
+	qm, err := resolveQueueManager(ctx, name)
 	if err != nil {
 		log.Debug(err)
 		return nil, err
 	}
 	f := filepath.Join(mqini.GetErrorLogDirectory(qm), "AMQERR01.json")
-	return mirrorLog(ctx, wg, f, fromStart, mf, true)
+	return mirrorLogCandidates(ctx, wg, "qmgr", f, fromStart, mirrorFuncForSource("qmgr", mf), true)
 }
 
 // mirrorHTPasswdLogs starts a goroutine to mirror the contents of the MQ HTPasswd authorization service's log
 func mirrorHTPasswdLogs(ctx context.Context, wg *sync.WaitGroup, name string, fromStart bool, mf mirrorFunc) (chan error, error) {
-	return mirrorLog(ctx, wg, "/var/mqm/errors/mqhtpass.json", false, mf, true)
+	mf = mirrorFuncForSource("htpass", mf)
+	if getHTPasswdAsQmgr() {
+		// The messages are treated as queue manager messages, so tag them with where they really came from
+		mf = tagLogSource(mf, "htpass")
+	}
+	return mirrorLog(ctx, wg, "htpass", "/var/mqm/errors/mqhtpass.json", false, mf, true)
+}
+
+// getHTPasswdAsQmgr returns true if the HTPasswd log should be treated as part of the queue manager's logs
+func getHTPasswdAsQmgr() bool {
+	enabled := os.Getenv("MQ_LOGGING_HTPASS_AS_QMGR")
+	return enabled == "true" || enabled == "1"
+}
+
+// logSourceCategory returns the MQ_LOGGING_CONSOLE_SOURCE category which a log source belongs to
+func logSourceCategory(source string) string {
+	switch source {
+	case "system":
+		return "qmgr"
+	case "htpass":
+		if getHTPasswdAsQmgr() {
+			return "qmgr"
+		}
+	}
+	return source
+}
+
+// shouldMirrorHTPasswdLogs returns true if the HTPasswd log should be mirrored.  It is always mirrored,
+// unless it is treated as part of the queue manager's logs, when it follows the "qmgr" source.
+func shouldMirrorHTPasswdLogs() bool {
+	if !getHTPasswdAsQmgr() {
+		return true
+	}
+	return checkLogSourceForMirroring(logSourceCategory("htpass"))
+}
+
+// tagLogSource wraps a mirrorFunc, so that JSON log messages include an "ibm_logSource" field
+// with the name of the source they came from
+func tagLogSource(mf mirrorFunc, source string) mirrorFunc {
+	return func(msg string, isQMLog bool) bool {
+		obj, err := processLogMessage(msg)
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		obj["ibm_logSource"] = source
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		return mf(string(b), isQMLog)
+	}
 }
 
-// mirrorWebServerLogs starts a goroutine to mirror the contents of the Liberty web server messages.log
+// sourceCategories are the values of the "ibm_sourceCategory" field, one for each kind of log which can be mirrored
+var sourceCategories = []string{"qmgr", "web", "htpass", "system", "mqsc", "extra"}
+
+// getSourceCategoryEnabled returns true if JSON log messages should include the category of the log they were read from
+func getSourceCategoryEnabled() bool {
+	enabled := os.Getenv("MQ_LOGGING_SOURCE_CATEGORY")
+	return enabled == "true" || enabled == "1"
+}
+
+// sourceCategory returns the category for a log source.  Unlike logSourceCategory, this does not depend on
+// the configuration, so that it can be used to reliably group messages.  Any unknown source is "extra".
+func sourceCategory(source string) string {
+	for _, c := range sourceCategories {
+		if c == source {
+			return c
+		}
+	}
+	return "extra"
+}
+
+// tagSourceCategory wraps a mirrorFunc, so that JSON log messages include an "ibm_sourceCategory" field
+// with the category of the source they came from
+func tagSourceCategory(mf mirrorFunc, source string) mirrorFunc {
+	category := sourceCategory(source)
+	return func(msg string, isQMLog bool) bool {
+		obj, err := processLogMessage(trimRecordPrefix(msg))
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		obj["ibm_sourceCategory"] = category
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		return mf(string(b), isQMLog)
+	}
+}
+
+// webServerDir is the web server's data directory, which only exists if the web server is installed
+var webServerDir = "/var/mqm/web"
+
+// webServerGracePeriod is how long to wait for the web server's data directory to appear
+var webServerGracePeriod = 2 * time.Second
+
+// isWebServerEnabled returns true if the embedded web server has been enabled
+func isWebServerEnabled() bool {
+	enableWebServer := os.Getenv("MQ_ENABLE_EMBEDDED_WEB_SERVER")
+	return enableWebServer == "true" || enableWebServer == "1"
+}
+
+// getRequireLogSources returns true if a log source requested in MQ_LOGGING_CONSOLE_SOURCE must exist
+func getRequireLogSources() bool {
+	enabled := os.Getenv("MQ_LOGGING_REQUIRE_SOURCES")
+	return enabled == "true" || enabled == "1"
+}
+
+// waitForDirectory waits up to the specified time for a directory to exist, returning true if it does.
+// It stops waiting, and returns false, if the context is cancelled.
+func waitForDirectory(ctx context.Context, path string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		fi, err := os.Stat(path)
+		if err == nil && fi.IsDir() {
+			return true
+		}
+		if time.Now().After(deadline) || ctx.Err() != nil {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// mirrorWebServerLogs starts a goroutine to mirror the contents of the Liberty web server messages.log.
+// If the web server isn't enabled, no goroutine is started, and a nil channel is returned.  The goroutine
+// waits for the web server's directory to appear, so that startup isn't delayed.  If it doesn't appear,
+// the web server logs aren't mirrored, and if they are required, an error is sent to the returned channel,
+// and the container is terminated.
 func mirrorWebServerLogs(ctx context.Context, wg *sync.WaitGroup, name string, fromStart bool, mf mirrorFunc) (chan error, error) {
-	return mirrorLog(ctx, wg, "/var/mqm/web/installations/Installation1/servers/mqweb/logs/messages.log", fromStart, mf, true)
+	if !isWebServerEnabled() {
+		log.Println("Web server is not enabled, so web server logs will not be mirrored")
+		return nil, nil
+	}
+	path := filepath.Join(webServerDir, "installations/Installation1/servers/mqweb/logs/messages.log")
+	mf = mirrorFuncForSource("web", mf)
+	emptyNotice, err := getDurationEnv("MQ_LOGGING_WEB_EMPTY_NOTICE", 0)
+	if err != nil {
+		return nil, err
+	}
+	errorChannel := make(chan error, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if !waitForDirectory(ctx, webServerDir, webServerGracePeriod) {
+			if ctx.Err() != nil {
+				return
+			}
+			if getRequireLogSources() {
+				err := fmt.Errorf("web server directory %v does not exist, and web server logs are required", webServerDir)
+				errorChannel <- err
+				terminateWithReason(err.Error())
+				return
+			}
+			log.Printf("Web server directory %v does not exist, so web server logs will not be mirrored", webServerDir)
+			return
+		}
+		if emptyNotice > 0 {
+			mf = notifyEmptyLog(ctx, wg, "web", path, emptyNotice, mf)
+		}
+		errs, err := mirrorLogCandidates(ctx, wg, "web", path, fromStart, mf, true)
+		if err != nil {
+			log.Error(err)
+			errorChannel <- err
+			return
+		}
+		select {
+		case err := <-errs:
+			errorChannel <- err
+		case <-ctx.Done():
+		}
+	}()
+	return errorChannel, nil
+}
+
+// notifyEmptyLog wraps a mirrorFunc, and starts a goroutine which emits a "log_empty" event if nothing has
+// been mirrored from the log after the grace period, and the log is still empty.  This reassures operators
+// that an idle log is being watched.  The event is only emitted once.
+func notifyEmptyLog(ctx context.Context, wg *sync.WaitGroup, source string, path string, grace time.Duration, mf mirrorFunc) mirrorFunc {
+	var mirrored int32
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if atomic.LoadInt32(&mirrored) != 0 {
+			return
+		}
+		if fi, err := os.Stat(path); err == nil && fi.Size() > 0 {
+			return
+		}
+		emitEvent("INFO", "log_empty", fmt.Sprintf("Watching %v, which has had no content for %v", path, grace), map[string]interface{}{"ibm_source": source, "ibm_path": path})
+	}()
+	return func(msg string, isQMLog bool) bool {
+		atomic.StoreInt32(&mirrored, 1)
+		return mf(msg, isQMLog)
+	}
+}
+
+// logLabel is a static key/value pair added to every mirrored log message
+type logLabel struct {
+	key   string
+	value string
+}
+
+// getLogLabels parses MQ_LOGGING_LABELS, which is a comma-separated list of key=value pairs
+func getLogLabels() ([]logLabel, error) {
+	return parseLogLabels(os.Getenv("MQ_LOGGING_LABELS"))
+}
+
+func parseLogLabels(s string) ([]logLabel, error) {
+	labels := make([]logLabel, 0)
+	if strings.TrimSpace(s) == "" {
+		return labels, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid value for MQ_LOGGING_LABELS: %q is not in the form key=value", pair)
+		}
+		labels = append(labels, logLabel{key: strings.TrimSpace(kv[0]), value: strings.TrimSpace(kv[1])})
+	}
+	return labels, nil
+}
+
+// mirrorOptions holds the settings which control how each mirrored log message is transformed
+type mirrorOptions struct {
+	// source is the category ("qmgr" or "web") of the log being mirrored, which is used to route
+	// messages which aren't JSON to declared sinks, or empty if it isn't known
+	source string
+	// labels are static key/value pairs added to every message
+	labels []logLabel
+	// recordBytes adds the size of the original log record as a field, in JSON format
+	recordBytes bool
+	// verboseWindow is the time of day when all messages are mirrored, or nil to always mirror all messages
+	verboseWindow *timeWindow
+	// quietLevel is the minimum level of message mirrored outside the verbose window
+	quietLevel logLevel
+	// minLevel is the minimum level of message mirrored at any time
+	minLevel logLevel
+	// excludeRegexps are the regular expressions for log lines which aren't mirrored.  They are compiled
+	// once, by configureLogger.
+	excludeRegexps []*regexp.Regexp
+	// elapsed adds the time since the queue manager started as a field, in JSON format
+	elapsed bool
+	// keyStyles are applied to the field names of messages, in JSON format
+	keyStyles []keyStyle
+	// fatalIDs are message IDs which cause the container to terminate
+	fatalIDs []string
+	// timestampField is an extra name for the ibm_datetime field, in JSON format
+	timestampField string
+	// removeTimestamp removes the ibm_datetime field, if timestampField is set
+	removeTimestamp bool
+	// readyIDs are message IDs which cause the "mq_ready" event to be emitted, or nil if the event is disabled
+	readyIDs []string
+	// rawLine adds the original log record after each formatted line, in basic format
+	rawLine bool
+	// droppedFields logs the fields of each record which aren't shown, in basic format
+	droppedFields bool
+	// dualOutput adds the original log record after each formatted line, in either format
+	dualOutput bool
+	// requireFields are field names which a message must have to be mirrored
+	requireFields []string
+	// requireAllFields is true if a message must have all of requireFields, rather than any of them
+	requireAllFields bool
+	// shutdownIDs are message IDs which indicate the queue manager is shutting down
+	shutdownIDs []string
+	// shutdownMode controls what happens to messages once the queue manager is shutting down
+	shutdownMode shutdownMode
+	// includeIDs holds the only message IDs to mirror, or is empty to mirror all messages
+	includeIDs map[string]bool
+	// emptyRecord controls what happens to records which are an empty JSON object
+	emptyRecord emptyRecordMode
+	// missingMessage controls what happens to records with no message, in basic format
+	missingMessage missingMessageMode
+	// missingMessagePlaceholder is used in place of a missing message, in placeholder mode
+	missingMessagePlaceholder string
+	// epoch identifies this container incarnation, and is added as a field in JSON format, if not empty
+	epoch string
+	// qmgrStatus is the cached status of the queue manager, which is added as a field in JSON format, or nil
+	qmgrStatus *queueManagerStatus
+	// reasonCode adds any MQ reason code found in a message as a field, in JSON format
+	reasonCode bool
+	// severityNumber is the scale for a numeric severity, added as a field in JSON format, if not empty
+	severityNumber severityNumberScale
+	// template renders each message, in JSON format, or is nil to use the built-in format
+	template *template.Template
+	// schema is checked against each message, in JSON format, or is nil if messages aren't checked
+	schema *recordSchema
+	// convert changes each message in JSON format to a format based on it, such as ECS, or is nil
+	convert func(line string) string
+}
+
+// getMirrorOptions reads the settings for transforming mirrored log messages from the environment
+func getMirrorOptions() (mirrorOptions, error) {
+	var opts mirrorOptions
+	var err error
+	opts.labels, err = getLogLabels()
+	if err != nil {
+		return opts, err
+	}
+	recordBytes := os.Getenv("MQ_LOGGING_RECORD_BYTES")
+	opts.recordBytes = recordBytes == "true" || recordBytes == "1"
+	elapsed := os.Getenv("MQ_LOGGING_ELAPSED_TIME")
+	opts.elapsed = elapsed == "true" || elapsed == "1"
+	opts.keyStyles, err = getKeyStyles()
+	if err != nil {
+		return opts, err
+	}
+	opts.verboseWindow, opts.quietLevel, err = getVerboseWindow()
+	if err != nil {
+		return opts, err
+	}
+	opts.minLevel, err = getConsoleLogLevel()
+	if err != nil {
+		return opts, err
+	}
+	opts.requireFields, opts.requireAllFields, err = getRequiredFields()
+	if err != nil {
+		return opts, err
+	}
+	opts.shutdownIDs, opts.shutdownMode, err = getShutdownOptions()
+	if err != nil {
+		return opts, err
+	}
+	opts.includeIDs = getIncludeIDs()
+	opts.emptyRecord, err = getEmptyRecordMode()
+	if err != nil {
+		return opts, err
+	}
+	opts.missingMessage, opts.missingMessagePlaceholder, err = getMissingMessageMode()
+	if err != nil {
+		return opts, err
+	}
+	opts.epoch, err = getLogEpoch()
+	if err != nil {
+		return opts, err
+	}
+	opts.reasonCode = getReasonCodeEnabled()
+	opts.severityNumber, err = getSeverityNumberScale()
+	if err != nil {
+		return opts, err
+	}
+	opts.template = getLogTemplate()
+	opts.schema, err = getRecordSchema()
+	if err != nil {
+		return opts, err
+	}
+	opts.fatalIDs = getFatalMessageIDs()
+	opts.readyIDs = getReadyMessageIDs()
+	// The raw record is only for debugging the basic format, so is ignored unless debug is enabled
+	rawLine := os.Getenv("MQ_LOGGING_BASIC_RAW")
+	opts.rawLine = getDebug() && (rawLine == "true" || rawLine == "1")
+	droppedFields := os.Getenv("MQ_LOGGING_BASIC_DROPPED_FIELDS")
+	opts.droppedFields = getDebug() && (droppedFields == "true" || droppedFields == "1")
+	dualOutput := os.Getenv("MQ_LOGGING_DUAL_OUTPUT")
+	opts.dualOutput = dualOutput == "true" || dualOutput == "1"
+	opts.timestampField = strings.TrimSpace(os.Getenv("MQ_LOGGING_TIMESTAMP_FIELD"))
+	removeTimestamp := os.Getenv("MQ_LOGGING_TIMESTAMP_FIELD_REMOVE_ORIGINAL")
+	opts.removeTimestamp = opts.timestampField != "" && (removeTimestamp == "true" || removeTimestamp == "1")
+	return opts, nil
+}
+
+// droppedBasicFields returns the names of the fields of a log record whose values don't appear in the
+// line formatted from it, in basic format.  The time is always reformatted, so it isn't checked.
+func droppedBasicFields(obj map[string]interface{}, line string) []string {
+	dropped := make([]string, 0)
+	for k, v := range obj {
+		if k == "ibm_datetime" || v == nil {
+			continue
+		}
+		value, ok := v.(string)
+		if !ok {
+			value = fmt.Sprint(v)
+		}
+		if value != "" && !strings.Contains(line, value) {
+			dropped = append(dropped, k)
+		}
+	}
+	sort.Strings(dropped)
+	return dropped
+}
+
+// rawLinePrefix marks the original log record, when it is added after a formatted line
+const rawLinePrefix = "# raw: "
+
+// qmgrStartTime holds the time the queue manager started, in nanoseconds since the epoch, or zero if it hasn't started
+var qmgrStartTime int64
+
+// queueManagerStartedMessageID is the ID of the message logged when the queue manager has started
+const queueManagerStartedMessageID = "AMQ8003I"
+
+// markQueueManagerStarted records the time the queue manager started, if it hasn't already been recorded
+func markQueueManagerStarted(t time.Time) {
+	atomic.CompareAndSwapInt64(&qmgrStartTime, 0, t.UnixNano())
+}
+
+// getQueueManagerStartTime returns the time the queue manager started, and false if it hasn't started yet
+func getQueueManagerStartTime() (time.Time, bool) {
+	start := atomic.LoadInt64(&qmgrStartTime)
+	if start == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, start), true
+}
+
+// isFilteredRecord returns true if a parsed JSON log message should not be mirrored, based on the options
+func isFilteredRecord(obj map[string]interface{}, opts mirrorOptions) bool {
+	if opts.minLevel > levelDebug && newMQLogRecord(obj).Severity() < opts.minLevel {
+		return true
+	}
+	if opts.verboseWindow != nil && !opts.verboseWindow.contains(timeNow()) && newMQLogRecord(obj).Severity() < opts.quietLevel {
+		return true
+	}
+	// Excluded message IDs have already been dropped before the message was parsed, so if an ID is both
+	// included and excluded, the exclusion wins
+	if len(opts.includeIDs) > 0 && !opts.includeIDs[newMQLogRecord(obj).MessageID()] {
+		return true
+	}
+	if len(opts.requireFields) > 0 && !hasRequiredFields(obj, opts.requireFields, opts.requireAllFields) {
+		return true
+	}
+	if opts.shutdownMode == shutdownModeSuppress && isShutdownAffected(obj, opts.shutdownMode) {
+		return true
+	}
+	return false
+}
+
+// getRequiredFields returns the field names in MQ_LOGGING_CONSOLE_REQUIRE_FIELD, and whether
+// MQ_LOGGING_CONSOLE_REQUIRE_FIELD_MODE requires "all" of them to be present, rather than "any"
+func getRequiredFields() ([]string, bool, error) {
+	fields := make([]string, 0)
+	for _, f := range strings.Split(os.Getenv("MQ_LOGGING_CONSOLE_REQUIRE_FIELD"), ",") {
+		f = strings.TrimSpace(f)
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_CONSOLE_REQUIRE_FIELD_MODE"))); mode {
+	case "", "any":
+		return fields, false, nil
+	case "all":
+		return fields, true, nil
+	default:
+		return nil, false, fmt.Errorf("invalid value for MQ_LOGGING_CONSOLE_REQUIRE_FIELD_MODE: %v", mode)
+	}
+}
+
+// hasRequiredFields returns true if a parsed JSON log message has any (or all) of the fields
+func hasRequiredFields(obj map[string]interface{}, fields []string, all bool) bool {
+	for _, f := range fields {
+		_, ok := obj[f]
+		if ok && !all {
+			return true
+		}
+		if !ok && all {
+			return false
+		}
+	}
+	return all
+}
+
+// addsJSONFields returns true if the options require any fields to be added to JSON log messages
+func (o mirrorOptions) addsJSONFields() bool {
+	return len(o.labels) > 0 || o.recordBytes || o.elapsed || len(o.keyStyles) > 0 || o.timestampField != "" || o.epoch != "" || o.qmgrStatus != nil || o.severityNumber != severityNumberNone || o.reasonCode
+}
+
+// addJSONFields adds any configured fields to a copy of a parsed JSON log message, normalizes the field
+// names, and returns the re-encoded message.  If nothing needs to change, the original message is returned unchanged.
+func addJSONFields(obj map[string]interface{}, msg string, opts mirrorOptions) string {
+	tagShutdown := opts.shutdownMode == shutdownModeTag && isShutdownAffected(obj, opts.shutdownMode)
+	if !opts.addsJSONFields() && !tagShutdown {
+		return msg
+	}
+	// Work on a copy, so that the original message can still be formatted for other destinations
+	copied := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		copied[k] = v
+	}
+	obj = copied
+	addLabels(obj, opts.labels)
+	if tagShutdown {
+		obj["ibm_shuttingDown"] = true
+	}
+	if opts.epoch != "" {
+		obj["ibm_epoch"] = opts.epoch
+	}
+	if opts.qmgrStatus != nil {
+		obj["ibm_qmgrStatus"] = opts.qmgrStatus.get()
+	}
+	if opts.reasonCode {
+		if code, ok := findReasonCode(obj); ok {
+			obj["ibm_reasonCode"] = code
+		}
+	}
+	if opts.severityNumber != severityNumberNone {
+		obj["ibm_severityNumber"] = severityNumber(normalizeSeverity(obj), opts.severityNumber)
+	}
+	if opts.recordBytes {
+		// This is the size of the record as read from the source log, before any fields were added,
+		// and excluding the new-line.  This makes it independent of the other fields being added.
+		obj["ibm_recordBytes"] = len(msg)
+	}
+	if opts.elapsed {
+		// Messages from before the queue manager started don't have an elapsed time
+		if start, ok := getQueueManagerStartTime(); ok {
+			obj["ibm_qmgrElapsedMs"] = timeNow().Sub(start).Milliseconds()
+		}
+	}
+	datetime, hasDatetime := obj["ibm_datetime"]
+	if hasDatetime && opts.removeTimestamp {
+		delete(obj, "ibm_datetime")
+	}
+	out := normalizeKeys(obj, opts.keyStyles)
+	// The timestamp field is added after normalizing, so that it always has the name the operator asked for
+	if hasDatetime && opts.timestampField != "" {
+		out[opts.timestampField] = datetime
+	}
+	// #nosec G104 - a map parsed from JSON can always be marshalled again
+	b, _ := json.Marshal(out)
+	return string(b)
+}
+
+// addLabels adds the labels to a parsed JSON log message.  Fields which are already present in
+// the message are not overwritten.
+func addLabels(obj map[string]interface{}, labels []logLabel) {
+	for _, l := range labels {
+		if _, ok := obj[l.key]; !ok {
+			obj[l.key] = l.value
+		}
+	}
+}
+
+// addLabelsBasic appends the labels to a "basic" format line, before its trailing new-line
+func addLabelsBasic(line string, labels []logLabel) string {
+	if len(labels) == 0 {
+		return line
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.key+"="+l.value)
+	}
+	return fmt.Sprintf("%s [%s]\n", strings.TrimSuffix(line, "\n"), strings.Join(pairs, ", "))
 }
 
 func getDebug() bool {
@@ -221,77 +977,410 @@ func getDebug() bool {
 	return false
 }
 
+// configureMirroring sets up the settings and destinations which are common to all log formats.
+// It must be called after the logger has been created.
+func configureMirroring() (mirrorOptions, error) {
+	checkLogFormatConflict()
+	configureJournald()
+	configureMirrorOffsets()
+	err := configureMerge()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureConsole()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureOutputQueue()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureExcludeFile()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureExcludeDigest()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureDedup()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureEscalation()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureErrorSummary()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureStartupSkip()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureSinkBackoff()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	err = configureLogSinks()
+	if err != nil {
+		return mirrorOptions{}, err
+	}
+	opts, err := getMirrorOptions()
+	if err == nil && opts.dualOutput {
+		log.Printf("Warning: MQ_LOGGING_DUAL_OUTPUT is set, so each log message is mirrored twice.  This is intended for temporary use, while validating a log parser.")
+	}
+	return opts, err
+}
+
 func configureLogger(name string) (mirrorFunc, error) {
+	return configureLoggerFormat(name, getLogFormat())
+}
+
+// configureLoggerFormat configures the logger and log mirroring for the specified format.  If the
+// format is not valid, the logger and the returned mirrorFunc use basic format, as well as an error
+// being returned.
+func configureLoggerFormat(name string, f string) (mirrorFunc, error) {
 	var err error
-	f := getLogFormat()
 	d := getDebug()
-	switch f {
-	case "json":
+	switch {
+	case f == "json" || jsonConverters[f] != nil:
 		log, err = logger.NewLogger(os.Stderr, d, true, name)
 		if err != nil {
 			return nil, err
 		}
-		return func(msg string, isQMLog bool) bool {
-			arrLoggingConsoleExcludeIds := strings.Split(strings.ToUpper(os.Getenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID")), ",")
-			if isExcludedMsgIdPresent(msg, arrLoggingConsoleExcludeIds) {
-				//If excluded id is present do not mirror it, return back
+		eventsJSON = true
+	case f == "basic":
+		log, err = logger.NewLogger(os.Stderr, d, false, name)
+		if err != nil {
+			return nil, err
+		}
+		eventsJSON = false
+	default:
+		log, err = logger.NewLogger(os.Stderr, d, false, name)
+		if err != nil {
+			return nil, err
+		}
+		eventsJSON = false
+		return newBasicMirrorFunc(mirrorOptions{}), fmt.Errorf("invalid value for LOG_FORMAT: %v", f)
+	}
+	opts, err := configureMirroring()
+	if err != nil {
+		return nil, err
+	}
+	opts.excludeRegexps = getExcludeRegexps()
+	opts.qmgrStatus, err = configureQueueManagerStatus(name)
+	if err != nil {
+		return nil, err
+	}
+	err = configureDeclaredSinks(f, opts)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := getLogFormatOverrides()
+	if err != nil {
+		return nil, err
+	}
+	opts.source = "qmgr"
+	webOpts := opts
+	webOpts.source = "web"
+	sourceMirrorFuncs = make(map[string]mirrorFunc)
+	for source, format := range overrides {
+		if format != f {
+			if source == "web" {
+				sourceMirrorFuncs[source] = newMirrorFunc(format, webOpts)
+			} else {
+				sourceMirrorFuncs[source] = newMirrorFunc(format, opts)
+			}
+		}
+	}
+	if _, ok := sourceMirrorFuncs["web"]; !ok && len(declaredSinks) > 0 {
+		sourceMirrorFuncs["web"] = newMirrorFunc(f, webOpts)
+	}
+	return newMirrorFunc(f, opts), nil
+}
+
+// newMirrorFunc returns a mirrorFunc which writes log messages in the specified format ("json", "basic",
+// or one of the formats based on JSON)
+func newMirrorFunc(format string, opts mirrorOptions) mirrorFunc {
+	if format == "json" {
+		return newJSONMirrorFunc(opts)
+	}
+	if convert, ok := jsonConverters[format]; ok {
+		opts.convert = convert
+		if format == "syslog" {
+			// Messages in syslog format aren't JSON, so can't be checked against a JSON schema
+			opts.schema = nil
+		}
+		return newJSONMirrorFunc(opts)
+	}
+	return newBasicMirrorFunc(opts)
+}
+
+// newJSONMirrorFunc returns a mirrorFunc which writes log messages in JSON format
+func newJSONMirrorFunc(opts mirrorOptions) mirrorFunc {
+	return func(msg string, isQMLog bool) bool {
+		if id := excludedRule(msg, opts); id != "" {
+			//If excluded id is present do not mirror it, return back
+			recordExcluded(id)
+			return false
+		}
+		// Check if the message is JSON, ignoring any leading white space or byte-order mark
+		if trimmed := trimRecordPrefix(msg); len(trimmed) > 0 && trimmed[0] == '{' {
+			msg = trimmed
+			obj, err := processLogMessage(msg)
+			if err == nil && len(obj) == 0 {
+				return mirrorEmptyRecord(opts.emptyRecord)
+			}
+			if err == nil && isQMLog && filterQMLogMessage(obj) {
 				return false
 			}
-			// Check if the message is JSON
-			if len(msg) > 0 && msg[0] == '{' {
-				obj, err := processLogMessage(msg)
-				if err == nil && isQMLog && filterQMLogMessage(obj) {
+			if err == nil && obj["ibm_messageId"] == queueManagerStartedMessageID {
+				markQueueManagerStarted(timeNow())
+			}
+			if err == nil {
+				recordErrorMessage(obj)
+				checkFatalMessage(obj, opts.fatalIDs)
+			}
+			if err == nil && isReadyMessage(obj, opts.readyIDs) {
+				// Emit the ready event after the message itself, even if the message is filtered
+				defer emitReadyEvent(newMQLogRecord(obj).MessageID())
+			}
+			if err == nil && isShutdownMessage(obj, opts.shutdownIDs) {
+				// The shutdown message itself is mirrored as normal
+				defer markShuttingDown()
+			}
+			if err == nil && isFilteredRecord(obj, opts) {
+				return false
+			}
+			if err != nil {
+				reportUnparseableRecord(msg, err)
+			} else {
+				if isDuplicateRecord(obj, msg) {
+					return false
+				}
+				line := addJSONFields(obj, msg, opts)
+				if opts.convert != nil {
+					line = opts.convert(line)
+				}
+				if opts.schema != nil && !conformsToSchema(opts.schema, line) {
+					return false
+				}
+				if opts.template != nil {
+					line = renderTemplate(opts.template, line)
+				}
+				if opts.dualOutput {
+					line += "\n" + rawLinePrefix + msg
+				}
+				emitMirroredLine(opts.source, obj, line+"\n")
+			}
+		} else {
+			// The log being mirrored isn't JSON, so wrap it in a simple JSON message
+			// MQ error logs are usually JSON, but this is useful for Liberty logs - usually expect WLP_LOGGING_MESSAGE_FORMAT=JSON to be set when mirroring Liberty logs.
+			if isDuplicateRecord(nil, msg) {
+				return false
+			}
+			if opts.template != nil || opts.schema != nil || opts.convert != nil {
+				line := addJSONFields(map[string]interface{}{"message": msg}, msg, opts)
+				if line == msg {
+					// Nothing was added, so the message still needs to be encoded
+					// #nosec G104 - a string can always be marshalled
+					b, _ := json.Marshal(map[string]interface{}{"message": msg})
+					line = string(b)
+				}
+				if opts.convert != nil {
+					line = opts.convert(line)
+				}
+				if opts.schema != nil && !conformsToSchema(opts.schema, line) {
 					return false
 				}
-				if err != nil {
-					log.Printf("Failed to unmarshall JSON in log message - %v", msg)
-				} else {
-					fmt.Println(msg)
+				if opts.template != nil {
+					line = renderTemplate(opts.template, line)
 				}
+				emitMirroredLine(opts.source, nil, line+"\n")
+			} else if opts.addsJSONFields() {
+				emitMirroredLine(opts.source, nil, addJSONFields(map[string]interface{}{"message": msg}, msg, opts)+"\n")
 			} else {
-				// The log being mirrored isn't JSON, so wrap it in a simple JSON message
-				// MQ error logs are usually JSON, but this is useful for Liberty logs - usually expect WLP_LOGGING_MESSAGE_FORMAT=JSON to be set when mirroring Liberty logs.
-				fmt.Printf("{\"message\":\"%s\"}\n", msg)
+				emitMirroredLine(opts.source, nil, fmt.Sprintf("{\"message\":\"%s\"}\n", msg))
 			}
-			return true
-		}, nil
-	case "basic":
-		log, err = logger.NewLogger(os.Stderr, d, false, name)
-		if err != nil {
-			return nil, err
 		}
-		return func(msg string, isQMLog bool) bool {
-			arrLoggingConsoleExcludeIds := strings.Split(strings.ToUpper(os.Getenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID")), ",")
-			if isExcludedMsgIdPresent(msg, arrLoggingConsoleExcludeIds) {
-				//If excluded id is present do not mirror it, return back
+		return true
+	}
+}
+
+// newBasicMirrorFunc returns a mirrorFunc which writes log messages in basic format
+func newBasicMirrorFunc(opts mirrorOptions) mirrorFunc {
+	return func(msg string, isQMLog bool) bool {
+		if id := excludedRule(msg, opts); id != "" {
+			//If excluded id is present do not mirror it, return back
+			recordExcluded(id)
+			return false
+		}
+		// Check if the message is JSON, ignoring any leading white space or byte-order mark
+		if trimmed := trimRecordPrefix(msg); len(trimmed) > 0 && trimmed[0] == '{' {
+			msg = trimmed
+			// Parse the JSON message, and print a simplified version
+			obj, err := processLogMessage(msg)
+			if err == nil && len(obj) == 0 {
+				return mirrorEmptyRecord(opts.emptyRecord)
+			}
+			if err == nil && isQMLog && filterQMLogMessage(obj) {
 				return false
 			}
-			// Check if the message is JSON
-			if len(msg) > 0 && msg[0] == '{' {
-				// Parse the JSON message, and print a simplified version
-				obj, err := processLogMessage(msg)
-				if err == nil && isQMLog && filterQMLogMessage(obj) {
+			if err == nil && obj["ibm_messageId"] == queueManagerStartedMessageID {
+				markQueueManagerStarted(timeNow())
+			}
+			if err == nil {
+				recordErrorMessage(obj)
+				checkFatalMessage(obj, opts.fatalIDs)
+			}
+			if err == nil && isReadyMessage(obj, opts.readyIDs) {
+				// Emit the ready event after the message itself, even if the message is filtered
+				defer emitReadyEvent(newMQLogRecord(obj).MessageID())
+			}
+			if err == nil && isShutdownMessage(obj, opts.shutdownIDs) {
+				// The shutdown message itself is mirrored as normal
+				defer markShuttingDown()
+			}
+			if err == nil && isFilteredRecord(obj, opts) {
+				return false
+			}
+			if err == nil {
+				var ok bool
+				obj, ok = handleMissingMessage(obj, opts.missingMessage, opts.missingMessagePlaceholder)
+				if !ok {
 					return false
 				}
-				if err != nil {
-					log.Printf("Failed to unmarshall JSON in log message - %v", err)
-				} else {
-					fmt.Print(formatBasic(obj))
-				}
+			}
+			if err != nil {
+				reportUnparseableRecord(msg, err)
 			} else {
-				// The log being mirrored isn't JSON, so just print it.
-				// MQ error logs are usually JSON, but this is useful for Liberty logs - usually expect WLP_LOGGING_MESSAGE_FORMAT=JSON to be set when mirroring Liberty logs.
-				fmt.Println(msg)
+				if isDuplicateRecord(obj, msg) {
+					return false
+				}
+				labels := opts.labels
+				if opts.shutdownMode == shutdownModeTag && isShutdownAffected(obj, opts.shutdownMode) {
+					labels = append(append([]logLabel{}, labels...), logLabel{key: "ibm_shuttingDown", value: "true"})
+				}
+				line := addLabelsBasic(formatBasic(obj), labels)
+				if opts.droppedFields {
+					if dropped := droppedBasicFields(obj, line); len(dropped) > 0 {
+						log.Debugf("Fields not shown in basic format for %v: %v", msg, strings.Join(dropped, ", "))
+					}
+				}
+				if opts.rawLine || opts.dualOutput {
+					line += rawLinePrefix + msg + "\n"
+				}
+				emitMirroredLine(opts.source, obj, line)
 			}
-			return true
-		}, nil
-	default:
-		log, err = logger.NewLogger(os.Stdout, d, false, name)
+		} else {
+			// The log being mirrored isn't JSON, so just print it.
+			// MQ error logs are usually JSON, but this is useful for Liberty logs - usually expect WLP_LOGGING_MESSAGE_FORMAT=JSON to be set when mirroring Liberty logs.
+			if isDuplicateRecord(nil, msg) {
+				return false
+			}
+			emitMirroredLine(opts.source, nil, addLabelsBasic(msg+"\n", opts.labels))
+		}
+		return true
+	}
+}
+
+// logSink is an additional destination for mirrored log messages, alongside the console
+type logSink interface {
+	// Write sends a log line, which includes its trailing new-line.  It must not block for long.
+	Write(line string)
+	// Close flushes any buffered log lines, and releases resources
+	Close() error
+}
+
+// sinks holds the additional destinations for mirrored log messages
+var sinks []logSink
+
+// configureLogSinks creates any additional destinations for mirrored log messages
+func configureLogSinks() error {
+	sinks = nil
+	h, err := configureHTTPSink()
+	if err != nil {
+		return err
+	}
+	if h != nil {
+		sinks = append(sinks, h)
+	}
+	u, err := configureUDSSink()
+	if err != nil {
+		return err
+	}
+	if u != nil {
+		sinks = append(sinks, u)
+	}
+	return nil
+}
+
+// closeLogSinks flushes and closes all additional destinations for mirrored log messages
+func closeLogSinks() {
+	for _, s := range sinks {
+		err := s.Close()
 		if err != nil {
-			return nil, err
+			log.Errorf("Error closing log sink: %v", err)
+		}
+	}
+	closeDeclaredSinks()
+}
+
+// terminateRecord returns a log record with exactly one trailing new-line.  Depending on the format, the
+// record may already have been given a new-line, or may end with the new-line (or carriage return) of a
+// multi-line message or template.
+func terminateRecord(line string) string {
+	return strings.TrimRight(line, "\r\n") + "\n"
+}
+
+// emitMirroredLine writes a mirrored log line, which is terminated with a single new-line, whether or
+// not it already has one.  The obj parameter is the parsed JSON log message, or nil if the message
+// wasn't JSON, and source is the category of the log it came from, or empty if it isn't known.
+func emitMirroredLine(source string, obj map[string]interface{}, line string) {
+	line = terminateRecord(line)
+	mirroredVolume.record(timeNow(), len(line))
+	for _, s := range sinks {
+		s.Write(line)
+	}
+	if len(declaredSinks) > 0 {
+		for _, d := range declaredSinks {
+			d.write(source, obj, line)
+		}
+		return
+	}
+	if journal != nil {
+		err := journal.send(obj, strings.TrimSuffix(line, "\n"))
+		if err == nil {
+			return
 		}
-		return nil, fmt.Errorf("invalid value for LOG_FORMAT: %v", f)
+		log.Debugf("Unable to send log message to journald: %v", err)
+	}
+	// Colors are only used in basic format, so that JSON and syslog can still be parsed
+	if consoleColors != nil && logFormatForSource(source) == "basic" {
+		line = consoleColors.colorize(obj, line)
 	}
+	if w := severityStream(obj); w != nil {
+		w.WriteLine(line, "")
+		return
+	}
+	writeConsole(line, newMQLogRecord(obj).MessageID())
+}
+
+// writeConsole writes a log line to the console, through the output queue if there is one
+func writeConsole(line string, messageID string) {
+	if output != nil {
+		output.Write(line, messageID)
+		return
+	}
+	console.WriteLine(line, messageID)
+}
+
+// trimRecordPrefix removes any white space or byte-order mark from the start of a log record.  Some
+// tools which write to the logs add these, which would otherwise stop a JSON record being recognized.
+func trimRecordPrefix(msg string) string {
+	return strings.TrimLeft(msg, "\ufeff \t\r")
 }
 
 func processLogMessage(msg string) (map[string]interface{}, error) {
@@ -300,69 +1389,154 @@ func processLogMessage(msg string) (map[string]interface{}, error) {
 	return obj, err
 }
 
+// osHostname returns the host name of the container, and can be replaced for testing
+var osHostname = os.Hostname
+
+// hostnameWarningOnce makes sure the warning about a missing host name is only logged once
+var hostnameWarningOnce sync.Once
+
+// getFilterHostname returns the host name used to filter queue manager log messages for a multi-instance
+// queue manager.  This is MQ_MULTI_INSTANCE_HOSTNAME if it is set, otherwise the container's host name.
+func getFilterHostname() (string, error) {
+	if hostname := strings.TrimSpace(os.Getenv("MQ_MULTI_INSTANCE_HOSTNAME")); hostname != "" {
+		return hostname, nil
+	}
+	hostname, err := osHostname()
+	if err == nil && hostname == "" {
+		err = errors.New("host name is empty")
+	}
+	return hostname, err
+}
+
 func filterQMLogMessage(obj map[string]interface{}) bool {
-	hostname, err := os.Hostname()
-	if os.Getenv("MQ_MULTI_INSTANCE") == "true" && err == nil && !strings.Contains(obj["host"].(string), hostname) {
-		return true
+	if os.Getenv("MQ_MULTI_INSTANCE") != "true" {
+		return false
 	}
-	return false
+	hostname, err := getFilterHostname()
+	if err != nil {
+		hostnameWarningOnce.Do(func() {
+			log.Printf("Warning: unable to get the host name, so log messages from other instances of the queue manager will not be filtered.  Set MQ_MULTI_INSTANCE_HOSTNAME to the host name to use: %v", err)
+		})
+		return false
+	}
+	return !strings.Contains(newMQLogRecord(obj).Field("host"), hostname)
 }
 
 // Function to check if ids provided in MQ_LOGGING_CONSOLE_EXCLUDE_ID are present in given log line or not
 func isExcludedMsgIdPresent(msg string, envExcludeIds []string) bool {
+	return excludedMsgId(msg, envExcludeIds) != ""
+}
+
+// excludedMsgId returns the first id provided in MQ_LOGGING_CONSOLE_EXCLUDE_ID which is present in the
+// given log line, or an empty string if none are present
+func excludedMsgId(msg string, envExcludeIds []string) string {
 	for _, id := range envExcludeIds {
-		if id != "" && strings.Contains(msg, strings.TrimSpace(id)) {
-			return true
+		id = strings.TrimSpace(id)
+		if id != "" && strings.Contains(msg, id) {
+			return id
 		}
 	}
-	return false
+	return ""
+}
+
+// getIncludeIDs returns the message IDs in MQ_LOGGING_CONSOLE_INCLUDE_ID.  If any are set, only JSON log
+// messages with one of these IDs are mirrored.  If none are set, the set is empty, and all messages are
+// mirrored as usual.  MQ_LOGGING_CONSOLE_EXCLUDE_ID takes precedence over this list.
+func getIncludeIDs() map[string]bool {
+	ids := make(map[string]bool)
+	for _, id := range strings.Split(strings.ToUpper(os.Getenv("MQ_LOGGING_CONSOLE_INCLUDE_ID")), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// defaultDiagPaths are the directories listed when collecting diagnostics
+var defaultDiagPaths = []string{
+	"/mnt/",
+	"/mnt/mqm",
+	"/mnt/mqm/data",
+	"/mnt/mqm-log/log",
+	"/mnt/mqm-data/qmgrs",
+	"/var/mqm",
+	"/var/mqm/errors",
+	"/etc/mqm",
+	"/run",
+}
+
+// getDiagPaths returns the directories to list when collecting diagnostics.  MQ_DIAG_PATHS can
+// hold a comma-separated list of extra directories, and directories prefixed with "-" are
+// removed from the defaults.  Paths which aren't absolute, or which don't exist, are skipped.
+func getDiagPaths() []string {
+	paths := append([]string{}, defaultDiagPaths...)
+	for _, p := range strings.Split(os.Getenv("MQ_DIAG_PATHS"), ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "-") {
+			remove := filepath.Clean(strings.TrimPrefix(p, "-"))
+			kept := paths[:0]
+			for _, d := range paths {
+				if filepath.Clean(d) != remove {
+					kept = append(kept, d)
+				}
+			}
+			paths = kept
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			log.Printf("Ignoring diagnostics path %v, which is not an absolute path", p)
+			continue
+		}
+		_, err := os.Stat(p)
+		if err != nil {
+			log.Printf("Ignoring diagnostics path %v: %v", p, err)
+			continue
+		}
+		paths = append(paths, filepath.Clean(p))
+	}
+	return paths
 }
 
 func logDiagnostics() {
 	if getDebug() {
 		log.Debug("--- Start Diagnostics ---")
+		start := time.Now()
 
 		// show the directory ownership/permissions
-		// #nosec G104
-		out, _, _ := command.Run("ls", "-l", "/mnt/")
-		log.Debugf("/mnt/:\n%s", out)
-		// #nosec G104
-		out, _, _ = command.Run("ls", "-l", "/mnt/mqm")
-		log.Debugf("/mnt/mqm:\n%s", out)
-		// #nosec G104
-		out, _, _ = command.Run("ls", "-l", "/mnt/mqm/data")
-		log.Debugf("/mnt/mqm/data:\n%s", out)
-		// #nosec G104
-		out, _, _ = command.Run("ls", "-l", "/mnt/mqm-log/log")
-		log.Debugf("/mnt/mqm-log/log:\n%s", out)
-		// #nosec G104
-		out, _, _ = command.Run("ls", "-l", "/mnt/mqm-data/qmgrs")
-		log.Debugf("/mnt/mqm-data/qmgrs:\n%s", out)
-		// #nosec G104
-		out, _, _ = command.Run("ls", "-l", "/var/mqm")
-		log.Debugf("/var/mqm:\n%s", out)
-		// #nosec G104
-		out, _, _ = command.Run("ls", "-l", "/var/mqm/errors")
-		log.Debugf("/var/mqm/errors:\n%s", out)
-		// #nosec G104
-		out, _, _ = command.Run("ls", "-l", "/etc/mqm")
-		log.Debugf("/etc/mqm:\n%s", out)
-		// #nosec G104
-		out, _, _ = command.Run("ls", "-l", "/run")
-		log.Debugf("/run:\n%s", out)
+		for _, path := range getDiagPaths() {
+			cmdStart := time.Now()
+			// #nosec G104 G204 - paths are validated by getDiagPaths
+			out, _, _ := command.Run("ls", "-l", path)
+			log.Debugf("%v:\n%s", path, out)
+			// Listing a directory on a slow volume (e.g. NFS) can take a long time
+			log.Debugf("Diagnostics command \"ls -l %v\" took %v", path, time.Since(cmdStart))
+		}
 
 		// Print out summary of any FDCs
+		cmdStart := time.Now()
 		// #nosec G204
 		cmd := exec.Command("/opt/mqm/bin/ffstsummary")
 		cmd.Dir = "/var/mqm/errors"
 		// #nosec G104
 		outB, _ := cmd.CombinedOutput()
 		log.Debugf("ffstsummary:\n%s", string(outB))
+		log.Debugf("Diagnostics command \"ffstsummary\" took %v", time.Since(cmdStart))
 
+		log.Debugf("Diagnostics collection took %v", time.Since(start))
 		log.Debug("---  End Diagnostics  ---")
 	}
 }
 
+// getSuppressDeprecation returns true if the operator has asked not to see deprecation messages
+func getSuppressDeprecation() bool {
+	suppress := os.Getenv("MQ_LOGGING_SUPPRESS_DEPRECATION")
+	return suppress == "true" || suppress == "1"
+}
+
 // Returns the value of MQ_LOGGING_CONSOLE_SOURCE environment variable
 func getMQLogConsoleSource() string {
 	return strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_CONSOLE_SOURCE")))
@@ -394,8 +1568,21 @@ func isLogConsoleSourceValid() bool {
 	return retValue
 }
 
-// To check which all logs have to be mirrored
+// To check which all logs have to be mirrored.  A source which isn't selected in
+// MQ_LOGGING_CONSOLE_SOURCE can still be mirrored if it's referenced by other settings, depending on
+// MQ_LOGGING_SOURCE_CONFLICT.
 func checkLogSourceForMirroring(source string) bool {
+	if isLogSourceSelected(source) {
+		return true
+	}
+	// An invalid policy is reported at startup, so the default is used here
+	policy, _ := getSourceConflictPolicy()
+	return policy == sourceConflictInclude && len(getLogSourceReferences()[source]) > 0
+}
+
+// isLogSourceSelected returns true if a source is selected for mirroring in MQ_LOGGING_CONSOLE_SOURCE,
+// or by default if it isn't set
+func isLogSourceSelected(source string) bool {
 	logsrcs := getMQLogConsoleSource()
 
 	//Nothing set, this is when we mirror all
@@ -422,8 +1609,10 @@ func checkLogSourceForMirroring(source string) bool {
 			//If value of source is web and it exists in environment variable, and mirror web logs
 			if source == "web" {
 				//If older environment variable is set make sure to print appropriate message
-				if os.Getenv("MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG") != "" {
-					log.Println("Environment variable MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG has now been replaced. Use MQ_LOGGING_CONSOLE_SOURCE instead.")
+				if os.Getenv("MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG") != "" && !getSuppressDeprecation() {
+					webLogDeprecationOnce.Do(func() {
+						log.Println("Environment variable MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG has now been replaced. Use MQ_LOGGING_CONSOLE_SOURCE instead.")
+					})
 				}
 				return true
 			}
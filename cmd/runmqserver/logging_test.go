@@ -16,11 +16,22 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ibm-messaging/mq-container/pkg/logger"
+	"github.com/ibm-messaging/mq-container/pkg/mqini"
 )
 
 var formatBasicTests = []struct {
@@ -137,3 +148,929 @@ func TestIsExcludedMsgIDPresent(t *testing.T) {
 		}
 	}
 }
+
+// captureLog redirects the internal logger to a buffer for the duration of a test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	oldLog := log
+	l, err := logger.NewLogger(&buf, true, false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log = l
+	t.Cleanup(func() { log = oldLog })
+	return &buf
+}
+
+func TestWebLogDeprecationPrintedOnce(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_SOURCE", "web")
+	t.Setenv("MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG", "true")
+	webLogDeprecationOnce = sync.Once{}
+	buf := captureLog(t)
+	for i := 0; i < 3; i++ {
+		checkLogSourceForMirroring("web")
+	}
+	count := strings.Count(buf.String(), "MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG has now been replaced")
+	if count != 1 {
+		t.Errorf("Expected deprecation message to be printed once, got %v", count)
+	}
+}
+
+func TestWebLogDeprecationSuppressed(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_SOURCE", "web")
+	t.Setenv("MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG", "true")
+	t.Setenv("MQ_LOGGING_SUPPRESS_DEPRECATION", "true")
+	webLogDeprecationOnce = sync.Once{}
+	buf := captureLog(t)
+	checkLogSourceForMirroring("web")
+	if strings.Contains(buf.String(), "MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG") {
+		t.Errorf("Expected deprecation message to be suppressed, got %v", buf.String())
+	}
+}
+
+var parseLogLabelsTests = []struct {
+	in        string
+	expected  []logLabel
+	expectErr bool
+}{
+	{"", []logLabel{}, false},
+	{"env=prod", []logLabel{{"env", "prod"}}, false},
+	{"env=prod, team=payments", []logLabel{{"env", "prod"}, {"team", "payments"}}, false},
+	{"expr=a=b", []logLabel{{"expr", "a=b"}}, false},
+	{"env", nil, true},
+	{"env=prod,,team=payments", nil, true},
+	{"=prod", nil, true},
+}
+
+func TestParseLogLabels(t *testing.T) {
+	for _, table := range parseLogLabelsTests {
+		labels, err := parseLogLabels(table.in)
+		if table.expectErr {
+			if err == nil {
+				t.Errorf("parseLogLabels(%q) - expected error, got %v", table.in, labels)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseLogLabels(%q) - unexpected error: %v", table.in, err)
+			continue
+		}
+		if fmt.Sprint(labels) != fmt.Sprint(table.expected) {
+			t.Errorf("parseLogLabels(%q) - expected %v, got %v", table.in, table.expected, labels)
+		}
+	}
+}
+
+func TestAddLabels(t *testing.T) {
+	labels := []logLabel{{"env", "prod"}, {"message", "ignored"}}
+	obj := map[string]interface{}{"message": "Hello world"}
+	out := addJSONFields(obj, "{\"message\":\"Hello world\"}", mirrorOptions{labels: labels})
+	expected := "{\"env\":\"prod\",\"message\":\"Hello world\"}"
+	if out != expected {
+		t.Errorf("addJSONFields() - expected %v, got %v", expected, out)
+	}
+	out = addLabelsBasic("2020/06/24 00:00:00 Hello world\n", labels[:1])
+	expected = "2020/06/24 00:00:00 Hello world [env=prod]\n"
+	if out != expected {
+		t.Errorf("addLabelsBasic() - expected %q, got %q", expected, out)
+	}
+}
+
+func TestResolveQueueManagerTimeout(t *testing.T) {
+	oldGet, oldTimeout := getQueueManager, qmgrResolveTimeout
+	defer func() {
+		getQueueManager, qmgrResolveTimeout = oldGet, oldTimeout
+	}()
+	release := make(chan struct{})
+	defer close(release)
+	getQueueManager = func(name string) (*mqini.QueueManager, error) {
+		// Simulate a volume which is very slow to respond
+		<-release
+		return &mqini.QueueManager{Name: name}, nil
+	}
+	qmgrResolveTimeout = 100 * time.Millisecond
+	var wg sync.WaitGroup
+	start := time.Now()
+	_, err := mirrorQueueManagerErrorLogs(context.Background(), &wg, "QM1", false, func(msg string, isQMLog bool) bool {
+		return true
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Expected resolution to give up after the timeout, took %v", time.Since(start))
+	}
+}
+
+func TestResolveQueueManagerCancelled(t *testing.T) {
+	oldGet := getQueueManager
+	defer func() { getQueueManager = oldGet }()
+	release := make(chan struct{})
+	defer close(release)
+	getQueueManager = func(name string) (*mqini.QueueManager, error) {
+		<-release
+		return &mqini.QueueManager{Name: name}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := resolveQueueManager(ctx, "QM1")
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("Expected a cancellation error, got %v", err)
+	}
+}
+
+func TestResolveQueueManagerError(t *testing.T) {
+	oldGet := getQueueManager
+	defer func() { getQueueManager = oldGet }()
+	getQueueManager = func(name string) (*mqini.QueueManager, error) {
+		return nil, errors.New("dspmqinf failed")
+	}
+	_, err := resolveQueueManager(context.Background(), "QM1")
+	if err == nil || err.Error() != "dspmqinf failed" {
+		t.Errorf("Expected resolution error to be returned, got %v", err)
+	}
+}
+
+var logFormatConflictTests = []struct {
+	newFormat       string
+	oldFormat       string
+	expectedMessage string
+}{
+	{"json", "basic", "Using MQ_LOGGING_CONSOLE_FORMAT."},
+	{"JSON", "json", ""},
+	{"json", "", ""},
+	{"", "basic", ""},
+	{"yaml", "json", "Warning: Environment variables MQ_LOGGING_CONSOLE_FORMAT=yaml and LOG_FORMAT=json are both set, but MQ_LOGGING_CONSOLE_FORMAT is not a valid log format. Using basic."},
+}
+
+func TestCheckLogFormatConflict(t *testing.T) {
+	for _, table := range logFormatConflictTests {
+		t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", table.newFormat)
+		t.Setenv("LOG_FORMAT", table.oldFormat)
+		buf := captureLog(t)
+		checkLogFormatConflict()
+		if table.expectedMessage == "" && buf.Len() != 0 || !strings.Contains(buf.String(), table.expectedMessage) {
+			t.Errorf("checkLogFormatConflict() with MQ_LOGGING_CONSOLE_FORMAT=%v, LOG_FORMAT=%v - expected %q, got %q", table.newFormat, table.oldFormat, table.expectedMessage, buf.String())
+		}
+		// An invalid value is never reported as being used
+		if !isValidLogFormat(strings.ToLower(table.newFormat)) && strings.Contains(buf.String(), "Using MQ_LOGGING_CONSOLE_FORMAT") {
+			t.Errorf("checkLogFormatConflict() with MQ_LOGGING_CONSOLE_FORMAT=%v - expected the invalid value not to be used, got %q", table.newFormat, buf.String())
+		}
+	}
+}
+
+func TestAddRecordBytes(t *testing.T) {
+	msg := "{\"message\":\"Hello world\"}"
+	obj, err := processLogMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := addJSONFields(obj, msg, mirrorOptions{recordBytes: true})
+	expected := "{\"ibm_recordBytes\":25,\"message\":\"Hello world\"}"
+	if out != expected {
+		t.Errorf("addJSONFields() - expected %v, got %v", expected, out)
+	}
+	// The message should be unchanged if no fields are added
+	out = addJSONFields(obj, msg, mirrorOptions{})
+	if out != msg {
+		t.Errorf("addJSONFields() - expected %v, got %v", msg, out)
+	}
+}
+
+func TestLogTerminationExitCode(t *testing.T) {
+	oldPath, oldCode := terminationLogPath, terminationExitCode
+	defer func() {
+		terminationLogPath, terminationExitCode = oldPath, oldCode
+	}()
+	terminationLogPath = filepath.Join(t.TempDir(), "termination-log")
+	captureLog(t)
+
+	terminationExitCode = nil
+	logTermination("Queue manager failed")
+	b, err := os.ReadFile(terminationLogPath + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\"message\":\"Queue manager failed\",\"exitCode\":null}"
+	if string(b) != expected {
+		t.Errorf("Expected termination record %v, got %v", expected, string(b))
+	}
+
+	setTerminationExitCode(1)
+	logTerminationf("Error checking license acceptance: %v", "no license")
+	b, err = os.ReadFile(terminationLogPath + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "{\"message\":\"Error checking license acceptance: no license\",\"exitCode\":1}"
+	if string(b) != expected {
+		t.Errorf("Expected termination record %v, got %v", expected, string(b))
+	}
+	b, err = os.ReadFile(terminationLogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "Error checking license acceptance: no license" {
+		t.Errorf("Expected plain termination message to be unchanged, got %v", string(b))
+	}
+}
+
+func TestMirrorWebServerLogsDisabled(t *testing.T) {
+	t.Setenv("MQ_ENABLE_EMBEDDED_WEB_SERVER", "false")
+	buf := captureLog(t)
+	var wg sync.WaitGroup
+	c, err := mirrorWebServerLogs(context.Background(), &wg, "QM1", false, func(msg string, isQMLog bool) bool {
+		return true
+	})
+	wg.Wait()
+	if err != nil || c != nil {
+		t.Errorf("Expected no mirroring to be started, got channel=%v, err=%v", c, err)
+	}
+	if !strings.Contains(buf.String(), "Web server is not enabled") {
+		t.Errorf("Expected informational message, got %v", buf.String())
+	}
+}
+
+func TestMirrorWebServerLogsPathNeverAppears(t *testing.T) {
+	oldDir, oldGrace := webServerDir, webServerGracePeriod
+	defer func() {
+		webServerDir, webServerGracePeriod = oldDir, oldGrace
+	}()
+	webServerDir = filepath.Join(t.TempDir(), "web")
+	webServerGracePeriod = 200 * time.Millisecond
+	t.Setenv("MQ_ENABLE_EMBEDDED_WEB_SERVER", "true")
+	buf := captureLog(t)
+	var wg sync.WaitGroup
+	start := time.Now()
+	c, err := mirrorWebServerLogs(context.Background(), &wg, "QM1", false, func(msg string, isQMLog bool) bool {
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Startup isn't held up while waiting for the directory
+	if elapsed := time.Since(start); elapsed >= webServerGracePeriod {
+		t.Errorf("Expected to return without waiting for the directory; took %v", elapsed)
+	}
+	// If mirroring had been started, this would hang, as the context is never cancelled
+	wg.Wait()
+	select {
+	case err := <-c:
+		t.Errorf("Expected no error; got %v", err)
+	default:
+	}
+	if !strings.Contains(buf.String(), "does not exist, so web server logs will not be mirrored") {
+		t.Errorf("Expected informational message, got %v", buf.String())
+	}
+}
+
+func TestMirrorWebServerLogsRequired(t *testing.T) {
+	captureLog(t)
+	oldDir, oldGrace, oldPath, oldRequest := webServerDir, webServerGracePeriod, terminationLogPath, requestTermination
+	defer func() {
+		webServerDir, webServerGracePeriod, terminationLogPath, requestTermination = oldDir, oldGrace, oldPath, oldRequest
+		fatalMessageOnce = sync.Once{}
+		fatalMessageReason = ""
+	}()
+	webServerDir = filepath.Join(t.TempDir(), "web")
+	webServerGracePeriod = 200 * time.Millisecond
+	terminationLogPath = filepath.Join(t.TempDir(), "termination-log")
+	requested := 0
+	requestTermination = func() { requested++ }
+	t.Setenv("MQ_ENABLE_EMBEDDED_WEB_SERVER", "true")
+	t.Setenv("MQ_LOGGING_REQUIRE_SOURCES", "true")
+	var wg sync.WaitGroup
+	c, err := mirrorWebServerLogs(context.Background(), &wg, "QM1", false, func(msg string, isQMLog bool) bool {
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	select {
+	case err := <-c:
+		if !strings.Contains(err.Error(), "web server logs are required") {
+			t.Errorf("Expected an error when the required web server logs never appear; got %v", err)
+		}
+	default:
+		t.Error("Expected an error when the required web server logs never appear")
+	}
+	if requested != 1 || !strings.Contains(getFatalMessageReason(), "web server logs are required") {
+		t.Errorf("Expected termination to be requested; got %v requests, with reason %q", requested, getFatalMessageReason())
+	}
+}
+
+func TestMirrorWebServerLogsEmptyNotice(t *testing.T) {
+	oldDir, oldJSON := webServerDir, eventsJSON
+	defer func() { webServerDir, eventsJSON = oldDir, oldJSON }()
+	webServerDir = filepath.Join(t.TempDir(), "web")
+	logDir := filepath.Join(webServerDir, "installations/Installation1/servers/mqweb/logs")
+	os.MkdirAll(logDir, 0700)
+	os.WriteFile(filepath.Join(logDir, "messages.log"), []byte{}, 0600)
+	eventsJSON = true
+	out := captureConsole(t)
+	t.Setenv("MQ_ENABLE_EMBEDDED_WEB_SERVER", "true")
+	t.Setenv("MQ_LOGGING_WEB_EMPTY_NOTICE", "100ms")
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	_, err := mirrorWebServerLogs(ctx, &wg, "QM1", false, func(msg string, isQMLog bool) bool {
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	cancel()
+	wg.Wait()
+	if strings.Count(out.String(), "\"ibm_event\":\"log_empty\"") != 1 {
+		t.Errorf("Expected a single notice for the empty web server log; got %v", out.String())
+	}
+}
+
+func TestAddElapsedTime(t *testing.T) {
+	oldNow, oldStart := timeNow, qmgrStartTime
+	defer func() {
+		timeNow, qmgrStartTime = oldNow, oldStart
+	}()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	qmgrStartTime = 0
+	opts := mirrorOptions{elapsed: true}
+
+	// Before the queue manager has started, there's no elapsed time
+	obj := map[string]interface{}{"message": "A"}
+	out := addJSONFields(obj, "", opts)
+	if out != "{\"message\":\"A\"}" {
+		t.Errorf("Expected no elapsed time before start, got %v", out)
+	}
+
+	markQueueManagerStarted(now.Add(-1500 * time.Millisecond))
+	// A second start time should be ignored
+	markQueueManagerStarted(now)
+	obj = map[string]interface{}{"message": "B"}
+	out = addJSONFields(obj, "", opts)
+	if out != "{\"ibm_qmgrElapsedMs\":1500,\"message\":\"B\"}" {
+		t.Errorf("Expected elapsed time of 1500ms, got %v", out)
+	}
+}
+
+func TestGetDiagPaths(t *testing.T) {
+	captureLog(t)
+	custom := t.TempDir()
+	missing := filepath.Join(custom, "missing")
+	t.Setenv("MQ_DIAG_PATHS", custom+", relative/path,"+missing+",-/run")
+	paths := getDiagPaths()
+	has := func(p string) bool {
+		for _, d := range paths {
+			if d == p {
+				return true
+			}
+		}
+		return false
+	}
+	if !has(custom) {
+		t.Errorf("Expected custom path %v to be listed; got %v", custom, paths)
+	}
+	if !has("/var/mqm") {
+		t.Errorf("Expected default paths to be kept; got %v", paths)
+	}
+	for _, p := range []string{"relative/path", missing, "/run"} {
+		if has(p) {
+			t.Errorf("Expected path %v to be skipped; got %v", p, paths)
+		}
+	}
+}
+
+var timestampFieldTests = []struct {
+	name     string
+	opts     mirrorOptions
+	expected string
+}{
+	{"Duplicate", mirrorOptions{timestampField: "@timestamp"}, "{\"@timestamp\":\"2024-01-01T10:00:00.000Z\",\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"A\"}"},
+	{"Rename", mirrorOptions{timestampField: "time", removeTimestamp: true}, "{\"message\":\"A\",\"time\":\"2024-01-01T10:00:00.000Z\"}"},
+	{"Rename with key style", mirrorOptions{timestampField: "Time", removeTimestamp: true, keyStyles: []keyStyle{keyStyleLowercase}}, "{\"Time\":\"2024-01-01T10:00:00.000Z\",\"message\":\"A\"}"},
+}
+
+func TestTimestampField(t *testing.T) {
+	for _, table := range timestampFieldTests {
+		t.Run(table.name, func(t *testing.T) {
+			obj := map[string]interface{}{"ibm_datetime": "2024-01-01T10:00:00.000Z", "message": "A"}
+			out := addJSONFields(obj, "", table.opts)
+			if out != table.expected {
+				t.Errorf("Expected %v; got %v", table.expected, out)
+			}
+		})
+	}
+}
+
+func TestTimestampFieldRemoveRequiresField(t *testing.T) {
+	t.Setenv("MQ_LOGGING_TIMESTAMP_FIELD", "")
+	t.Setenv("MQ_LOGGING_TIMESTAMP_FIELD_REMOVE_ORIGINAL", "true")
+	opts, err := getMirrorOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.removeTimestamp {
+		t.Error("Expected ibm_datetime not to be removed, when no timestamp field is set")
+	}
+}
+
+func TestBasicRawLine(t *testing.T) {
+	oldLog := log
+	defer func() { log = oldLog }()
+	raw := "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"Hello\"}"
+	for _, debug := range []string{"false", "true"} {
+		t.Run("DEBUG="+debug, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "basic")
+			t.Setenv("MQ_LOGGING_BASIC_RAW", "true")
+			t.Setenv("DEBUG", debug)
+			mf, err := configureLogger("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := captureConsole(t)
+			mf(raw, false)
+			expected := "2024-01-01T10:00:00.000Z Hello\n"
+			if debug == "true" {
+				expected += "# raw: " + raw + "\n"
+			}
+			if buf.String() != expected {
+				t.Errorf("Expected %q; got %q", expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestBasicDroppedFields(t *testing.T) {
+	oldLog := log
+	defer func() { log = oldLog }()
+	raw := "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"ibm_messageId\":\"AMQ9209E\",\"message\":\"AMQ9209E: Connection closed\",\"ibm_commentInsert1\":\"APP.SVRCONN\",\"ibm_processId\":1234,\"ibm_serverName\":\"QM1\",\"loglevel\":\"ERROR\"}"
+	for _, debug := range []string{"false", "true"} {
+		t.Run("DEBUG="+debug, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "basic")
+			t.Setenv("MQ_LOGGING_BASIC_DROPPED_FIELDS", "true")
+			t.Setenv("DEBUG", debug)
+			_, err := configureLogger("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts, err := getMirrorOptions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			logBuf := captureLog(t)
+			buf := captureConsole(t)
+			newBasicMirrorFunc(opts)(raw, false)
+			expected := "2024-01-01T10:00:00.000Z AMQ9209E: Connection closed [CommentInsert1(APP.SVRCONN)]\n"
+			if buf.String() != expected {
+				t.Errorf("Expected %q; got %q", expected, buf.String())
+			}
+			// The message ID and insert are shown, but the other fields aren't
+			report := "Fields not shown in basic format for " + raw + ": ibm_processId, ibm_serverName, loglevel"
+			if strings.Contains(logBuf.String(), report) != (debug == "true") {
+				t.Errorf("Expected dropped field report=%v; got %q", debug == "true", logBuf.String())
+			}
+		})
+	}
+}
+
+func TestDualOutput(t *testing.T) {
+	oldLog := log
+	defer func() { log = oldLog }()
+	raw := "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"Hello\"}"
+	var tests = []struct {
+		format   string
+		expected string
+	}{
+		{"basic", "2024-01-01T10:00:00.000Z Hello [host=mq1]\n# raw: " + raw + "\n"},
+		{"json", "{\"host\":\"mq1\",\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"Hello\"}\n# raw: " + raw + "\n"},
+	}
+	for _, table := range tests {
+		t.Run(table.format, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", table.format)
+			t.Setenv("MQ_LOGGING_DUAL_OUTPUT", "true")
+			t.Setenv("MQ_LOGGING_LABELS", "host=mq1")
+			mf, err := configureLogger("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := captureConsole(t)
+			mf(raw, false)
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+		})
+	}
+}
+
+var logSourceCategoryTests = []struct {
+	source      string
+	htpassQmgr  string
+	expected    string
+	mirrorHTPwd bool
+}{
+	{"system", "", "qmgr", true},
+	{"web", "", "web", true},
+	{"htpass", "", "htpass", true},
+	{"htpass", "true", "qmgr", false},
+}
+
+func TestLogSourceCategory(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_SOURCE", "web")
+	for _, table := range logSourceCategoryTests {
+		t.Setenv("MQ_LOGGING_HTPASS_AS_QMGR", table.htpassQmgr)
+		category := logSourceCategory(table.source)
+		if category != table.expected {
+			t.Errorf("logSourceCategory(%v) with MQ_LOGGING_HTPASS_AS_QMGR=%v - expected %v, got %v", table.source, table.htpassQmgr, table.expected, category)
+		}
+		// Only the web source is enabled, so the htpass log is skipped if it's treated as part of the qmgr source
+		if shouldMirrorHTPasswdLogs() != table.mirrorHTPwd {
+			t.Errorf("shouldMirrorHTPasswdLogs() with MQ_LOGGING_HTPASS_AS_QMGR=%v - expected %v", table.htpassQmgr, table.mirrorHTPwd)
+		}
+	}
+}
+
+func TestTagLogSource(t *testing.T) {
+	var got []string
+	mf := tagLogSource(func(msg string, isQMLog bool) bool {
+		got = append(got, msg)
+		return true
+	}, "htpass")
+	mf("{\"message\":\"Authentication failed\"}", true)
+	mf("Not JSON", true)
+	expected := []string{"{\"ibm_logSource\":\"htpass\",\"message\":\"Authentication failed\"}", "Not JSON"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v; got %v", expected, got)
+	}
+}
+
+var requiredFieldsTests = []struct {
+	mode     string
+	obj      map[string]interface{}
+	filtered bool
+}{
+	{"any", map[string]interface{}{"message": "A", "ibm_arithInsert2": 1.0}, false},
+	{"any", map[string]interface{}{"message": "A", "ibm_commentInsert1": "x"}, false},
+	{"any", map[string]interface{}{"message": "A"}, true},
+	{"all", map[string]interface{}{"message": "A", "ibm_arithInsert2": 1.0, "ibm_commentInsert1": "x"}, false},
+	{"all", map[string]interface{}{"message": "A", "ibm_arithInsert2": 1.0}, true},
+	{"all", map[string]interface{}{"message": "A"}, true},
+}
+
+func TestRequiredFields(t *testing.T) {
+	for i, table := range requiredFieldsTests {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_CONSOLE_REQUIRE_FIELD", "ibm_arithInsert2, ibm_commentInsert1")
+			t.Setenv("MQ_LOGGING_CONSOLE_REQUIRE_FIELD_MODE", table.mode)
+			opts, err := getMirrorOptions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isFilteredRecord(table.obj, opts) != table.filtered {
+				t.Errorf("isFilteredRecord() with mode %v and %v - expected %v", table.mode, table.obj, table.filtered)
+			}
+		})
+	}
+}
+
+var includeIDTests = []struct {
+	include  string
+	exclude  string
+	msg      string
+	mirrored bool
+}{
+	{"", "", "{\"ibm_messageId\":\"AMQ9209E\",\"message\":\"A\"}", true},
+	{"AMQ7467I, amq7468i", "", "{\"ibm_messageId\":\"AMQ7468I\",\"message\":\"A\"}", true},
+	{"AMQ7467I,AMQ7468I", "", "{\"ibm_messageId\":\"AMQ9209E\",\"message\":\"A\"}", false},
+	{"AMQ7467I,AMQ7468I", "", "{\"message\":\"No ID\"}", false},
+	{"AMQ7467I,AMQ7468I", "AMQ7468I", "{\"ibm_messageId\":\"AMQ7468I\",\"message\":\"A\"}", false},
+	{"AMQ7467I,AMQ7468I", "AMQ7468I", "{\"ibm_messageId\":\"AMQ7467I\",\"message\":\"A\"}", true},
+	{"AMQ7467I", "", "Not JSON", true},
+}
+
+func TestIncludeIDs(t *testing.T) {
+	for i, table := range includeIDTests {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_CONSOLE_INCLUDE_ID", table.include)
+			t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID", table.exclude)
+			opts, err := getMirrorOptions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, format := range []string{"json", "basic"} {
+				captureConsole(t)
+				mirrored := newMirrorFunc(format, opts)(table.msg, false)
+				if mirrored != table.mirrored {
+					t.Errorf("Expected %v to be mirrored=%v in %v format, with include %q and exclude %q; got %v", table.msg, table.mirrored, format, table.include, table.exclude, mirrored)
+				}
+			}
+		})
+	}
+}
+
+func TestRequiredFieldsInvalidMode(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_REQUIRE_FIELD_MODE", "some")
+	_, err := getMirrorOptions()
+	if err == nil {
+		t.Error("Expected an error for an invalid mode")
+	}
+}
+
+func TestLogDiagnosticsDurations(t *testing.T) {
+	t.Setenv("DEBUG", "true")
+	t.Setenv("MQ_DIAG_PATHS", "")
+	buf := captureLog(t)
+	logDiagnostics()
+	out := buf.String()
+	for _, p := range defaultDiagPaths {
+		if !strings.Contains(out, fmt.Sprintf("Diagnostics command \"ls -l %v\" took ", p)) {
+			t.Errorf("Expected duration of listing %v to be logged; got %v", p, out)
+		}
+	}
+	for _, e := range []string{"Diagnostics command \"ffstsummary\" took ", "Diagnostics collection took "} {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected %q to be logged; got %v", e, out)
+		}
+	}
+}
+
+var logFormatOverrideTests = []struct {
+	value     string
+	global    string
+	overrides map[string]string
+	valid     bool
+}{
+	{"json", "json", map[string]string{}, true},
+	{"json;web:basic", "json", map[string]string{"web": "basic"}, true},
+	{" BASIC ; qmgr:json ; web:basic", "basic", map[string]string{"qmgr": "json", "web": "basic"}, true},
+	{"web:basic", "basic", map[string]string{"web": "basic"}, true},
+	{"json;web:xml", "json", nil, false},
+	{"json;other:basic", "json", nil, false},
+}
+
+func TestLogFormatOverrides(t *testing.T) {
+	for _, table := range logFormatOverrideTests {
+		t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", table.value)
+		if getLogFormat() != table.global {
+			t.Errorf("getLogFormat() with MQ_LOGGING_CONSOLE_FORMAT=%v - expected %v, got %v", table.value, table.global, getLogFormat())
+		}
+		overrides, err := getLogFormatOverrides()
+		if (err == nil) != table.valid {
+			t.Errorf("getLogFormatOverrides() with MQ_LOGGING_CONSOLE_FORMAT=%v - expected valid=%v, got error %v", table.value, table.valid, err)
+		}
+		if table.valid && !reflect.DeepEqual(overrides, table.overrides) {
+			t.Errorf("getLogFormatOverrides() with MQ_LOGGING_CONSOLE_FORMAT=%v - expected %v, got %v", table.value, table.overrides, overrides)
+		}
+	}
+}
+
+func TestLogFormatOverrideFormatting(t *testing.T) {
+	oldLog := log
+	defer func() {
+		log = oldLog
+		sourceMirrorFuncs = map[string]mirrorFunc{}
+	}()
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json;web:basic")
+	mf, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := captureConsole(t)
+	msg := "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"Hello\"}"
+	mirrorFuncForSource("qmgr", mf)(msg, false)
+	mirrorFuncForSource("web", mf)(msg, false)
+	expected := msg + "\n2024-01-01T10:00:00.000Z Hello\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q; got %q", expected, buf.String())
+	}
+}
+
+var recordPrefixTests = []struct {
+	name string
+	msg  string
+}{
+	{"BOM", "\ufeff{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"Hello\"}"},
+	{"Space", "  {\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"Hello\"}"},
+	{"Tab", "\t{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"Hello\"}"},
+}
+
+func TestRecordPrefixJSON(t *testing.T) {
+	oldLog := log
+	defer func() { log = oldLog }()
+	for _, format := range []string{"json", "basic"} {
+		t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", format)
+		mf, err := configureLogger("test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"Hello\"}\n"
+		if format == "basic" {
+			expected = "2024-01-01T10:00:00.000Z Hello\n"
+		}
+		for _, table := range recordPrefixTests {
+			t.Run(format+"/"+table.name, func(t *testing.T) {
+				buf := captureConsole(t)
+				mf(table.msg, false)
+				if buf.String() != expected {
+					t.Errorf("Expected %q; got %q", expected, buf.String())
+				}
+			})
+		}
+	}
+}
+
+func TestConfigureLoggerInvalidFormat(t *testing.T) {
+	oldLog, oldJSON := log, eventsJSON
+	defer func() { log, eventsJSON = oldLog, oldJSON }()
+	mf, err := configureLoggerFormat("test", "xml")
+	if err == nil {
+		t.Error("Expected an error for an invalid format")
+	}
+	if mf == nil {
+		t.Fatal("Expected a fallback mirrorFunc for an invalid format")
+	}
+	if eventsJSON {
+		t.Error("Expected events to be in basic format")
+	}
+	buf := captureConsole(t)
+	mf("{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"message\":\"Hello\"}", false)
+	expected := "2024-01-01T10:00:00.000Z Hello\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q; got %q", expected, buf.String())
+	}
+}
+
+var basicColumnsTests = []struct {
+	name     string
+	obj      map[string]interface{}
+	expected string
+}{
+	{
+		"Complete",
+		map[string]interface{}{"ibm_datetime": "2024-01-01T10:00:00.000Z", "loglevel": "INFO", "ibm_messageId": "AMQ5051I", "message": "AMQ5051I: The queue manager task 'LOGGER-IO' has started.", "ibm_commentInsert1": "LOGGER-IO"},
+		"2024-01-01T10:00:00.000Z INFO     AMQ5051I      The queue manager task 'LOGGER-IO' has started. [CommentInsert1(LOGGER-IO)]\n",
+	},
+	{
+		"Error",
+		map[string]interface{}{"ibm_datetime": "2024-01-01T10:00:01.000Z", "loglevel": "ERROR", "ibm_messageId": "AMQ9209E", "message": "AMQ9209E: Connection closed."},
+		"2024-01-01T10:00:01.000Z ERROR    AMQ9209E      Connection closed.\n",
+	},
+	{
+		"Missing",
+		map[string]interface{}{"message": "Hello"},
+		"-                        -        -             Hello\n",
+	},
+}
+
+func TestFormatBasicColumns(t *testing.T) {
+	t.Setenv("MQ_LOGGING_BASIC_COLUMNS", "true")
+	for _, table := range basicColumnsTests {
+		t.Run(table.name, func(t *testing.T) {
+			line := formatBasic(table.obj)
+			if line != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, line)
+			}
+		})
+	}
+	// The columns line up with those of a Liberty trace
+	trace := formatBasic(map[string]interface{}{"type": "liberty_trace", "ibm_datetime": "2024-01-01T10:00:00.000Z", "loglevel": "EVENT", "ibm_threadId": "00000027", "module": "com.ibm.ws.Module", "message": "Hello"})
+	mq := formatBasic(basicColumnsTests[0].obj)
+	for _, offset := range []int{25, 34, 48} {
+		if trace[offset-1] != ' ' || mq[offset-1] != ' ' || trace[offset] == ' ' || mq[offset] == ' ' {
+			t.Errorf("Expected columns to start at offset %v; got %q and %q", offset, trace, mq)
+		}
+	}
+}
+
+func TestFormatBasicOmitTime(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_OMIT_TIME", "true")
+	var tests = []struct {
+		name     string
+		obj      map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "MQ",
+			obj:      map[string]interface{}{"ibm_datetime": "2024-01-01T10:00:00.000Z", "message": "AMQ5051I: Started"},
+			expected: "AMQ5051I: Started\n",
+		},
+		{
+			name:     "Inserts",
+			obj:      map[string]interface{}{"ibm_datetime": "2024-01-01T10:00:00.000Z", "message": "AMQ9209E: Closed", "ibm_commentInsert1": "APP1"},
+			expected: "AMQ9209E: Closed [CommentInsert1(APP1)]\n",
+		},
+		{
+			name:     "Liberty",
+			obj:      map[string]interface{}{"type": "liberty_trace", "ibm_datetime": "2024-01-01T10:00:00.000+0000", "loglevel": "EVENT", "ibm_threadId": "00000027", "module": "com.ibm.ws.Module", "message": "Hello"},
+			expected: "00000027 Module        1 Hello\n",
+		},
+	}
+	for _, table := range tests {
+		t.Run(table.name, func(t *testing.T) {
+			line := formatBasic(table.obj)
+			if line != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, line)
+			}
+		})
+	}
+	t.Setenv("MQ_LOGGING_BASIC_COLUMNS", "true")
+	line := formatBasic(map[string]interface{}{"ibm_datetime": "2024-01-01T10:00:00.000Z", "loglevel": "INFO", "ibm_messageId": "AMQ5051I", "message": "AMQ5051I: Started"})
+	if strings.Contains(line, "2024") {
+		t.Errorf("Expected no time in columns; got %q", line)
+	}
+}
+
+func TestFilterQMLogMessageHostnameOverride(t *testing.T) {
+	t.Setenv("MQ_MULTI_INSTANCE", "true")
+	t.Setenv("MQ_MULTI_INSTANCE_HOSTNAME", "qm-pod-0")
+	if filterQMLogMessage(map[string]interface{}{"host": "qm-pod-0"}) {
+		t.Error("Expected message from this host not to be filtered")
+	}
+	if !filterQMLogMessage(map[string]interface{}{"host": "qm-pod-1"}) {
+		t.Error("Expected message from another host to be filtered")
+	}
+}
+
+func TestFilterQMLogMessageHostnameFailure(t *testing.T) {
+	oldHostname := osHostname
+	defer func() {
+		osHostname = oldHostname
+		hostnameWarningOnce = sync.Once{}
+	}()
+	osHostname = func() (string, error) { return "", errors.New("no host name") }
+	hostnameWarningOnce = sync.Once{}
+	t.Setenv("MQ_MULTI_INSTANCE", "true")
+	buf := captureLog(t)
+	if filterQMLogMessage(map[string]interface{}{"host": "qm-pod-1"}) || filterQMLogMessage(map[string]interface{}{"host": "qm-pod-2"}) {
+		t.Error("Expected messages not to be filtered when the host name is unknown")
+	}
+	if strings.Count(buf.String(), "unable to get the host name") != 1 {
+		t.Errorf("Expected a single warning; got %v", buf.String())
+	}
+}
+
+func TestMirroredRecordTerminator(t *testing.T) {
+	tmpl := template.Must(template.New("test").Parse("{{.message}}\n"))
+	jsonMsg := "{\"ibm_datetime\":\"2024-02-29T10:15:00.123Z\",\"loglevel\":\"INFO\",\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: Started\"}"
+	multiLineMsg := "{\"ibm_datetime\":\"2024-02-29T10:15:00.123Z\",\"loglevel\":\"INFO\",\"message\":\"Ends with a new-line\\n\",\"ibm_commentInsert1\":\"x\"}"
+	var tests = []struct {
+		name    string
+		format  string
+		opts    mirrorOptions
+		msg     string
+		records int
+	}{
+		{"json record", "json", mirrorOptions{}, jsonMsg, 1},
+		{"json text", "json", mirrorOptions{}, "Plain text", 1},
+		{"json text with carriage return", "json", mirrorOptions{}, "Plain text\r", 1},
+		{"json text with labels", "json", mirrorOptions{labels: []logLabel{{"env", "test"}}}, "Plain text\r", 1},
+		{"json record with template", "json", mirrorOptions{template: tmpl}, jsonMsg, 1},
+		{"json text with template", "json", mirrorOptions{template: tmpl}, "Plain text", 1},
+		{"json dual output", "json", mirrorOptions{dualOutput: true}, jsonMsg, 2},
+		{"ecs record", "ecs", mirrorOptions{}, jsonMsg, 1},
+		{"gelf record", "gelf", mirrorOptions{}, jsonMsg, 1},
+		{"syslog record", "syslog", mirrorOptions{}, jsonMsg, 1},
+		{"basic record", "basic", mirrorOptions{}, jsonMsg, 1},
+		{"basic record with inserts", "basic", mirrorOptions{}, multiLineMsg, 2},
+		{"basic text", "basic", mirrorOptions{}, "Plain text", 1},
+		{"basic text with carriage return", "basic", mirrorOptions{}, "Plain text\r\n", 1},
+		{"basic dual output", "basic", mirrorOptions{dualOutput: true}, jsonMsg, 2},
+	}
+	check := func(t *testing.T, out string, records int) {
+		if !strings.HasSuffix(out, "\n") || strings.HasSuffix(out, "\n\n") || strings.HasSuffix(out, "\r\n") {
+			t.Errorf("Expected a single new-line at the end; got %q", out)
+		}
+		if n := strings.Count(out, "\n"); n != records {
+			t.Errorf("Expected %v new-lines; got %v in %q", records, n, out)
+		}
+	}
+	for _, table := range tests {
+		t.Run(table.name, func(t *testing.T) {
+			buf := captureConsole(t)
+			newMirrorFunc(table.format, table.opts)(table.msg, true)
+			check(t, buf.String(), table.records)
+		})
+	}
+	for _, j := range []bool{true, false} {
+		t.Run(fmt.Sprintf("event json=%v", j), func(t *testing.T) {
+			oldJSON := eventsJSON
+			eventsJSON = j
+			t.Cleanup(func() { eventsJSON = oldJSON })
+			buf := captureConsole(t)
+			emitEvent("INFO", "test", "Test event", nil)
+			check(t, buf.String(), 1)
+		})
+	}
+	t.Run("declared sink", func(t *testing.T) {
+		t.Cleanup(func() { declaredSinks = nil })
+		t.Setenv("MQ_LOGGING_SINKS", "type=console,format=basic")
+		err := configureDeclaredSinks("json", mirrorOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := captureConsole(t)
+		newMirrorFunc("json", mirrorOptions{})("Plain text\r", true)
+		newMirrorFunc("json", mirrorOptions{})(multiLineMsg, true)
+		check(t, buf.String(), 3)
+	})
+}
@@ -0,0 +1,142 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// sourceConflictPolicy decides whether a log source is mirrored, when it isn't selected in
+// MQ_LOGGING_CONSOLE_SOURCE but other settings refer to it
+type sourceConflictPolicy string
+
+const (
+	// sourceConflictSelect only mirrors the sources selected in MQ_LOGGING_CONSOLE_SOURCE, and ignores any
+	// other settings for the other sources
+	sourceConflictSelect sourceConflictPolicy = "select"
+	// sourceConflictInclude also mirrors any source which other settings refer to
+	sourceConflictInclude sourceConflictPolicy = "include"
+)
+
+// getSourceConflictPolicy returns the policy in MQ_LOGGING_SOURCE_CONFLICT, which defaults to "select"
+func getSourceConflictPolicy() (sourceConflictPolicy, error) {
+	switch p := sourceConflictPolicy(strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_SOURCE_CONFLICT")))); p {
+	case "", sourceConflictSelect:
+		return sourceConflictSelect, nil
+	case sourceConflictInclude:
+		return p, nil
+	default:
+		return sourceConflictSelect, fmt.Errorf("invalid value for MQ_LOGGING_SOURCE_CONFLICT: %v", p)
+	}
+}
+
+// getLogSourceReferences returns the settings, other than MQ_LOGGING_CONSOLE_SOURCE, which refer to
+// each of the sources which can be selected there ("qmgr" and "web")
+func getLogSourceReferences() map[string][]string {
+	refs := make(map[string][]string)
+	add := func(source string, setting string) {
+		if source != "qmgr" && source != "web" {
+			return
+		}
+		for _, s := range refs[source] {
+			if s == setting {
+				return
+			}
+		}
+		refs[source] = append(refs[source], setting)
+	}
+	_, overrides := splitLogFormat(os.Getenv("MQ_LOGGING_CONSOLE_FORMAT"))
+	for _, token := range overrides {
+		add(strings.TrimSpace(strings.SplitN(token, ":", 2)[0]), "MQ_LOGGING_CONSOLE_FORMAT")
+	}
+	for _, source := range []string{"qmgr", "web"} {
+		name := "MQ_LOGGING_" + strings.ToUpper(source) + "_CANDIDATE_PATHS"
+		if strings.TrimSpace(os.Getenv(name)) != "" {
+			add(source, name)
+		}
+	}
+	if strings.TrimSpace(os.Getenv("MQ_LOGGING_QMGR_ERROR_LOG_GLOB")) != "" {
+		add("qmgr", "MQ_LOGGING_QMGR_ERROR_LOG_GLOB")
+	}
+	for _, declaration := range strings.Split(os.Getenv("MQ_LOGGING_SINKS"), ";") {
+		// Invalid declarations are reported when the sinks are configured
+		if options, err := parseSinkOptions(declaration); err == nil {
+			add(strings.ToLower(options["source"]), "MQ_LOGGING_SINKS")
+		}
+	}
+	if web := os.Getenv("MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG"); web == "true" || web == "1" {
+		add("web", "MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG")
+	}
+	return refs
+}
+
+// describeLogSourceConflicts returns a description of how each conflict between the log source
+// settings has been resolved.  MQ_LOGGING_CONSOLE_SOURCE takes precedence over the other settings,
+// unless MQ_LOGGING_SOURCE_CONFLICT is "include".  If a source's format is set more than once in
+// MQ_LOGGING_CONSOLE_FORMAT, the last one is used.
+func describeLogSourceConflicts() ([]string, error) {
+	policy, err := getSourceConflictPolicy()
+	if err != nil {
+		return nil, err
+	}
+	conflicts := make([]string, 0)
+	refs := getLogSourceReferences()
+	for _, source := range []string{"qmgr", "web"} {
+		if len(refs[source]) == 0 || isLogSourceSelected(source) {
+			continue
+		}
+		settings := strings.Join(refs[source], ", ")
+		if policy == sourceConflictInclude {
+			conflicts = append(conflicts, fmt.Sprintf("%v is not selected in MQ_LOGGING_CONSOLE_SOURCE, but is mirrored because it is used in %v", source, settings))
+		} else {
+			conflicts = append(conflicts, fmt.Sprintf("%v is not selected in MQ_LOGGING_CONSOLE_SOURCE, so is not mirrored, and its settings in %v are ignored", source, settings))
+		}
+	}
+	_, overrides := splitLogFormat(os.Getenv("MQ_LOGGING_CONSOLE_FORMAT"))
+	formats := make(map[string][]string)
+	for _, token := range overrides {
+		parts := strings.SplitN(token, ":", 2)
+		source := strings.TrimSpace(parts[0])
+		formats[source] = append(formats[source], strings.TrimSpace(parts[1]))
+	}
+	sources := make([]string, 0, len(formats))
+	for source := range formats {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		if f := formats[source]; len(f) > 1 {
+			conflicts = append(conflicts, fmt.Sprintf("MQ_LOGGING_CONSOLE_FORMAT sets the format of %v more than once, so the last one (%v) is used", source, f[len(f)-1]))
+		}
+	}
+	return conflicts, nil
+}
+
+// reportLogSourceConflicts logs a warning for each conflict between the log source settings, describing
+// how it has been resolved
+func reportLogSourceConflicts() error {
+	conflicts, err := describeLogSourceConflicts()
+	if err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		log.Printf("Warning: %v", c)
+	}
+	return nil
+}
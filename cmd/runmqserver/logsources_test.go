@@ -0,0 +1,128 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var logSourceConflictTests = []struct {
+	name      string
+	env       map[string]string
+	qmgr      bool
+	web       bool
+	conflicts []string
+}{
+	{
+		name: "no conflict",
+		env:  map[string]string{"MQ_LOGGING_CONSOLE_SOURCE": "qmgr,web", "MQ_LOGGING_CONSOLE_FORMAT": "json;web:basic"},
+		qmgr: true,
+		web:  true,
+	},
+	{
+		name: "format override for an unselected source",
+		env:  map[string]string{"MQ_LOGGING_CONSOLE_SOURCE": "qmgr", "MQ_LOGGING_CONSOLE_FORMAT": "json;web:basic"},
+		qmgr: true,
+		conflicts: []string{
+			"web is not selected in MQ_LOGGING_CONSOLE_SOURCE, so is not mirrored, and its settings in MQ_LOGGING_CONSOLE_FORMAT are ignored",
+		},
+	},
+	{
+		name: "several settings for an unselected source",
+		env: map[string]string{
+			"MQ_LOGGING_CONSOLE_SOURCE":         "web",
+			"MQ_LOGGING_QMGR_CANDIDATE_PATHS":   "/var/mqm/errors/AMQERR01.json",
+			"MQ_LOGGING_SINKS":                  "type=console;type=file,source=qmgr,path=/tmp/qmgr.json",
+			"MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG": "true",
+			"MQ_LOGGING_SUPPRESS_DEPRECATION":   "true",
+		},
+		web: true,
+		conflicts: []string{
+			"qmgr is not selected in MQ_LOGGING_CONSOLE_SOURCE, so is not mirrored, and its settings in MQ_LOGGING_QMGR_CANDIDATE_PATHS, MQ_LOGGING_SINKS are ignored",
+		},
+	},
+	{
+		name: "include policy",
+		env: map[string]string{
+			"MQ_LOGGING_CONSOLE_SOURCE":         "qmgr",
+			"MQ_LOGGING_SOURCE_CONFLICT":        "include",
+			"MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG": "true",
+			"MQ_LOGGING_SUPPRESS_DEPRECATION":   "true",
+		},
+		qmgr: true,
+		web:  true,
+		conflicts: []string{
+			"web is not selected in MQ_LOGGING_CONSOLE_SOURCE, but is mirrored because it is used in MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG",
+		},
+	},
+	{
+		name: "format set more than once",
+		env:  map[string]string{"MQ_LOGGING_CONSOLE_FORMAT": "json;web:basic;qmgr:basic;web:ecs"},
+		qmgr: true,
+		web:  true,
+		conflicts: []string{
+			"MQ_LOGGING_CONSOLE_FORMAT sets the format of web more than once, so the last one (ecs) is used",
+		},
+	},
+}
+
+func TestLogSourceConflicts(t *testing.T) {
+	for _, table := range logSourceConflictTests {
+		t.Run(table.name, func(t *testing.T) {
+			for _, name := range []string{"MQ_LOGGING_CONSOLE_SOURCE", "MQ_LOGGING_CONSOLE_FORMAT", "MQ_LOGGING_SOURCE_CONFLICT", "MQ_LOGGING_QMGR_CANDIDATE_PATHS", "MQ_LOGGING_WEB_CANDIDATE_PATHS", "MQ_LOGGING_SINKS", "MQ_ENABLE_EMBEDDED_WEB_SERVER_LOG"} {
+				t.Setenv(name, "")
+			}
+			for k, v := range table.env {
+				t.Setenv(k, v)
+			}
+			if qmgr := checkLogSourceForMirroring("qmgr"); qmgr != table.qmgr {
+				t.Errorf("Expected qmgr to be mirrored: %v; got %v", table.qmgr, qmgr)
+			}
+			if web := checkLogSourceForMirroring("web"); web != table.web {
+				t.Errorf("Expected web to be mirrored: %v; got %v", table.web, web)
+			}
+			conflicts, err := describeLogSourceConflicts()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(conflicts) != 0 || len(table.conflicts) != 0 {
+				if !reflect.DeepEqual(conflicts, table.conflicts) {
+					t.Errorf("Expected %q; got %q", table.conflicts, conflicts)
+				}
+			}
+		})
+	}
+}
+
+func TestReportLogSourceConflicts(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_SOURCE", "qmgr")
+	t.Setenv("MQ_LOGGING_WEB_CANDIDATE_PATHS", "/var/mqm/web/messages.log")
+	buf := captureLog(t)
+	err := reportLogSourceConflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Warning: web is not selected in MQ_LOGGING_CONSOLE_SOURCE, so is not mirrored, and its settings in MQ_LOGGING_WEB_CANDIDATE_PATHS are ignored"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected %q; got %q", expected, buf.String())
+	}
+	t.Setenv("MQ_LOGGING_SOURCE_CONFLICT", "merge")
+	if err := reportLogSourceConflicts(); err == nil {
+		t.Error("Expected an error for an invalid policy")
+	}
+}
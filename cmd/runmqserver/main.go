@@ -42,12 +42,16 @@ func doMain() error {
 	var devFlag = flag.Bool("dev", false, "used when running this program from runmqdevserver to control how TLS is configured")
 	flag.Parse()
 
+	// Any error returned from here results in an exit code of 1
+	setTerminationExitCode(1)
+
 	name, nameErr := name.GetQueueManagerName()
 	mf, err := configureLogger(name)
 	if err != nil {
 		logTermination(err)
 		return err
 	}
+	log.Debug(describeLoggingPipeline())
 
 	// Check whether they only want debug info
 	if *infoFlag {
@@ -251,7 +255,17 @@ func doMain() error {
 	if !isLogConsoleSourceValid() {
 		log.Println("One or more invalid value is provided for MQ_LOGGING_CONSOLE_SOURCE. Allowed values are 'qmgr' & 'web' in csv format")
 	}
+	err = reportLogSourceConflicts()
+	if err != nil {
+		logTermination(err)
+		return err
+	}
 
+	// Flush any buffered or additional log destinations, after log mirroring is complete
+	defer func() {
+		emitErrorSummary()
+		drainLogging()
+	}()
 	var wg sync.WaitGroup
 	defer func() {
 		log.Debug("Waiting for log mirroring to complete")
@@ -262,6 +276,14 @@ func doMain() error {
 		log.Debug("Cancel log mirroring")
 		cancelMirror()
 	}()
+	if getStatusSignalEnabled() {
+		handleStatusSignal(ctx, &wg)
+	}
+	err = startHeartbeat(ctx, &wg)
+	if err != nil {
+		logTermination(err)
+		return err
+	}
 
 	//For mirroring web server logs if source variable is set
 	if checkLogSourceForMirroring("web") {
@@ -330,7 +352,15 @@ func doMain() error {
 		}
 	}
 
-	if *devFlag && htpasswd.IsEnabled() {
+	if getFDCEventEnabled() {
+		err = watchFDCs(ctx, &wg, fdcDir)
+		if err != nil {
+			logTermination(err)
+			return err
+		}
+	}
+
+	if *devFlag && htpasswd.IsEnabled() && shouldMirrorHTPasswdLogs() {
 		_, err = mirrorHTPasswdLogs(ctx, &wg, name, newQM, mf)
 		if err != nil {
 			logTermination(err)
@@ -368,6 +398,7 @@ func doMain() error {
 		logTermination(err)
 		return err
 	}
+	markQueueManagerStarted(timeNow())
 
 	if enableTraceStrmqm == "true" || enableTraceStrmqm == "1" {
 		err = endMQTrace()
@@ -402,6 +433,10 @@ func doMain() error {
 	}
 	// Wait for terminate signal
 	<-signalControl
+	if reason := getFatalMessageReason(); reason != "" {
+		// The termination has already been logged, when the fatal message was seen
+		return errors.New(reason)
+	}
 	return nil
 }
 
@@ -0,0 +1,156 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxMergeWindow is the longest time a log message can be held back to merge sources, so that
+// messages are never delayed for long
+const maxMergeWindow = 5 * time.Second
+
+// merge holds back messages from the queue manager and web server logs, to emit them in timestamp
+// order, or is nil if the logs aren't merged
+var merge *mergeBuffer
+
+// mergedLine is a log message held back, to be emitted in timestamp order
+type mergedLine struct {
+	msg      string
+	datetime time.Time
+	mf       mirrorFunc
+	isQMLog  bool
+	// arrived is when the message was read
+	arrived time.Time
+}
+
+// mergeBuffer holds back log messages from several sources for a short window, and then emits them
+// in timestamp order, so that messages from different sources are interleaved
+type mergeBuffer struct {
+	mutex  sync.Mutex
+	window time.Duration
+	lines  []mergedLine
+	stop   chan struct{}
+}
+
+func newMergeBuffer(window time.Duration) *mergeBuffer {
+	return &mergeBuffer{window: window}
+}
+
+// wrap returns a mirrorFunc which holds back each message, and later passes it on to mf.  Messages
+// without a timestamp are kept with the message before them from the same source.
+func (m *mergeBuffer) wrap(mf mirrorFunc) mirrorFunc {
+	var previous time.Time
+	return func(msg string, isQMLog bool) bool {
+		now := timeNow()
+		datetime := previous
+		if obj, err := processLogMessage(trimRecordPrefix(msg)); err == nil {
+			if dt := newMQLogRecord(obj).Datetime(); !dt.IsZero() {
+				datetime = dt
+			}
+		}
+		if datetime.IsZero() {
+			datetime = now
+		}
+		previous = datetime
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		m.lines = append(m.lines, mergedLine{msg: msg, datetime: datetime, mf: mf, isQMLog: isQMLog, arrived: now})
+		// The message is assumed to be mirrored, as it isn't known yet whether it will be filtered
+		return true
+	}
+}
+
+// release emits the messages which have been held for the whole window, in timestamp order.  If all
+// is true, every message is emitted.
+func (m *mergeBuffer) release(now time.Time, all bool) {
+	cutoff := now.Add(-m.window)
+	m.mutex.Lock()
+	ready := make([]mergedLine, 0)
+	held := m.lines[:0]
+	for _, l := range m.lines {
+		if all || !l.arrived.After(cutoff) {
+			ready = append(ready, l)
+		} else {
+			held = append(held, l)
+		}
+	}
+	m.lines = held
+	m.mutex.Unlock()
+	sort.SliceStable(ready, func(i, j int) bool {
+		return ready[i].datetime.Before(ready[j].datetime)
+	})
+	for _, l := range ready {
+		l.mf(l.msg, l.isQMLog)
+	}
+}
+
+// run releases messages regularly, until stopped
+func (m *mergeBuffer) run(stop chan struct{}) {
+	interval := m.window / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.release(timeNow(), false)
+		}
+	}
+}
+
+// appliesTo returns true if messages from a source are merged
+func (m *mergeBuffer) appliesTo(source string) bool {
+	category := logSourceCategory(source)
+	return category == "qmgr" || category == "web"
+}
+
+// flushMerge stops holding back messages, and emits any which are held
+func flushMerge() {
+	if merge == nil {
+		return
+	}
+	if merge.stop != nil {
+		close(merge.stop)
+		merge.stop = nil
+	}
+	merge.release(timeNow(), true)
+}
+
+// configureMerge starts merging the queue manager and web server logs, if MQ_LOGGING_MERGE_WINDOW is set
+// to the time to hold back each message
+func configureMerge() error {
+	flushMerge()
+	merge = nil
+	window, err := getDurationEnv("MQ_LOGGING_MERGE_WINDOW", 0)
+	if err != nil || window == 0 {
+		return err
+	}
+	if window > maxMergeWindow {
+		return fmt.Errorf("invalid value for MQ_LOGGING_MERGE_WINDOW: %v is longer than %v", window, maxMergeWindow)
+	}
+	merge = newMergeBuffer(window)
+	merge.stop = make(chan struct{})
+	go merge.run(merge.stop)
+	return nil
+}
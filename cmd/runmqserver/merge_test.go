@@ -0,0 +1,74 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeBuffer(t *testing.T) {
+	oldNow := timeNow
+	defer func() { timeNow = oldNow }()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	emitted := make([]string, 0)
+	mf := func(msg string, isQMLog bool) bool {
+		emitted = append(emitted, msg)
+		return true
+	}
+	m := newMergeBuffer(time.Second)
+	qmgr := m.wrap(mf)
+	web := m.wrap(mf)
+	// Each source is read in a burst, but their timestamps interleave
+	qmgr("{\"ibm_datetime\":\"2024-01-01T11:59:59.100Z\",\"message\":\"Q1\"}", true)
+	qmgr("{\"ibm_datetime\":\"2024-01-01T11:59:59.300Z\",\"message\":\"Q2\"}", true)
+	qmgr("Continued", true)
+	web("{\"ibm_datetime\":\"2024-01-01T11:59:59.200+0000\",\"message\":\"W1\"}", false)
+	web("{\"ibm_datetime\":\"2024-01-01T11:59:59.400+0000\",\"message\":\"W2\"}", false)
+	// Nothing is emitted until the messages have been held for the whole window
+	m.release(now.Add(500*time.Millisecond), false)
+	if len(emitted) != 0 {
+		t.Fatalf("Expected messages to be held back; got %v", emitted)
+	}
+	now = now.Add(500 * time.Millisecond)
+	web("{\"ibm_datetime\":\"2024-01-01T11:59:59.000+0000\",\"message\":\"W3\"}", false)
+	m.release(now.Add(500*time.Millisecond), false)
+	expected := []string{
+		"{\"ibm_datetime\":\"2024-01-01T11:59:59.100Z\",\"message\":\"Q1\"}",
+		"{\"ibm_datetime\":\"2024-01-01T11:59:59.200+0000\",\"message\":\"W1\"}",
+		"{\"ibm_datetime\":\"2024-01-01T11:59:59.300Z\",\"message\":\"Q2\"}",
+		"Continued",
+		"{\"ibm_datetime\":\"2024-01-01T11:59:59.400+0000\",\"message\":\"W2\"}",
+	}
+	if !reflect.DeepEqual(emitted, expected) {
+		t.Errorf("Expected %v; got %v", expected, emitted)
+	}
+	// The message read later is held for its own window, so is emitted after the others
+	m.release(now.Add(time.Second), false)
+	if len(emitted) != len(expected)+1 || emitted[len(expected)] != "{\"ibm_datetime\":\"2024-01-01T11:59:59.000+0000\",\"message\":\"W3\"}" {
+		t.Errorf("Expected the late message to be emitted last; got %v", emitted)
+	}
+}
+
+func TestConfigureMergeWindowTooLong(t *testing.T) {
+	t.Setenv("MQ_LOGGING_MERGE_WINDOW", "1m")
+	err := configureMerge()
+	if err == nil {
+		t.Error("Expected an error for a window longer than the maximum")
+	}
+}
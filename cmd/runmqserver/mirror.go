@@ -18,7 +18,9 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"sync"
 	"time"
@@ -49,10 +51,43 @@ func waitForFile(ctx context.Context, path string) (os.FileInfo, error) {
 	}
 }
 
+// reopenLogFile opens a log file again after it has been rotated, and can be replaced for testing
+var reopenLogFile = openLogFile
+
+// openLogFile waits for a log file to exist, and then opens it.  During rotation, the file may be removed
+// again before it can be opened, in which case it waits for it to be created again.
+func openLogFile(ctx context.Context, path string) (*os.File, os.FileInfo, error) {
+	for {
+		_, err := waitForFile(ctx, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		// #nosec G304 - no harm, we open readonly and check error.
+		f, err := os.OpenFile(path, os.O_RDONLY, 0)
+		if os.IsNotExist(err) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			// #nosec G104 - the file was only opened for reading
+			f.Close()
+			return nil, nil, err
+		}
+		return f, fi, nil
+	}
+}
+
 type mirrorFunc func(msg string, isQMLog bool) bool
 
-// mirrorAvailableMessages prints lines from the file, until no more are available
-func mirrorAvailableMessages(f *os.File, mf mirrorFunc, isQMLog bool) {
+// mirrorAvailableMessages prints lines from the file, until no more are available.
+// If fileMtime is true, JSON log messages include the modification time of the file.
+func mirrorAvailableMessages(f *os.File, mf mirrorFunc, isQMLog bool, fileMtime bool) {
+	if fileMtime {
+		mf = withFileMtime(f, mf)
+	}
 	scanner := bufio.NewScanner(f)
 	count := 0
 	for scanner.Scan() {
@@ -71,14 +106,84 @@ func mirrorAvailableMessages(f *os.File, mf mirrorFunc, isQMLog bool) {
 	}
 }
 
+// getFileMtimeEnabled returns true if JSON log messages should include the modification time of the file they were read from
+func getFileMtimeEnabled() bool {
+	enabled := os.Getenv("MQ_LOGGING_FILE_MTIME")
+	return enabled == "true" || enabled == "1"
+}
+
+// withFileMtime wraps a mirrorFunc, so that JSON log messages include an "ibm_fileMtime" field, holding the
+// modification time of the file when the messages were read.  This is independent of the time in the
+// message itself, which can be wrong after the clock has been changed.
+func withFileMtime(f *os.File, mf mirrorFunc) mirrorFunc {
+	fi, err := f.Stat()
+	if err != nil {
+		log.Debugf("Unable to read modification time of %v: %v", f.Name(), err)
+		return mf
+	}
+	mtime := fi.ModTime().UTC().Format(eventTimestampFormat)
+	return func(msg string, isQMLog bool) bool {
+		obj, err := processLogMessage(trimRecordPrefix(msg))
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		obj["ibm_fileMtime"] = mtime
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		return mf(string(b), isQMLog)
+	}
+}
+
+// getLiveEventEnabled returns true if an event should be emitted when a log which is mirrored from the
+// start has caught up, to separate the replayed messages from new ones
+func getLiveEventEnabled() bool {
+	enabled := os.Getenv("MQ_LOGGING_LIVE_EVENT")
+	return enabled == "true" || enabled == "1"
+}
+
 // mirrorLog tails the specified file, and logs each line to stdout.
 // This is useful for usability, as the container console log can show
 // messages from the MQ error logs.
-func mirrorLog(ctx context.Context, wg *sync.WaitGroup, path string, fromStart bool, mf mirrorFunc, isQMLog bool) (chan error, error) {
+// The source identifies which kind of log is being mirrored (for example "qmgr" or "web").
+func mirrorLog(ctx context.Context, wg *sync.WaitGroup, source string, path string, fromStart bool, mf mirrorFunc, isQMLog bool) (chan error, error) {
 	errorChannel := make(chan error, 1)
+	// Lines read in the first pass of a file which is mirrored from the start are replayed lines
+	initialPass := fromStart
+	if merge != nil && merge.appliesTo(source) {
+		mf = merge.wrap(mf)
+	}
+	if startupSkip != nil {
+		mf = startupSkip.wrap(source, mf, func() bool { return initialPass })
+	}
+	if getEnglishMessages() {
+		mf = translateToEnglish(mf)
+	}
+	if getSourceCategoryEnabled() {
+		mf = tagSourceCategory(mf, source)
+	}
+	if getMonotonicFilter() {
+		mf = newMonotonicFilter(source, mf)
+	}
+	// Messages in basic format don't include any extra fields, so the file's modification time is only
+	// added for the other formats, which are all based on JSON
+	fileMtime := getFileMtimeEnabled() && logFormatForSource(source) != "basic"
+	nestedJSON, err := getNestedJSONMode()
+	if err != nil {
+		return nil, err
+	}
+	if nestedJSON != nestedJSONNone {
+		mf = expandNestedJSON(mf, nestedJSON)
+	}
+	if repeatEscalation != nil {
+		mf = repeatEscalation.wrap(mf)
+	}
+	mf = countLines(mf, source)
+	state := registerMirror(source, path)
+	mf = state.track(mf)
 	var offset int64 = -1
 	var f *os.File
-	var err error
 	var fi os.FileInfo
 	// Need to check if the file exists before returning, otherwise we have a
 	// race to see if the new file get created before we can test for it
@@ -108,6 +213,7 @@ func mirrorLog(ctx context.Context, wg *sync.WaitGroup, path string, fromStart b
 	go func() {
 		// Notify the wait group when this goroutine ends
 		defer func() {
+			state.stop()
 			log.Debugf("Finished monitoring %v", path)
 			wg.Done()
 		}()
@@ -116,6 +222,7 @@ func mirrorLog(ctx context.Context, wg *sync.WaitGroup, path string, fromStart b
 			fi, err = waitForFile(ctx, path)
 			if err != nil {
 				log.Error(err)
+				state.setState(mirrorStateFailed)
 				errorChannel <- err
 				return
 			}
@@ -127,6 +234,7 @@ func mirrorLog(ctx context.Context, wg *sync.WaitGroup, path string, fromStart b
 			f, err = os.OpenFile(path, os.O_RDONLY, 0)
 			if err != nil {
 				log.Error(err)
+				state.setState(mirrorStateFailed)
 				errorChannel <- err
 				return
 			}
@@ -135,53 +243,109 @@ func mirrorLog(ctx context.Context, wg *sync.WaitGroup, path string, fromStart b
 		fi, err = f.Stat()
 		if err != nil {
 			log.Error(err)
+			state.setState(mirrorStateFailed)
 			errorChannel <- err
 			return
 		}
+		state.setState(mirrorStateRunning)
+		// If we've persisted the position reached in this file before a restart, resume from there
+		resumed := false
+		if mirrorOffsets != nil {
+			if saved, ok := mirrorOffsets.lookup(path, fi); ok {
+				log.Debugf("Resuming from persisted offset %v in file %v", saved, path)
+				_, err = f.Seek(saved, 0)
+				if err != nil {
+					log.Errorf("Unable to resume from offset %v: %v", saved, err)
+				} else {
+					resumed = true
+				}
+			}
+		}
 		// The file now exists.  If it didn't exist before we started, offset=0
 		// Always start at the beginning if we've been told to go from the start
-		if offset != 0 && !fromStart {
+		if !resumed && offset != 0 && !fromStart {
 			log.Debugf("Seeking offset %v in file %v", offset, path)
 			_, err = f.Seek(offset, 0)
 			if err != nil {
 				log.Errorf("Unable to return to offset %v: %v", offset, err)
 			}
 		}
+		lag, err := newLagMonitor(path)
+		if err != nil {
+			log.Errorf("Unable to monitor mirroring lag: %v", err)
+		}
 		closing := false
 		for {
+			// Check how much has been written since the last time round the loop
+			lag.check(f)
 			// If there's already data there, mirror it now.
-			mirrorAvailableMessages(f, mf, isQMLog)
+			mirrorAvailableMessages(f, mf, isQMLog, fileMtime)
+			if initialPass && getLiveEventEnabled() {
+				// Everything which was already in the file has been mirrored, so mark where new messages start
+				emitEvent("INFO", "live_tailing_started", fmt.Sprintf("Live tailing started for %v", source), map[string]interface{}{"ibm_source": source, "ibm_path": path})
+			}
+			initialPass = false
 			// Wait for the new log file (after rotation)
 			newFI, err := waitForFile(ctx, path)
+			if err != nil && ctx.Err() != nil && os.IsNotExist(err) {
+				// Mirroring was stopped while the file was missing, for example part way through a
+				// rotation, so finish with what's left in the current file
+				log.Debugf("Log file %v is missing, so finishing mirroring", path)
+				mirrorAvailableMessages(f, mf, isQMLog, fileMtime)
+				return
+			}
 			if err != nil {
 				log.Error(err)
+				state.setState(mirrorStateFailed)
 				errorChannel <- err
 				return
 			}
 			if !os.SameFile(fi, newFI) {
 				log.Debugf("Detected log rotation in file %v", path)
+				// The log file may be a symbolic link, which has been changed to point to a different file.
+				// os.Stat follows the link, so this is detected and handled in the same way as a rotation.
 				// WARNING: There is a possible race condition here.  If *another*
 				// log rotation happens before we can open the new file, then we
 				// could skip all those messages.  This could happen with a very small
 				// MQ error log size.
-				mirrorAvailableMessages(f, mf, isQMLog)
+				mirrorAvailableMessages(f, mf, isQMLog, fileMtime)
 				err = f.Close()
 				if err != nil {
 					log.Errorf("Unable to close mirror file handle: %v", err)
 				}
 				// Re-open file
 				log.Debugf("Re-opening error log file %v", path)
-				// #nosec G304 - no harm, we open readonly and check error.
-				f, err = os.OpenFile(path, os.O_RDONLY, 0)
+				// Use the information for the file which was actually opened, in case the file (or the
+				// target of a symbolic link) has changed again since it was checked
+				f, fi, err = reopenLogFile(ctx, path)
+				if err != nil && ctx.Err() != nil && os.IsNotExist(err) {
+					// Mirroring was stopped while the new file was missing, and everything in the old
+					// file has already been mirrored
+					log.Debugf("Log file %v is missing after rotation, so finishing mirroring", path)
+					return
+				}
 				if err != nil {
 					log.Error(err)
+					state.setState(mirrorStateFailed)
 					errorChannel <- err
 					return
 				}
-				fi = newFI
+				emitEvent("INFO", "log_rotated", fmt.Sprintf("Log rotated, reopened %v", path), map[string]interface{}{"ibm_path": path})
 				// Don't seek this time, because we know it's a new file
-				mirrorAvailableMessages(f, mf, isQMLog)
+				mirrorAvailableMessages(f, mf, isQMLog, fileMtime)
+			} else if pos, err := f.Seek(0, io.SeekCurrent); err == nil && newFI.Size() < pos {
+				// The file has been truncated in place, so start again from the beginning.  A file which has
+				// been truncated and then written past the previous position can't be detected this way.
+				log.Debugf("Detected truncation of file %v from %v to %v bytes", path, pos, newFI.Size())
+				_, err = f.Seek(0, io.SeekStart)
+				if err != nil {
+					log.Errorf("Unable to return to the start of truncated file %v: %v", path, err)
+				} else {
+					emitEvent("INFO", "log_truncated", fmt.Sprintf("Log truncated, reading %v from the start", path), map[string]interface{}{"ibm_path": path})
+					mirrorAvailableMessages(f, mf, isQMLog, fileMtime)
+				}
 			}
+			saveMirrorOffset(path, f, fi)
 			select {
 			case <-ctx.Done():
 				log.Debugf("Context cancelled for mirroring %v", path)
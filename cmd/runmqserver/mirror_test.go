@@ -16,9 +16,12 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -40,7 +43,7 @@ func TestMirrorLogWithoutRotation(t *testing.T) {
 			count := 0
 			ctx, cancel := context.WithCancel(context.Background())
 			var wg sync.WaitGroup
-			_, err = mirrorLog(ctx, &wg, tmp.Name(), true, func(msg string, isQMLog bool) bool {
+			_, err = mirrorLog(ctx, &wg, "qmgr", tmp.Name(), true, func(msg string, isQMLog bool) bool {
 				count++
 				return true
 			}, false)
@@ -82,7 +85,7 @@ func TestMirrorLogWithRotation(t *testing.T) {
 			count := 0
 			ctx, cancel := context.WithCancel(context.Background())
 			var wg sync.WaitGroup
-			_, err = mirrorLog(ctx, &wg, tmp.Name(), true, func(msg string, isQMLog bool) bool {
+			_, err = mirrorLog(ctx, &wg, "qmgr", tmp.Name(), true, func(msg string, isQMLog bool) bool {
 				count++
 				return true
 			}, false)
@@ -136,7 +139,7 @@ func testMirrorLogExistingFile(t *testing.T, newQM bool) int {
 	count := 0
 	ctx, cancel := context.WithCancel(context.Background())
 	var wg sync.WaitGroup
-	_, err = mirrorLog(ctx, &wg, tmp.Name(), newQM, func(msg string, isQMLog bool) bool {
+	_, err = mirrorLog(ctx, &wg, "qmgr", tmp.Name(), newQM, func(msg string, isQMLog bool) bool {
 		count++
 		return true
 	}, false)
@@ -181,7 +184,7 @@ func TestMirrorLogCancelWhileWaiting(t *testing.T) {
 		cancel()
 		wg.Wait()
 	}()
-	_, err := mirrorLog(ctx, &wg, "fake.log", true, func(msg string, isQMLog bool) bool {
+	_, err := mirrorLog(ctx, &wg, "qmgr", "fake.log", true, func(msg string, isQMLog bool) bool {
 		return true
 	}, false)
 	if err != nil {
@@ -192,3 +195,473 @@ func TestMirrorLogCancelWhileWaiting(t *testing.T) {
 	wg.Wait()
 	// No need to assert anything.  If it didn't work, the code would have hung (TODO: not ideal)
 }
+
+// mirrorLogOnce mirrors a file from the start, until the mirroring is cancelled, returning the messages seen
+func mirrorLogOnce(t *testing.T, path string) []string {
+	var msgs []string
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	_, err := mirrorLog(ctx, &wg, "qmgr", path, true, func(msg string, isQMLog bool) bool {
+		msgs = append(msgs, msg)
+		return true
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	wg.Wait()
+	return msgs
+}
+
+func TestMirrorLogResumeFromPersistedOffset(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "AMQERR01.json")
+	offsetFile := filepath.Join(dir, "offsets.json")
+	defer func() { mirrorOffsets = nil }()
+
+	os.WriteFile(logFile, []byte("{\"message\"=\"A\"}\n{\"message\"=\"B\"}\n"), 0600)
+	mirrorOffsets = loadOffsetStore(offsetFile)
+	msgs := mirrorLogOnce(t, logFile)
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 log entries before restart; got %v", msgs)
+	}
+
+	// Simulate a restart, by appending to the file and reloading the offsets from disk
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "{\"message\"=\"C\"}")
+	f.Close()
+	mirrorOffsets = loadOffsetStore(offsetFile)
+	msgs = mirrorLogOnce(t, logFile)
+	if len(msgs) != 1 || msgs[0] != "{\"message\"=\"C\"}" {
+		t.Fatalf("Expected only the new log entry after restart; got %v", msgs)
+	}
+
+	// Replace the file, so that the persisted offset no longer applies.  The new file is created
+	// before the old one is removed, so that it can't re-use the same inode.
+	os.WriteFile(logFile+".new", []byte("{\"message\"=\"D\"}\n{\"message\"=\"E\"}\n{\"message\"=\"F\"}\n"), 0600)
+	os.Rename(logFile+".new", logFile)
+	mirrorOffsets = loadOffsetStore(offsetFile)
+	msgs = mirrorLogOnce(t, logFile)
+	if len(msgs) != 3 {
+		t.Fatalf("Expected all log entries from a replaced file; got %v", msgs)
+	}
+}
+
+// captureConsole redirects mirrored log output to a buffer for the duration of a test
+func captureConsole(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	oldConsole := console
+	console = newConsoleWriter(&buf, 0, nil)
+	t.Cleanup(func() { console = oldConsole })
+	return &buf
+}
+
+func TestMirrorLogRotationMarker(t *testing.T) {
+	out := captureConsole(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "AMQERR01.json")
+	os.WriteFile(path, []byte("{\"message\"=\"A\"}\n"), 0600)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	_, err := mirrorLog(ctx, &wg, "qmgr", path, true, func(msg string, isQMLog bool) bool {
+		return true
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Give the mirror a chance to read the original file
+	time.Sleep(time.Second)
+	os.Rename(path, filepath.Join(dir, "AMQERR02.json"))
+	os.WriteFile(path, []byte("{\"message\"=\"B\"}\n"), 0600)
+	time.Sleep(time.Second)
+	cancel()
+	wg.Wait()
+	expected := "Log rotated, reopened " + path
+	if strings.Count(out.String(), expected) != 1 {
+		t.Errorf("Expected one rotation marker %q, got %q", expected, out.String())
+	}
+}
+
+func TestMirrorLogLiveEvent(t *testing.T) {
+	t.Setenv("MQ_LOGGING_LIVE_EVENT", "true")
+	oldJSON := eventsJSON
+	defer func() { eventsJSON = oldJSON }()
+	eventsJSON = false
+	out := captureConsole(t)
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	mf := func(msg string, isQMLog bool) bool {
+		writeConsole(msg+"\n", "")
+		return true
+	}
+	sources := []struct {
+		source    string
+		fromStart bool
+	}{
+		{"qmgr", true},
+		{"web", true},
+		{"htpass", false},
+	}
+	for _, s := range sources {
+		path := filepath.Join(dir, s.source+".json")
+		os.WriteFile(path, []byte(s.source+" old\n"), 0600)
+		_, err := mirrorLog(ctx, &wg, s.source, path, s.fromStart, mf, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(time.Second)
+	for _, s := range sources {
+		f, err := os.OpenFile(filepath.Join(dir, s.source+".json"), os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(s.source + " new\n")
+		f.Close()
+	}
+	time.Sleep(time.Second)
+	cancel()
+	wg.Wait()
+	for _, s := range sources {
+		marker := "Live tailing started for " + s.source
+		if !s.fromStart {
+			if strings.Contains(out.String(), marker) {
+				t.Errorf("Expected no marker for %v, which wasn't replayed; got %q", s.source, out.String())
+			}
+			continue
+		}
+		if strings.Count(out.String(), marker) != 1 {
+			t.Errorf("Expected one marker %q; got %q", marker, out.String())
+			continue
+		}
+		// The marker comes between the replayed and new messages
+		i := strings.Index(out.String(), marker)
+		if strings.Index(out.String(), s.source+" old") > i || strings.Index(out.String(), s.source+" new") < i {
+			t.Errorf("Expected the marker between the old and new messages for %v; got %q", s.source, out.String())
+		}
+	}
+}
+
+var mirrorLogFileMtimeTests = []struct {
+	format   string
+	withTime bool
+}{
+	{"json", true},
+	{"basic", false},
+	{"basic;qmgr:json", true},
+	{"json;qmgr:basic", false},
+}
+
+func TestMirrorLogFileMtime(t *testing.T) {
+	t.Setenv("MQ_LOGGING_FILE_MTIME", "true")
+	path := filepath.Join(t.TempDir(), "AMQERR01.json")
+	os.WriteFile(path, []byte("{\"message\":\"A\"}\nNot JSON\n"), 0600)
+	for _, table := range mirrorLogFileMtimeTests {
+		t.Run(table.format, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", table.format)
+			for _, mtime := range []time.Time{
+				time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC),
+			} {
+				err := os.Chtimes(path, mtime, mtime)
+				if err != nil {
+					t.Fatal(err)
+				}
+				msgs := mirrorLogOnce(t, path)
+				expected := "{\"message\":\"A\"}"
+				if table.withTime {
+					expected = fmt.Sprintf("{\"ibm_fileMtime\":\"%v\",\"message\":\"A\"}", mtime.Format(eventTimestampFormat))
+				}
+				if len(msgs) != 2 || msgs[0] != expected || msgs[1] != "Not JSON" {
+					t.Errorf("Expected [%v Not JSON]; got %v", expected, msgs)
+				}
+			}
+		})
+	}
+}
+
+func TestMirrorLogSymlinkRepointed(t *testing.T) {
+	captureConsole(t)
+	dir := t.TempDir()
+	target1 := filepath.Join(dir, "AMQERR01.json.1")
+	target2 := filepath.Join(dir, "AMQERR01.json.2")
+	link := filepath.Join(dir, "AMQERR01.json")
+	os.WriteFile(target1, []byte("{\"message\":\"A\"}\n"), 0600)
+	os.WriteFile(target2, []byte("{\"message\":\"B\"}\n"), 0600)
+	err := os.Symlink(target1, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	var msgs []string
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	_, err = mirrorLog(ctx, &wg, "qmgr", link, true, func(msg string, isQMLog bool) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		msgs = append(msgs, msg)
+		return true
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForMessages := func(n int) {
+		for i := 0; i < 50; i++ {
+			mutex.Lock()
+			got := len(msgs)
+			mutex.Unlock()
+			if got >= n {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for %v messages; got %v", n, msgs)
+	}
+	waitForMessages(1)
+
+	// Repoint the link atomically, then keep writing to the new target
+	tmp := filepath.Join(dir, "link.tmp")
+	err = os.Symlink(target2, tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Rename(tmp, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForMessages(2)
+	f, err := os.OpenFile(target2, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "{\"message\":\"C\"}")
+	f.Close()
+	waitForMessages(3)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := []string{"{\"message\":\"A\"}", "{\"message\":\"B\"}", "{\"message\":\"C\"}"}
+	if strings.Join(msgs, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v; got %v", expected, msgs)
+	}
+}
+
+var sourceCategoryTests = []struct {
+	source   string
+	expected string
+}{
+	{"qmgr", "qmgr"},
+	{"system", "system"},
+	{"htpass", "htpass"},
+	{"web", "web"},
+	{"mqsc", "mqsc"},
+	{"other", "extra"},
+}
+
+func TestMirrorLogSourceCategory(t *testing.T) {
+	t.Setenv("MQ_LOGGING_SOURCE_CATEGORY", "true")
+	// The category doesn't depend on whether the HTPasswd log is treated as a queue manager log
+	t.Setenv("MQ_LOGGING_HTPASS_AS_QMGR", "true")
+	path := filepath.Join(t.TempDir(), "AMQERR01.json")
+	os.WriteFile(path, []byte("{\"message\":\"A\"}\nNot JSON\n"), 0600)
+	for _, table := range sourceCategoryTests {
+		t.Run(table.source, func(t *testing.T) {
+			var msgs []string
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			_, err := mirrorLog(ctx, &wg, table.source, path, true, func(msg string, isQMLog bool) bool {
+				msgs = append(msgs, msg)
+				return true
+			}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cancel()
+			wg.Wait()
+			expected := []string{"{\"ibm_sourceCategory\":\"" + table.expected + "\",\"message\":\"A\"}", "Not JSON"}
+			if !reflect.DeepEqual(msgs, expected) {
+				t.Errorf("Expected %v; got %v", expected, msgs)
+			}
+		})
+	}
+}
+
+var mirrorLogRotationTests = []struct {
+	name   string
+	rotate func(path string) error
+}{
+	{
+		name: "truncate and rewrite",
+		rotate: func(path string) error {
+			err := os.Truncate(path, 0)
+			if err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = fmt.Fprintln(f, "{\"message\":\"B\"}")
+			return err
+		},
+	},
+	{
+		name: "rename then recreate",
+		rotate: func(path string) error {
+			err := os.Rename(path, path+".1")
+			if err != nil {
+				return err
+			}
+			// Leave the file missing for longer than the mirror waits between checks
+			time.Sleep(time.Second)
+			return os.WriteFile(path, []byte("{\"message\":\"B\"}\n"), 0600)
+		},
+	},
+	{
+		name: "delete then recreate",
+		rotate: func(path string) error {
+			err := os.Remove(path)
+			if err != nil {
+				return err
+			}
+			time.Sleep(300 * time.Millisecond)
+			return os.WriteFile(path, []byte("{\"message\":\"B\"}\n"), 0600)
+		},
+	},
+	{
+		name: "recreate with more data than before",
+		rotate: func(path string) error {
+			err := os.Remove(path)
+			if err != nil {
+				return err
+			}
+			// The new file is longer than the old one, so can only be detected as a different file
+			return os.WriteFile(path, []byte("{\"message\":\"B\"}\n{\"message\":\"A message which is longer than the previous ones\"}\n"), 0600)
+		},
+	},
+}
+
+func TestMirrorLogRotation(t *testing.T) {
+	for _, table := range mirrorLogRotationTests {
+		t.Run(table.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "AMQERR01.json")
+			os.WriteFile(path, []byte("{\"message\":\"A message which is longer than the next one\"}\n"), 0600)
+			var mutex sync.Mutex
+			var msgs []string
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			defer func() {
+				cancel()
+				wg.Wait()
+			}()
+			_, err := mirrorLog(ctx, &wg, "qmgr", path, true, func(msg string, isQMLog bool) bool {
+				mutex.Lock()
+				defer mutex.Unlock()
+				msgs = append(msgs, msg)
+				return true
+			}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			waitForMessages := func(n int) {
+				for i := 0; i < 50; i++ {
+					mutex.Lock()
+					got := len(msgs)
+					mutex.Unlock()
+					if got >= n {
+						return
+					}
+					time.Sleep(100 * time.Millisecond)
+				}
+				t.Fatalf("Timed out waiting for %v messages; got %v", n, msgs)
+			}
+			waitForMessages(1)
+			err = table.rotate(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			waitForMessages(2)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if msgs[1] != "{\"message\":\"B\"}" {
+				t.Errorf("Expected the rewritten message; got %v", msgs)
+			}
+		})
+	}
+}
+
+var mirrorLogStopWhileMissingTests = []struct {
+	name string
+	// stop removes the log file, and then cancels mirroring before the file is recreated
+	stop func(t *testing.T, path string, cancel context.CancelFunc)
+}{
+	{
+		name: "while waiting for the file",
+		stop: func(t *testing.T, path string, cancel context.CancelFunc) {
+			os.Remove(path)
+			time.Sleep(time.Second)
+			cancel()
+		},
+	},
+	{
+		name: "while reopening the file after rotation",
+		stop: func(t *testing.T, path string, cancel context.CancelFunc) {
+			oldReopen := reopenLogFile
+			t.Cleanup(func() { reopenLogFile = oldReopen })
+			reopenLogFile = func(ctx context.Context, path string) (*os.File, os.FileInfo, error) {
+				// The new file is removed again before it can be opened
+				os.Remove(path)
+				cancel()
+				return openLogFile(ctx, path)
+			}
+			os.Rename(path, path+".1")
+			os.WriteFile(path, []byte("{\"message\":\"B\"}\n"), 0600)
+		},
+	},
+}
+
+func TestMirrorLogStopWhileMissing(t *testing.T) {
+	for _, table := range mirrorLogStopWhileMissingTests {
+		t.Run(table.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "AMQERR01.json")
+			os.WriteFile(path, []byte("{\"message\":\"A\"}\n"), 0600)
+			var mutex sync.Mutex
+			var msgs []string
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			errs, err := mirrorLog(ctx, &wg, "qmgr", path, true, func(msg string, isQMLog bool) bool {
+				mutex.Lock()
+				defer mutex.Unlock()
+				msgs = append(msgs, msg)
+				return true
+			}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(time.Second)
+			// Remove the file part way through a rotation, and stop before it is recreated
+			table.stop(t, path, cancel)
+			wg.Wait()
+			select {
+			case err := <-errs:
+				t.Errorf("Expected no error; got %v", err)
+			default:
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if len(msgs) != 1 {
+				t.Errorf("Expected one message; got %v", msgs)
+			}
+		})
+	}
+}
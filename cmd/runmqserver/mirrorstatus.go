@@ -0,0 +1,145 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The states of a goroutine mirroring a log
+const (
+	mirrorStateWaiting = "waiting"
+	mirrorStateRunning = "running"
+	mirrorStateFailed  = "failed"
+	mirrorStateStopped = "stopped"
+)
+
+// mirrorState tracks the progress of a goroutine mirroring a log, for reporting on request
+type mirrorState struct {
+	mutex     sync.Mutex
+	source    string
+	path      string
+	state     string
+	bytesRead int64
+	lastLine  time.Time
+}
+
+// mirrorStates holds the state of each log being mirrored, keyed by path
+var mirrorStates = struct {
+	sync.Mutex
+	mirrors map[string]*mirrorState
+}{mirrors: make(map[string]*mirrorState)}
+
+// registerMirror starts tracking the state of a log being mirrored.  The log is waiting until the file exists.
+func registerMirror(source string, path string) *mirrorState {
+	s := &mirrorState{source: source, path: path, state: mirrorStateWaiting}
+	mirrorStates.Lock()
+	defer mirrorStates.Unlock()
+	mirrorStates.mirrors[path] = s
+	return s
+}
+
+// setState changes the state of the mirror
+func (s *mirrorState) setState(state string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.state = state
+}
+
+// stop marks the mirror as stopped, unless it has failed
+func (s *mirrorState) stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.state != mirrorStateFailed {
+		s.state = mirrorStateStopped
+	}
+}
+
+// track wraps a mirrorFunc, so that the bytes and time of each line read are recorded
+func (s *mirrorState) track(mf mirrorFunc) mirrorFunc {
+	return func(msg string, isQMLog bool) bool {
+		s.mutex.Lock()
+		// Include the new-line, which was removed when the line was read
+		s.bytesRead += int64(len(msg)) + 1
+		s.lastLine = timeNow()
+		s.mutex.Unlock()
+		return mf(msg, isQMLog)
+	}
+}
+
+// fields returns the state of the mirror, as fields for the status event
+func (s *mirrorState) fields() map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f := map[string]interface{}{
+		"source":    s.source,
+		"path":      s.path,
+		"state":     s.state,
+		"bytesRead": s.bytesRead,
+	}
+	if !s.lastLine.IsZero() {
+		f["lastLineTime"] = s.lastLine.UTC().Format(eventTimestampFormat)
+	}
+	return f
+}
+
+// emitMirrorStatus emits a "mirror_status" event, listing each log being mirrored and its state
+func emitMirrorStatus() {
+	mirrorStates.Lock()
+	paths := make([]string, 0, len(mirrorStates.mirrors))
+	for path := range mirrorStates.mirrors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	mirrors := make([]map[string]interface{}, 0, len(paths))
+	for _, path := range paths {
+		mirrors = append(mirrors, mirrorStates.mirrors[path].fields())
+	}
+	mirrorStates.Unlock()
+	emitEvent("INFO", "mirror_status", fmt.Sprintf("Mirroring %v logs", len(mirrors)), map[string]interface{}{"ibm_mirrors": mirrors})
+}
+
+// getStatusSignalEnabled returns true if the mirroring status should be emitted when SIGUSR1 is received
+func getStatusSignalEnabled() bool {
+	enabled := os.Getenv("MQ_LOGGING_STATUS_SIGNAL")
+	return enabled == "true" || enabled == "1"
+}
+
+// handleStatusSignal emits the mirroring status each time SIGUSR1 is received, until the context is cancelled
+func handleStatusSignal(ctx context.Context, wg *sync.WaitGroup) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				emitMirrorStatus()
+			}
+		}
+	}()
+}
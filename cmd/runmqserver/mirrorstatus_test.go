@@ -0,0 +1,66 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleStatusSignal(t *testing.T) {
+	oldMirrors, oldJSON, oldNow := mirrorStates.mirrors, eventsJSON, timeNow
+	defer func() { mirrorStates.mirrors, eventsJSON, timeNow = oldMirrors, oldJSON, oldNow }()
+	mirrorStates.mirrors = make(map[string]*mirrorState)
+	eventsJSON = true
+	timeNow = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	buf := captureConsole(t)
+
+	qmgr := registerMirror("qmgr", "/var/mqm/qmgrs/QM1/errors/AMQERR01.json")
+	qmgr.setState(mirrorStateRunning)
+	mf := qmgr.track(func(msg string, isQMLog bool) bool { return true })
+	mf("{\"message\":\"A\"}", true)
+	registerMirror("web", "/var/mqm/web/installations/Installation1/servers/mqweb/logs/messages.log")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	handleStatusSignal(ctx, &wg)
+	err := syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	var obj map[string]interface{}
+	err = json.Unmarshal(buf.Bytes(), &obj)
+	if err != nil {
+		t.Fatalf("Expected a single status record; got %v", buf.String())
+	}
+	expected := []interface{}{
+		map[string]interface{}{"source": "qmgr", "path": "/var/mqm/qmgrs/QM1/errors/AMQERR01.json", "state": "running", "bytesRead": float64(16), "lastLineTime": "2024-01-01T12:00:00.000Z"},
+		map[string]interface{}{"source": "web", "path": "/var/mqm/web/installations/Installation1/servers/mqweb/logs/messages.log", "state": "waiting", "bytesRead": float64(0)},
+	}
+	if obj["ibm_event"] != "mirror_status" || !reflect.DeepEqual(obj["ibm_mirrors"], expected) {
+		t.Errorf("Expected the status of each mirror; got %v", buf.String())
+	}
+}
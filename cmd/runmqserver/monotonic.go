@@ -0,0 +1,51 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// getMonotonicFilter returns true if records older than the last record mirrored from the same
+// source should be dropped
+func getMonotonicFilter() bool {
+	enabled := os.Getenv("MQ_LOGGING_MONOTONIC")
+	return enabled == "true" || enabled == "1"
+}
+
+// newMonotonicFilter wraps a mirrorFunc, so that records with a timestamp older than the most
+// recently mirrored record are dropped.  This prevents old records being mirrored again, for
+// example when a log is reopened after rotation.  Records without a timestamp are always mirrored.
+func newMonotonicFilter(source string, mf mirrorFunc) mirrorFunc {
+	var last time.Time
+	return func(msg string, isQMLog bool) bool {
+		obj, err := processLogMessage(msg)
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		datetime := newMQLogRecord(obj).Datetime()
+		if datetime.IsZero() {
+			return mf(msg, isQMLog)
+		}
+		if datetime.Before(last) {
+			log.Debugf("Dropping %v log record from %v, which is older than the last record mirrored", source, datetime)
+			return false
+		}
+		last = datetime
+		return mf(msg, isQMLog)
+	}
+}
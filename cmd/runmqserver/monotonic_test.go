@@ -0,0 +1,44 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMonotonicFilter(t *testing.T) {
+	var msgs []string
+	mf := newMonotonicFilter("qmgr", func(msg string, isQMLog bool) bool {
+		msgs = append(msgs, msg)
+		return true
+	})
+	in := []string{
+		"{\"ibm_datetime\":\"2024-01-01T10:00:02.000Z\",\"message\":\"A\"}",
+		"{\"ibm_datetime\":\"2024-01-01T10:00:01.000Z\",\"message\":\"Old\"}",
+		"{\"message\":\"No timestamp\"}",
+		"Not JSON",
+		"{\"ibm_datetime\":\"2024-01-01T10:00:02.000Z\",\"message\":\"Same time\"}",
+		"{\"ibm_datetime\":\"2024-01-01T10:00:03.000Z\",\"message\":\"B\"}",
+	}
+	for _, msg := range in {
+		mf(msg, true)
+	}
+	expected := []string{in[0], in[2], in[3], in[4], in[5]}
+	if !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("Expected %v; got %v", expected, msgs)
+	}
+}
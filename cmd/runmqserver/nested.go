@@ -0,0 +1,85 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// nestedJSONMode controls what happens to a message field which itself contains a JSON object
+type nestedJSONMode string
+
+const (
+	// nestedJSONNone leaves the message field as a string
+	nestedJSONNone nestedJSONMode = ""
+	// nestedJSONMerge merges the fields of the embedded object into the log message
+	nestedJSONMerge nestedJSONMode = "merge"
+	// nestedJSONNest adds the embedded object to the log message as an "ibm_messageJSON" field
+	nestedJSONNest nestedJSONMode = "nest"
+)
+
+// getNestedJSONMode returns the value of MQ_LOGGING_NESTED_JSON, which is off by default
+func getNestedJSONMode() (nestedJSONMode, error) {
+	mode := nestedJSONMode(strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_NESTED_JSON"))))
+	switch mode {
+	case nestedJSONNone, nestedJSONMerge, nestedJSONNest:
+		return mode, nil
+	}
+	return nestedJSONNone, fmt.Errorf("invalid value for MQ_LOGGING_NESTED_JSON: %v", mode)
+}
+
+// expandNestedJSON wraps a mirrorFunc, so that a JSON log message whose message field is itself a JSON object
+// is decoded.  In merge mode, the fields of the embedded object are added to the log message, without replacing
+// any which are already there, except that the message field is replaced by the embedded message field if it has one.
+// In nest mode, the embedded object is added as an "ibm_messageJSON" field.  Other messages are passed through untouched.
+func expandNestedJSON(mf mirrorFunc, mode nestedJSONMode) mirrorFunc {
+	return func(msg string, isQMLog bool) bool {
+		obj, err := processLogMessage(trimRecordPrefix(msg))
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		text, ok := obj["message"].(string)
+		if !ok || !strings.HasPrefix(strings.TrimSpace(text), "{") {
+			return mf(msg, isQMLog)
+		}
+		var embedded map[string]interface{}
+		err = json.Unmarshal([]byte(text), &embedded)
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		switch mode {
+		case nestedJSONMerge:
+			for k, v := range embedded {
+				if _, exists := obj[k]; !exists {
+					obj[k] = v
+				}
+			}
+			if inner, ok := embedded["message"]; ok {
+				obj["message"] = inner
+			}
+		case nestedJSONNest:
+			obj["ibm_messageJSON"] = embedded
+		}
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return mf(msg, isQMLog)
+		}
+		return mf(string(b), isQMLog)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+)
+
+const doubleEncodedMessage = "{\"loglevel\":\"INFO\",\"message\":\"{\\\"message\\\":\\\"User logged in\\\",\\\"user\\\":\\\"admin\\\",\\\"loglevel\\\":\\\"DEBUG\\\"}\"}"
+
+var expandNestedJSONTests = []struct {
+	name     string
+	mode     nestedJSONMode
+	msg      string
+	expected string
+}{
+	{"Merge", nestedJSONMerge, doubleEncodedMessage, "{\"loglevel\":\"INFO\",\"message\":\"User logged in\",\"user\":\"admin\"}"},
+	{"Nest", nestedJSONNest, doubleEncodedMessage, "{\"ibm_messageJSON\":{\"loglevel\":\"DEBUG\",\"message\":\"User logged in\",\"user\":\"admin\"},\"loglevel\":\"INFO\",\"message\":\"{\\\"message\\\":\\\"User logged in\\\",\\\"user\\\":\\\"admin\\\",\\\"loglevel\\\":\\\"DEBUG\\\"}\"}"},
+	{"PlainMessage", nestedJSONMerge, "{\"message\":\"Hello\"}", "{\"message\":\"Hello\"}"},
+	{"InvalidEmbedded", nestedJSONMerge, "{\"message\":\"{not JSON\"}", "{\"message\":\"{not JSON\"}"},
+	{"NotJSON", nestedJSONNest, "Not JSON", "Not JSON"},
+}
+
+func TestExpandNestedJSON(t *testing.T) {
+	for _, table := range expandNestedJSONTests {
+		t.Run(table.name, func(t *testing.T) {
+			var got string
+			mf := expandNestedJSON(func(msg string, isQMLog bool) bool {
+				got = msg
+				return true
+			}, table.mode)
+			mf(table.msg, false)
+			if got != table.expected {
+				t.Errorf("Expected %v; got %v", table.expected, got)
+			}
+		})
+	}
+}
+
+func TestNestedJSONModeDefault(t *testing.T) {
+	mode, err := getNestedJSONMode()
+	if err != nil || mode != nestedJSONNone {
+		t.Errorf("Expected embedded JSON to be left alone by default; got %v, %v", mode, err)
+	}
+	t.Setenv("MQ_LOGGING_NESTED_JSON", "flatten")
+	_, err = getNestedJSONMode()
+	if err == nil {
+		t.Error("Expected an error for an invalid mode")
+	}
+}
@@ -0,0 +1,137 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// defaultMirrorOffsetFile is the location of the persisted read offsets, on the data volume
+const defaultMirrorOffsetFile string = "/var/mqm/errors/.runmqserver-mirror-offsets.json"
+
+// mirrorOffsets holds the persisted read offsets for mirrored logs, or nil if offsets aren't being persisted
+var mirrorOffsets *offsetStore
+
+// mirrorOffset is the persisted position in a mirrored log file
+type mirrorOffset struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// offsetStore persists the position reached in each mirrored log file, so that mirroring can
+// resume from the same place after a restart, without emitting lines a second time
+type offsetStore struct {
+	mutex   sync.Mutex
+	path    string
+	offsets map[string]mirrorOffset
+}
+
+// loadOffsetStore reads the persisted offsets from a file.  A missing or unreadable file results
+// in an empty store.
+func loadOffsetStore(path string) *offsetStore {
+	s := &offsetStore{
+		path:    path,
+		offsets: make(map[string]mirrorOffset),
+	}
+	// #nosec G304 - the path is not user-supplied
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debugf("Unable to read mirror offsets from %v: %v", path, err)
+		}
+		return s
+	}
+	err = json.Unmarshal(b, &s.offsets)
+	if err != nil {
+		log.Printf("Ignoring invalid mirror offsets file %v: %v", path, err)
+		s.offsets = make(map[string]mirrorOffset)
+	}
+	return s
+}
+
+// fileInode returns the inode number of a file, or zero if it isn't available
+func fileInode(fi os.FileInfo) uint64 {
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return stat.Ino
+	}
+	return 0
+}
+
+// lookup returns the persisted offset for a log file, if the file is the same one the offset was
+// recorded for
+func (s *offsetStore) lookup(path string, fi os.FileInfo) (int64, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	o, ok := s.offsets[path]
+	if !ok || o.Inode == 0 || o.Inode != fileInode(fi) || o.Offset > fi.Size() {
+		return 0, false
+	}
+	return o.Offset, true
+}
+
+// save records the offset reached in a log file, and writes all offsets to disk
+func (s *offsetStore) save(path string, fi os.FileInfo, offset int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	o := mirrorOffset{Inode: fileInode(fi), Offset: offset}
+	if s.offsets[path] == o {
+		return
+	}
+	s.offsets[path] = o
+	b, err := json.Marshal(s.offsets)
+	if err != nil {
+		log.Debugf("Unable to encode mirror offsets: %v", err)
+		return
+	}
+	// Write to a temporary file first, so that the offsets file is never left partially written
+	tmp := filepath.Join(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp")
+	// #nosec G306 - its a read by owner/s group, and pose no harm.
+	err = os.WriteFile(tmp, b, 0660)
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		log.Debugf("Unable to write mirror offsets to %v: %v", s.path, err)
+	}
+}
+
+// saveMirrorOffset persists the current read position in a mirrored log file, if enabled
+func saveMirrorOffset(path string, f *os.File, fi os.FileInfo) {
+	if mirrorOffsets == nil {
+		return
+	}
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		log.Debugf("Unable to determine offset in file %v: %v", path, err)
+		return
+	}
+	mirrorOffsets.save(path, fi, pos)
+}
+
+// configureMirrorOffsets enables persisting of mirror offsets, if MQ_LOGGING_PERSIST_OFFSET is set
+func configureMirrorOffsets() {
+	persist := os.Getenv("MQ_LOGGING_PERSIST_OFFSET")
+	if persist != "true" && persist != "1" {
+		mirrorOffsets = nil
+		return
+	}
+	mirrorOffsets = loadOffsetStore(defaultMirrorOffsetFile)
+}
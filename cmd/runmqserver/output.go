@@ -0,0 +1,173 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// backpressurePolicy controls what happens when console output can't keep up with mirrored log messages
+type backpressurePolicy string
+
+const (
+	// backpressureBlock waits for space in the output queue, which slows down mirroring
+	backpressureBlock backpressurePolicy = "block"
+	// backpressureDropOldest discards the oldest queued line, to make space for the new one
+	backpressureDropOldest backpressurePolicy = "drop-oldest"
+	// backpressureDropNewest discards the new line
+	backpressureDropNewest backpressurePolicy = "drop-newest"
+)
+
+// output queues mirrored log lines for the console, or is nil if lines are written directly
+var output *outputQueue
+
+// outputLine is a log line waiting to be written
+type outputLine struct {
+	line      string
+	messageID string
+}
+
+// outputQueue decouples the mirroring of logs from writing to the console, so that a slow
+// console doesn't stall the reading of log files
+type outputQueue struct {
+	policy  backpressurePolicy
+	queue   chan outputLine
+	write   func(line string, messageID string)
+	wg      sync.WaitGroup
+	once    sync.Once
+	dropped uint64
+	// mutex makes sure that dropping the oldest line, and queueing the new one, can't be interleaved with another writer
+	mutex sync.Mutex
+	// closeMutex stops lines being queued while the queue is closed
+	closeMutex sync.RWMutex
+	closed     bool
+}
+
+// newOutputQueue creates a queue of the given size, and starts a goroutine to write the queued lines
+func newOutputQueue(size int, policy backpressurePolicy, write func(line string, messageID string)) *outputQueue {
+	q := &outputQueue{
+		policy: policy,
+		queue:  make(chan outputLine, size),
+		write:  write,
+	}
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		for l := range q.queue {
+			q.write(l.line, l.messageID)
+		}
+	}()
+	return q
+}
+
+// Write queues a log line, applying the backpressure policy if the queue is full
+func (q *outputQueue) Write(line string, messageID string) {
+	q.closeMutex.RLock()
+	defer q.closeMutex.RUnlock()
+	if q.closed {
+		// Anything logged after shutdown is written directly
+		q.write(line, messageID)
+		return
+	}
+	l := outputLine{line: line, messageID: messageID}
+	switch q.policy {
+	case backpressureDropNewest:
+		select {
+		case q.queue <- l:
+		default:
+			atomic.AddUint64(&q.dropped, 1)
+		}
+	case backpressureDropOldest:
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		for {
+			select {
+			case q.queue <- l:
+				return
+			default:
+			}
+			select {
+			case <-q.queue:
+				atomic.AddUint64(&q.dropped, 1)
+			default:
+			}
+		}
+	default:
+		q.queue <- l
+	}
+}
+
+// Dropped returns the number of lines which have been discarded
+func (q *outputQueue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// Close writes any queued lines, and stops the queue.  Lines written after the queue is closed are
+// written directly.
+func (q *outputQueue) Close() {
+	q.once.Do(func() {
+		q.closeMutex.Lock()
+		q.closed = true
+		close(q.queue)
+		q.closeMutex.Unlock()
+		q.wg.Wait()
+		if dropped := q.Dropped(); dropped > 0 {
+			log.Printf("Dropped %v log messages because the console was too slow", dropped)
+		}
+	})
+}
+
+// closeOutputQueue writes any queued lines to the console
+func closeOutputQueue() {
+	if output != nil {
+		output.Close()
+	}
+}
+
+// configureOutputQueue sets up a queue for console output, if MQ_LOGGING_OUTPUT_QUEUE_SIZE is set.
+// MQ_LOGGING_BACKPRESSURE_POLICY controls what happens when the queue is full.
+func configureOutputQueue() error {
+	// Write anything queued, and stop the goroutine writing it, before the queue is replaced
+	closeOutputQueue()
+	output = nil
+	size, err := getPositiveIntEnv("MQ_LOGGING_OUTPUT_QUEUE_SIZE", 0)
+	if err != nil {
+		return err
+	}
+	policy := backpressurePolicy(strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_BACKPRESSURE_POLICY"))))
+	switch policy {
+	case "":
+		policy = backpressureBlock
+	case backpressureBlock, backpressureDropOldest, backpressureDropNewest:
+	default:
+		return fmt.Errorf("invalid value for MQ_LOGGING_BACKPRESSURE_POLICY: %v", policy)
+	}
+	if size == 0 {
+		return nil
+	}
+	output = newOutputQueue(size, policy, writeQueuedLine)
+	return nil
+}
+
+// writeQueuedLine writes a line from the output queue to the current console, which may have been
+// replaced since the line was queued
+func writeQueuedLine(line string, messageID string) {
+	console.WriteLine(line, messageID)
+}
@@ -0,0 +1,144 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// slowWriter records lines, but doesn't write anything until it is released
+type slowWriter struct {
+	mutex   sync.Mutex
+	lines   []string
+	release chan struct{}
+	started chan struct{}
+	once    sync.Once
+}
+
+func newSlowWriter() *slowWriter {
+	return &slowWriter{release: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (w *slowWriter) write(line string, messageID string) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.lines = append(w.lines, line)
+}
+
+var backpressureTests = []struct {
+	policy   backpressurePolicy
+	expected []string
+	dropped  uint64
+}{
+	{backpressureBlock, []string{"0", "1", "2", "3", "4"}, 0},
+	{backpressureDropNewest, []string{"0", "1", "2"}, 2},
+	{backpressureDropOldest, []string{"0", "3", "4"}, 2},
+}
+
+func TestOutputQueueBackpressure(t *testing.T) {
+	captureLog(t)
+	for _, table := range backpressureTests {
+		t.Run(string(table.policy), func(t *testing.T) {
+			w := newSlowWriter()
+			q := newOutputQueue(2, table.policy, w.write)
+			// The first line is taken by the writer, which then stalls
+			q.Write("0", "")
+			<-w.started
+			done := make(chan struct{})
+			go func() {
+				for i := 1; i < 5; i++ {
+					q.Write(fmt.Sprint(i), "")
+				}
+				close(done)
+			}()
+			if table.policy == backpressureBlock {
+				select {
+				case <-done:
+					t.Fatal("Expected writes to block while the console is slow")
+				default:
+				}
+				close(w.release)
+				<-done
+			} else {
+				<-done
+				close(w.release)
+			}
+			q.Close()
+			if !reflect.DeepEqual(w.lines, table.expected) {
+				t.Errorf("Expected lines %v; got %v", table.expected, w.lines)
+			}
+			if q.Dropped() != table.dropped {
+				t.Errorf("Expected %v dropped lines; got %v", table.dropped, q.Dropped())
+			}
+		})
+	}
+}
+
+func TestOutputQueueWriteAfterClose(t *testing.T) {
+	var lines []string
+	q := newOutputQueue(1, backpressureBlock, func(line string, messageID string) {
+		lines = append(lines, line)
+	})
+	q.Close()
+	q.Write("late", "")
+	if !reflect.DeepEqual(lines, []string{"late"}) {
+		t.Errorf("Expected line written after close to be written directly; got %v", lines)
+	}
+}
+
+func TestConfigureOutputQueueInvalidPolicy(t *testing.T) {
+	defer func() { output = nil }()
+	t.Setenv("MQ_LOGGING_OUTPUT_QUEUE_SIZE", "10")
+	t.Setenv("MQ_LOGGING_BACKPRESSURE_POLICY", "sometimes")
+	err := configureOutputQueue()
+	if err == nil {
+		t.Error("Expected an error for an invalid backpressure policy")
+	}
+}
+
+func TestConfigureOutputQueueReplaced(t *testing.T) {
+	defer func() {
+		closeOutputQueue()
+		output = nil
+	}()
+	t.Setenv("MQ_LOGGING_OUTPUT_QUEUE_SIZE", "10")
+	buf := captureConsole(t)
+	err := configureOutputQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeConsole("A\n", "")
+	// Replacing the queue writes anything queued on the old one
+	err = configureOutputQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "A\n" {
+		t.Errorf("Expected the old queue to be written when it was replaced; got %q", buf.String())
+	}
+	// Queued lines are written to the console which is current when they are written
+	buf2 := captureConsole(t)
+	writeConsole("B\n", "")
+	closeOutputQueue()
+	if buf.String() != "A\n" || buf2.String() != "B\n" {
+		t.Errorf("Expected the line to be written to the new console; got %q and %q", buf.String(), buf2.String())
+	}
+}
@@ -0,0 +1,210 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// describeLoggingPipeline returns a readable description of the effective log mirroring pipeline, in the
+// order messages pass through it: sources, then filters, then transforms, then sinks.  The sinks are
+// those set up by configureLogger, so it should be called afterwards.
+func describeLoggingPipeline() string {
+	format := getLogFormat()
+	opts, err := getMirrorOptions()
+	if err != nil {
+		return fmt.Sprintf("Logging pipeline: invalid configuration: %v", err)
+	}
+	overrides, err := getLogFormatOverrides()
+	if err != nil {
+		return fmt.Sprintf("Logging pipeline: invalid configuration: %v", err)
+	}
+
+	sources := make([]string, 0)
+	for _, source := range []string{"qmgr", "web", "htpass"} {
+		if source == "htpass" && !shouldMirrorHTPasswdLogs() {
+			continue
+		}
+		if source != "htpass" && !checkLogSourceForMirroring(source) {
+			continue
+		}
+		f := format
+		if override, ok := overrides[source]; ok {
+			f = override
+		}
+		sources = append(sources, fmt.Sprintf("%v (%v)", source, f))
+	}
+	if getFDCEventEnabled() {
+		sources = append(sources, "FDC files in "+fdcDir)
+	}
+
+	filters := make([]string, 0)
+	ids := make([]string, 0)
+	for _, id := range getExcludeIDs() {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		filters = append(filters, "exclude message IDs "+strings.Join(ids, ","))
+	}
+	if s := strings.TrimSpace(os.Getenv("MQ_LOGGING_CONSOLE_EXCLUDE_REGEX")); s != "" {
+		filters = append(filters, "exclude lines matching MQ_LOGGING_CONSOLE_EXCLUDE_REGEX")
+	}
+	if len(opts.includeIDs) > 0 {
+		included := make([]string, 0, len(opts.includeIDs))
+		for id := range opts.includeIDs {
+			included = append(included, id)
+		}
+		sort.Strings(included)
+		filters = append(filters, "only message IDs "+strings.Join(included, ","))
+	}
+	if os.Getenv("MQ_MULTI_INSTANCE") == "true" {
+		filters = append(filters, "only messages from this host")
+	}
+	if opts.emptyRecord == emptyRecordMark {
+		filters = append(filters, "mark empty records")
+	} else {
+		filters = append(filters, "drop empty records")
+	}
+	if opts.missingMessage == missingMessageSkip {
+		filters = append(filters, "drop records with no message in basic format")
+	}
+	if len(opts.requireFields) > 0 {
+		mode := "any"
+		if opts.requireAllFields {
+			mode = "all"
+		}
+		filters = append(filters, fmt.Sprintf("require %v of fields %v", mode, strings.Join(opts.requireFields, ",")))
+	}
+	if opts.minLevel > levelDebug {
+		filters = append(filters, fmt.Sprintf("only %v and above", opts.minLevel))
+	}
+	if opts.verboseWindow != nil {
+		filters = append(filters, fmt.Sprintf("only %v and above outside the verbose window", opts.quietLevel))
+	}
+	if opts.shutdownMode == shutdownModeSuppress {
+		filters = append(filters, "suppress non-errors after "+strings.Join(opts.shutdownIDs, ","))
+	}
+	if consoleDedup != nil {
+		filters = append(filters, fmt.Sprintf("collapse repeated messages, with a summary every %v", consoleDedup.interval))
+	}
+
+	transforms := make([]string, 0)
+	if mode, err := getNestedJSONMode(); err == nil && mode != nestedJSONNone {
+		transforms = append(transforms, fmt.Sprintf("%v embedded JSON messages", mode))
+	}
+	if getEnglishMessages() {
+		transforms = append(transforms, "translate known messages to English")
+	}
+	if getSourceCategoryEnabled() {
+		transforms = append(transforms, "add ibm_sourceCategory")
+	}
+	if getFileMtimeEnabled() {
+		transforms = append(transforms, "add ibm_fileMtime")
+	}
+	if getMonotonicFilter() {
+		transforms = append(transforms, "order timestamps monotonically")
+	}
+	if len(opts.labels) > 0 {
+		keys := make([]string, 0, len(opts.labels))
+		for _, l := range opts.labels {
+			keys = append(keys, l.key)
+		}
+		transforms = append(transforms, "add labels "+strings.Join(keys, ","))
+	}
+	if opts.shutdownMode == shutdownModeTag {
+		transforms = append(transforms, "tag non-errors after "+strings.Join(opts.shutdownIDs, ","))
+	}
+	if repeatEscalation != nil {
+		transforms = append(transforms, fmt.Sprintf("escalate messages repeated more than %v times in %v to %v", repeatEscalation.threshold, repeatEscalation.window, repeatEscalation.level))
+	}
+	if opts.epoch != "" {
+		transforms = append(transforms, "add ibm_epoch")
+	}
+	if activeQueueManagerStatus != nil {
+		transforms = append(transforms, "add ibm_qmgrStatus")
+	}
+	if opts.reasonCode {
+		transforms = append(transforms, "add ibm_reasonCode")
+	}
+	if opts.severityNumber != severityNumberNone {
+		transforms = append(transforms, fmt.Sprintf("add ibm_severityNumber (%v)", opts.severityNumber))
+	}
+	if opts.recordBytes {
+		transforms = append(transforms, "add ibm_recordBytes")
+	}
+	if opts.elapsed {
+		transforms = append(transforms, "add ibm_qmgrElapsedMs")
+	}
+	for _, style := range opts.keyStyles {
+		transforms = append(transforms, fmt.Sprintf("rename keys (%v)", style))
+	}
+	if opts.timestampField != "" {
+		transforms = append(transforms, "copy ibm_datetime to "+opts.timestampField)
+	}
+	if merge != nil {
+		transforms = append(transforms, fmt.Sprintf("merge qmgr and web in timestamp order, within %v", merge.window))
+	}
+	if opts.dualOutput {
+		transforms = append(transforms, "follow each message with its original record")
+	}
+	if opts.schema != nil {
+		transforms = append(transforms, "check against MQ_LOGGING_JSON_SCHEMA in JSON format")
+	}
+	if opts.template != nil {
+		transforms = append(transforms, "render with MQ_LOGGING_TEMPLATE in JSON format")
+	}
+	if getBasicColumns() {
+		transforms = append(transforms, "fixed-width columns in basic format")
+	}
+
+	sinkNames := make([]string, 0)
+	if len(declaredSinks) > 0 {
+		for _, d := range declaredSinks {
+			sinkNames = append(sinkNames, fmt.Sprintf("%v (%v)", d.kind, d.format))
+		}
+	} else {
+		stream := "stdout"
+		if s, err := getConsoleStream(); err == nil && s == os.Stderr {
+			stream = "stderr"
+		}
+		sinkNames = append(sinkNames, fmt.Sprintf("console %v (%v)", stream, format))
+		if journal != nil {
+			sinkNames = append(sinkNames, "journald")
+		}
+	}
+	for _, s := range sinks {
+		switch s := s.(type) {
+		case *httpSink:
+			sinkNames = append(sinkNames, "http "+s.url)
+		case *udsSink:
+			sinkNames = append(sinkNames, "socket "+s.path)
+		}
+	}
+
+	describe := func(items []string) string {
+		if len(items) == 0 {
+			return "none"
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("Logging pipeline:\n  sources: %v\n  filters: %v\n  transforms: %v\n  sinks: %v",
+		describe(sources), describe(filters), describe(transforms), describe(sinkNames))
+}
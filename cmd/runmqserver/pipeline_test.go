@@ -0,0 +1,72 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDescribeLoggingPipeline(t *testing.T) {
+	oldLog, oldJSON := log, eventsJSON
+	defer func() {
+		log, eventsJSON = oldLog, oldJSON
+		closeDeclaredSinks()
+		declaredSinks = nil
+	}()
+	path := filepath.Join(t.TempDir(), "mirror.json")
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json;web:basic")
+	t.Setenv("MQ_LOGGING_CONSOLE_SOURCE", "qmgr,web")
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID", "AMQ5051I, AMQ5041I")
+	t.Setenv("MQ_LOGGING_LABELS", "cluster=east")
+	t.Setenv("MQ_LOGGING_SOURCE_CATEGORY", "true")
+	t.Setenv("MQ_LOGGING_SINKS", "type=console,format=basic;type=file,format=json,path="+path)
+	_, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Logging pipeline:\n" +
+		"  sources: qmgr (json), web (basic), htpass (json)\n" +
+		"  filters: exclude message IDs AMQ5051I,AMQ5041I, drop empty records\n" +
+		"  transforms: add ibm_sourceCategory, add labels cluster\n" +
+		"  sinks: console (basic), file (json)"
+	description := describeLoggingPipeline()
+	if description != expected {
+		t.Errorf("Expected %q; got %q", expected, description)
+	}
+
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "basic")
+	t.Setenv("MQ_LOGGING_CONSOLE_SOURCE", "qmgr")
+	t.Setenv("MQ_LOGGING_CONSOLE_EXCLUDE_ID", "")
+	t.Setenv("MQ_LOGGING_LABELS", "")
+	t.Setenv("MQ_LOGGING_SOURCE_CATEGORY", "")
+	t.Setenv("MQ_LOGGING_HTPASS_AS_QMGR", "true")
+	t.Setenv("MQ_LOGGING_SINKS", "")
+	t.Setenv("MQ_LOGGING_EMPTY_RECORD", "mark")
+	_, err = configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "Logging pipeline:\n" +
+		"  sources: qmgr (basic), htpass (basic)\n" +
+		"  filters: mark empty records\n" +
+		"  transforms: none\n" +
+		"  sinks: console stdout (basic)"
+	description = describeLoggingPipeline()
+	if description != expected {
+		t.Errorf("Expected %q; got %q", expected, description)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/ibm-messaging/mq-container/internal/command"
+)
+
+const (
+	defaultQueueManagerStatusInterval = 10 * time.Second
+	// unknownQueueManagerStatus is used when the status isn't available
+	unknownQueueManagerStatus = "UNKNOWN"
+)
+
+// dspmqStatus matches the status in the output of "dspmq -n"
+var dspmqStatus = regexp.MustCompile(`STATUS\(([^)]*)\)`)
+
+// getQueueManagerStatus returns the status of a queue manager, such as "RUNNING", and can be replaced for testing
+var getQueueManagerStatus = func(name string) (string, error) {
+	out, rc, err := command.Run("dspmq", "-n", "-m", name)
+	if err != nil {
+		return "", fmt.Errorf("dspmq failed with rc %v: %v", rc, err)
+	}
+	m := dspmqStatus.FindStringSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("no status in dspmq output: %v", out)
+	}
+	return m[1], nil
+}
+
+// queueManagerStatus caches the status of the queue manager, so that it can be added to log messages
+// without running a command for each one
+type queueManagerStatus struct {
+	mutex  sync.Mutex
+	name   string
+	status string
+	// source gets the current status
+	source func(name string) (string, error)
+	stop   chan struct{}
+}
+
+// get returns the cached status
+func (s *queueManagerStatus) get() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.status
+}
+
+// refresh updates the cached status.  If the status isn't available, for example because the queue
+// manager hasn't been created yet, the status is unknown until the next refresh.
+func (s *queueManagerStatus) refresh() {
+	status, err := s.source(s.name)
+	if err != nil {
+		log.Debugf("Unable to get status of queue manager %v: %v", s.name, err)
+		status = unknownQueueManagerStatus
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status = status
+}
+
+// run refreshes the status periodically, until stopped
+func (s *queueManagerStatus) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.refresh()
+		}
+	}
+}
+
+// activeQueueManagerStatus is the status being refreshed, so that it can be stopped if the logger is configured again
+var activeQueueManagerStatus *queueManagerStatus
+
+// configureQueueManagerStatus starts caching the status of the queue manager, if MQ_LOGGING_QMGR_STATUS is
+// set, so that it can be added to log messages in JSON format.  The status is refreshed with the interval
+// in MQ_LOGGING_QMGR_STATUS_INTERVAL.  Nil is returned if the status isn't needed.
+func configureQueueManagerStatus(name string) (*queueManagerStatus, error) {
+	if activeQueueManagerStatus != nil {
+		close(activeQueueManagerStatus.stop)
+		activeQueueManagerStatus = nil
+	}
+	enabled := os.Getenv("MQ_LOGGING_QMGR_STATUS")
+	if enabled != "true" && enabled != "1" {
+		return nil, nil
+	}
+	interval, err := getDurationEnv("MQ_LOGGING_QMGR_STATUS_INTERVAL", defaultQueueManagerStatusInterval)
+	if err != nil {
+		return nil, err
+	}
+	s := &queueManagerStatus{name: name, status: unknownQueueManagerStatus, source: getQueueManagerStatus, stop: make(chan struct{})}
+	go func() {
+		s.refresh()
+		s.run(interval)
+	}()
+	activeQueueManagerStatus = s
+	return s, nil
+}
@@ -0,0 +1,87 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// stubQueueManagerStatus replaces the queue manager status with one set by the test, for the duration of the test
+func stubQueueManagerStatus(t *testing.T) func(status string, err error) {
+	var mutex sync.Mutex
+	current, currentErr := "", errors.New("not started")
+	oldStatus := getQueueManagerStatus
+	getQueueManagerStatus = func(name string) (string, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return current, currentErr
+	}
+	t.Cleanup(func() { getQueueManagerStatus = oldStatus })
+	return func(status string, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		current, currentErr = status, err
+	}
+}
+
+func TestQueueManagerStatusRefresh(t *testing.T) {
+	setStatus := stubQueueManagerStatus(t)
+	s := &queueManagerStatus{name: "QM1", status: unknownQueueManagerStatus, source: getQueueManagerStatus}
+	steps := []struct {
+		status   string
+		err      error
+		expected string
+	}{
+		{"STARTING", nil, "STARTING"},
+		{"RUNNING", nil, "RUNNING"},
+		{"", errors.New("dspmq failed"), unknownQueueManagerStatus},
+		{"ENDING IMMEDIATELY", nil, "ENDING IMMEDIATELY"},
+	}
+	for _, step := range steps {
+		setStatus(step.status, step.err)
+		s.refresh()
+		if s.get() != step.expected {
+			t.Errorf("Expected status %v; got %v", step.expected, s.get())
+		}
+	}
+}
+
+func TestQueueManagerStatusField(t *testing.T) {
+	oldLog := log
+	defer func() {
+		log = oldLog
+		close(activeQueueManagerStatus.stop)
+		activeQueueManagerStatus = nil
+	}()
+	setStatus := stubQueueManagerStatus(t)
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json")
+	t.Setenv("MQ_LOGGING_QMGR_STATUS", "true")
+	t.Setenv("MQ_LOGGING_QMGR_STATUS_INTERVAL", "1h")
+	mf, err := configureLogger("QM1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setStatus("RUNNING", nil)
+	activeQueueManagerStatus.refresh()
+	buf := captureConsole(t)
+	mf("{\"message\":\"Hello\"}", false)
+	expected := "{\"ibm_qmgrStatus\":\"RUNNING\",\"message\":\"Hello\"}\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q; got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,66 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// reasonCodePatterns match an MQ reason code in the text of a message or insert, for example
+// "failed with reason code 2035" or "MQRC_NOT_AUTHORIZED (2035)"
+var reasonCodePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)reason code:?\s*'?(\d{4})\b`),
+	regexp.MustCompile(`MQRC_[A-Z0-9_]+\s*\(\s*(\d{4})\s*\)`),
+}
+
+// getReasonCodeEnabled returns true if an MQ reason code found in a message should be added as a field
+func getReasonCodeEnabled() bool {
+	enabled := os.Getenv("MQ_LOGGING_REASON_CODE")
+	return enabled == "true" || enabled == "1"
+}
+
+// findReasonCode returns the MQ reason code in a message, looking in the text of the message and then
+// in its comment inserts.  It returns false if there isn't a reason code.
+func findReasonCode(obj map[string]interface{}) (int, bool) {
+	texts := []string{newMQLogRecord(obj).Message()}
+	inserts := make([]string, 0)
+	for k := range obj {
+		if strings.HasPrefix(k, "ibm_commentInsert") {
+			inserts = append(inserts, k)
+		}
+	}
+	// Check the inserts in order, so that the result doesn't depend on map ordering
+	sortInsertNames(inserts)
+	for _, k := range inserts {
+		if s, ok := obj[k].(string); ok {
+			texts = append(texts, s)
+		}
+	}
+	for _, text := range texts {
+		for _, pattern := range reasonCodePatterns {
+			if m := pattern.FindStringSubmatch(text); m != nil {
+				// #nosec G104 - the pattern only matches digits
+				code, _ := strconv.Atoi(m[1])
+				return code, true
+			}
+		}
+	}
+	return 0, false
+}
+
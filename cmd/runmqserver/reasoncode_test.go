@@ -0,0 +1,58 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+)
+
+func TestReasonCode(t *testing.T) {
+	t.Setenv("MQ_LOGGING_REASON_CODE", "true")
+	opts, err := getMirrorOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		name     string
+		msg      string
+		expected string
+	}{
+		{
+			name:     "Message",
+			msg:      "{\"ibm_messageId\":\"AMQ9508E\",\"message\":\"AMQ9508E: The connection attempt to queue manager 'QM1' failed with reason code 2059.\"}",
+			expected: "{\"ibm_messageId\":\"AMQ9508E\",\"ibm_reasonCode\":2059,\"message\":\"AMQ9508E: The connection attempt to queue manager 'QM1' failed with reason code 2059.\"}\n",
+		},
+		{
+			name:     "Insert",
+			msg:      "{\"ibm_messageId\":\"AMQ9999E\",\"message\":\"AMQ9999E: Channel program ended abnormally.\",\"ibm_commentInsert1\":\"APP.SVRCONN\",\"ibm_commentInsert2\":\"MQRC_NOT_AUTHORIZED (2035)\"}",
+			expected: "{\"ibm_commentInsert1\":\"APP.SVRCONN\",\"ibm_commentInsert2\":\"MQRC_NOT_AUTHORIZED (2035)\",\"ibm_messageId\":\"AMQ9999E\",\"ibm_reasonCode\":2035,\"message\":\"AMQ9999E: Channel program ended abnormally.\"}\n",
+		},
+		{
+			name:     "None",
+			msg:      "{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: The queue manager task 'LOGGER-IO' has started.\"}",
+			expected: "{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: The queue manager task 'LOGGER-IO' has started.\"}\n",
+		},
+	}
+	for _, table := range tests {
+		t.Run(table.name, func(t *testing.T) {
+			buf := captureConsole(t)
+			newJSONMirrorFunc(opts)(table.msg, false)
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recordTimeFormats are the formats used for "ibm_datetime" by MQ and Liberty
+var recordTimeFormats = []string{
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05.000Z0700",
+	time.RFC3339Nano,
+}
+
+// MQLogRecord is a parsed JSON log message from MQ or Liberty, with typed access to the common fields
+type MQLogRecord struct {
+	fields map[string]interface{}
+}
+
+// newMQLogRecord wraps a parsed JSON log message.  The map is not copied.
+func newMQLogRecord(obj map[string]interface{}) MQLogRecord {
+	return MQLogRecord{fields: obj}
+}
+
+// Field returns the value of a string field, or an empty string if the field is missing or not a string
+func (r MQLogRecord) Field(key string) string {
+	s, _ := r.fields[key].(string)
+	return s
+}
+
+// DatetimeText returns the time the message was logged, as it should be displayed.  Some tools write
+// "ibm_datetime" as a number of milliseconds since the epoch, which is converted to an ISO-8601 UTC time.
+func (r MQLogRecord) DatetimeText() string {
+	if ms, ok := r.fields["ibm_datetime"].(float64); ok {
+		return time.UnixMilli(int64(ms)).UTC().Format(eventTimestampFormat)
+	}
+	return r.Field("ibm_datetime")
+}
+
+// Datetime returns the time the message was logged, or the zero time if it's missing or invalid
+func (r MQLogRecord) Datetime() time.Time {
+	s := r.DatetimeText()
+	for _, format := range recordTimeFormats {
+		t, err := time.Parse(format, s)
+		if err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// MessageID returns the MQ or Liberty message ID, for example "AMQ5051I"
+func (r MQLogRecord) MessageID() string {
+	return r.Field("ibm_messageId")
+}
+
+// Severity returns the canonical severity of the message
+func (r MQLogRecord) Severity() logLevel {
+	return normalizeSeverity(r.fields)
+}
+
+// Message returns the text of the message
+func (r MQLogRecord) Message() string {
+	return r.Field("message")
+}
+
+// defaultInsertPrefixes are the prefixes of the fields which MQ uses for message inserts
+var defaultInsertPrefixes = []string{"ibm_commentInsert", "ibm_arithInsert"}
+
+// getInsertPrefixes returns the prefixes of the fields which hold message inserts.  Any prefixes listed in
+// MQ_LOGGING_INSERT_PREFIXES are used as well as the defaults, for example for inserts added by exits.
+func getInsertPrefixes() []string {
+	prefixes := append([]string{}, defaultInsertPrefixes...)
+	for _, p := range strings.Split(os.Getenv("MQ_LOGGING_INSERT_PREFIXES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// insertName returns the name of an insert field, as used in the MQ "MessageDetail=Extended" format.
+// Any "ibm_" prefix is removed, and the first letter is made upper case, so that (for example)
+// "ibm_commentInsert1" becomes "CommentInsert1".
+func insertName(field string) string {
+	name := strings.TrimPrefix(field, "ibm_")
+	if name == "" {
+		return field
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// Inserts returns the message inserts, keyed by the names used in the MQ "MessageDetail=Extended"
+// format (for example "CommentInsert1").  Numeric inserts with a value of zero, such as unused
+// arithmetic inserts, are omitted.
+func (r MQLogRecord) Inserts() map[string]string {
+	prefixes := getInsertPrefixes()
+	inserts := make(map[string]string)
+	for k, v := range r.fields {
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+			if n, ok := v.(float64); !ok || n != 0 {
+				inserts[insertName(k)] = fmt.Sprint(v)
+			}
+			break
+		}
+	}
+	return inserts
+}
+
+// splitInsertName splits an insert name into its prefix and numeric index, for example
+// "CommentInsert10" into "CommentInsert" and 10.  The index is -1 if the name has no numeric suffix.
+func splitInsertName(name string) (string, int) {
+	i := len(name)
+	for i > 0 && name[i-1] >= '0' && name[i-1] <= '9' {
+		i--
+	}
+	n, err := strconv.Atoi(name[i:])
+	if err != nil {
+		return name, -1
+	}
+	return name[:i], n
+}
+
+// sortInsertNames sorts insert names by prefix, and then numerically by index, so that (for example)
+// "CommentInsert2" comes before "CommentInsert10".  Names without a numeric index are sorted by name.
+func sortInsertNames(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		pi, ni := splitInsertName(names[i])
+		pj, nj := splitInsertName(names[j])
+		if pi != pj {
+			return pi < pj
+		}
+		if ni != nj {
+			return ni < nj
+		}
+		return names[i] < names[j]
+	})
+}
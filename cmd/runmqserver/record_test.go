@@ -0,0 +1,159 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var mqLogRecordTests = []struct {
+	name      string
+	in        string
+	datetime  time.Time
+	messageID string
+	severity  logLevel
+	message   string
+	inserts   map[string]string
+}{
+	{
+		"MQ",
+		"{\"ibm_datetime\":\"2024-03-01T10:15:30.123Z\",\"ibm_messageId\":\"AMQ9209E\",\"loglevel\":\"ERROR\",\"message\":\"AMQ9209E: Connection closed.\",\"ibm_commentInsert1\":\"localhost\",\"ibm_arithInsert1\":2,\"ibm_arithInsert2\":0}",
+		time.Date(2024, 3, 1, 10, 15, 30, 123000000, time.UTC),
+		"AMQ9209E",
+		levelError,
+		"AMQ9209E: Connection closed.",
+		map[string]string{"CommentInsert1": "localhost", "ArithInsert1": "2"},
+	},
+	{
+		"Liberty",
+		"{\"ibm_datetime\":\"2024-03-01T10:15:30.123+0000\",\"ibm_messageId\":\"CWWKF0011I\",\"loglevel\":\"AUDIT\",\"message\":\"CWWKF0011I: The server is ready.\",\"type\":\"liberty_message\"}",
+		time.Date(2024, 3, 1, 10, 15, 30, 123000000, time.UTC),
+		"CWWKF0011I",
+		levelInfo,
+		"CWWKF0011I: The server is ready.",
+		map[string]string{},
+	},
+	{
+		"Epoch milliseconds",
+		"{\"ibm_datetime\":1709288130123,\"message\":\"Hello\"}",
+		time.Date(2024, 3, 1, 10, 15, 30, 123000000, time.UTC),
+		"",
+		levelInfo,
+		"Hello",
+		map[string]string{},
+	},
+	{
+		"Missing fields",
+		"{\"ibm_datetime\":true,\"message\":null}",
+		time.Time{},
+		"",
+		levelInfo,
+		"",
+		map[string]string{},
+	},
+}
+
+func TestMQLogRecord(t *testing.T) {
+	for _, table := range mqLogRecordTests {
+		t.Run(table.name, func(t *testing.T) {
+			obj, err := processLogMessage(table.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := newMQLogRecord(obj)
+			if !r.Datetime().Equal(table.datetime) {
+				t.Errorf("Datetime() - expected %v, got %v", table.datetime, r.Datetime())
+			}
+			if r.MessageID() != table.messageID {
+				t.Errorf("MessageID() - expected %v, got %v", table.messageID, r.MessageID())
+			}
+			if r.Severity() != table.severity {
+				t.Errorf("Severity() - expected %v, got %v", table.severity, r.Severity())
+			}
+			if r.Message() != table.message {
+				t.Errorf("Message() - expected %v, got %v", table.message, r.Message())
+			}
+			if !reflect.DeepEqual(r.Inserts(), table.inserts) {
+				t.Errorf("Inserts() - expected %v, got %v", table.inserts, r.Inserts())
+			}
+		})
+	}
+}
+
+func TestSortInsertNames(t *testing.T) {
+	names := make([]string, 0)
+	expected := make([]string, 0)
+	for i := 1; i <= 12; i++ {
+		expected = append(expected, fmt.Sprintf("CommentInsert%v", i))
+	}
+	for i := 12; i >= 1; i-- {
+		names = append(names, fmt.Sprintf("CommentInsert%v", i))
+	}
+	names = append(names, "ArithInsert2", "ArithInsert10", "CommentInsertX")
+	expected = append([]string{"ArithInsert2", "ArithInsert10"}, expected...)
+	expected = append(expected, "CommentInsertX")
+	sortInsertNames(names)
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v; got %v", expected, names)
+	}
+}
+
+func TestFormatBasicInsertOrder(t *testing.T) {
+	obj := map[string]interface{}{"ibm_datetime": "2024-01-01T10:00:00.000Z", "message": "Hello"}
+	parts := make([]string, 0)
+	for i := 1; i <= 12; i++ {
+		obj[fmt.Sprintf("ibm_commentInsert%v", i)] = fmt.Sprint(i)
+		parts = append(parts, fmt.Sprintf("CommentInsert%v(%v)", i, i))
+	}
+	expected := "2024-01-01T10:00:00.000Z Hello [" + strings.Join(parts, ", ") + "]\n"
+	if out := formatBasic(obj); out != expected {
+		t.Errorf("Expected %q; got %q", expected, out)
+	}
+}
+
+func TestFormatBasicEpochMillis(t *testing.T) {
+	obj, err := processLogMessage("{\"ibm_datetime\":1709288130123,\"message\":\"AMQ5051I: Started\"}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "2024-03-01T10:15:30.123Z AMQ5051I: Started\n"
+	line := formatBasic(obj)
+	if line != expected {
+		t.Errorf("Expected %q; got %q", expected, line)
+	}
+}
+
+func TestFormatBasicCustomInsertPrefix(t *testing.T) {
+	t.Setenv("MQ_LOGGING_INSERT_PREFIXES", "exitInsert, ibm_exitCode")
+	obj := map[string]interface{}{
+		"ibm_datetime":       "2024-01-01T10:00:00.000Z",
+		"message":            "AMQ9999E: Channel ended",
+		"ibm_commentInsert1": "TO.QM2",
+		"ibm_arithInsert1":   float64(0),
+		"exitInsert1":        "SECEXIT",
+		"exitInsert2":        float64(0),
+		"ibm_exitCode":       float64(2),
+		"other":              "ignored",
+	}
+	expected := "2024-01-01T10:00:00.000Z AMQ9999E: Channel ended [CommentInsert1(TO.QM2), ExitCode(2), ExitInsert1(SECEXIT)]\n"
+	if out := formatBasic(obj); out != expected {
+		t.Errorf("Expected %q; got %q", expected, out)
+	}
+}
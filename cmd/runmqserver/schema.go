@@ -0,0 +1,363 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// schemaViolations counts the mirrored log records which didn't conform to the schema
+var schemaViolations uint64
+
+// recordSchema is a JSON Schema, used to check each mirrored log record.  Only the keywords
+// commonly used to describe a flat log record are supported: type, enum, const, required,
+// properties, additionalProperties, items, minimum, maximum, minLength, maxLength and pattern.
+type recordSchema struct {
+	types                []string
+	enum                 []interface{}
+	constValue           interface{}
+	hasConst             bool
+	required             []string
+	properties           map[string]*recordSchema
+	additionalProperties *recordSchema
+	noAdditional         bool
+	items                *recordSchema
+	minimum              *float64
+	maximum              *float64
+	minLength            *int
+	maxLength            *int
+	pattern              *regexp.Regexp
+}
+
+// unsupportedSchemaKeywords are the JSON Schema keywords which would change whether a record conforms,
+// but which aren't supported.  They are rejected, rather than ignored, so that a schema never accepts
+// records which it was written to reject.
+var unsupportedSchemaKeywords = map[string]bool{
+	"$ref": true, "$dynamicRef": true, "$recursiveRef": true,
+	"allOf": true, "anyOf": true, "oneOf": true, "not": true, "if": true, "then": true, "else": true,
+	"format": true, "multipleOf": true, "exclusiveMinimum": true, "exclusiveMaximum": true,
+	"patternProperties": true, "propertyNames": true, "minProperties": true, "maxProperties": true,
+	"dependencies": true, "dependentRequired": true, "dependentSchemas": true, "unevaluatedProperties": true,
+	"prefixItems": true, "contains": true, "minContains": true, "maxContains": true, "minItems": true,
+	"maxItems": true, "uniqueItems": true, "additionalItems": true, "unevaluatedItems": true,
+}
+
+// getRecordSchema reads the JSON Schema from the file named by MQ_LOGGING_JSON_SCHEMA, or returns nil if
+// it isn't set
+func getRecordSchema() (*recordSchema, error) {
+	path := strings.TrimSpace(os.Getenv("MQ_LOGGING_JSON_SCHEMA"))
+	if path == "" {
+		return nil, nil
+	}
+	// #nosec G304 - the path is set by the administrator of the container
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for MQ_LOGGING_JSON_SCHEMA: %v", err)
+	}
+	var raw interface{}
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for MQ_LOGGING_JSON_SCHEMA: %v is not valid JSON: %v", path, err)
+	}
+	s, err := parseRecordSchema(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for MQ_LOGGING_JSON_SCHEMA: %v", err)
+	}
+	return s, nil
+}
+
+// parseRecordSchema converts a decoded JSON Schema into a recordSchema
+func parseRecordSchema(raw interface{}) (*recordSchema, error) {
+	s := &recordSchema{}
+	if b, ok := raw.(bool); ok {
+		// A schema of false matches nothing, and true matches anything
+		if !b {
+			s.enum = []interface{}{}
+		}
+		return s, nil
+	}
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema must be an object, not %v", raw)
+	}
+	unsupported := make([]string, 0)
+	for keyword := range obj {
+		if unsupportedSchemaKeywords[keyword] {
+			unsupported = append(unsupported, keyword)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return nil, fmt.Errorf("unsupported keywords %v; only type, enum, const, required, properties, additionalProperties, items, minimum, maximum, minLength, maxLength and pattern are supported", strings.Join(unsupported, ", "))
+	}
+	switch t := obj["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("type must be a string or an array of strings")
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("type must be a string or an array of strings")
+	}
+	if e, ok := obj["enum"]; ok {
+		s.enum, ok = e.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("enum must be an array")
+		}
+	}
+	s.constValue, s.hasConst = obj["const"]
+	if r, ok := obj["required"]; ok {
+		names, ok := r.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("required must be an array of strings")
+		}
+		for _, v := range names {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("required must be an array of strings")
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	if p, ok := obj["properties"]; ok {
+		props, ok := p.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("properties must be an object")
+		}
+		s.properties = make(map[string]*recordSchema, len(props))
+		for name, v := range props {
+			ps, err := parseRecordSchema(v)
+			if err != nil {
+				return nil, fmt.Errorf("property %v: %v", name, err)
+			}
+			s.properties[name] = ps
+		}
+	}
+	if a, ok := obj["additionalProperties"]; ok {
+		if b, ok := a.(bool); ok {
+			s.noAdditional = !b
+		} else {
+			as, err := parseRecordSchema(a)
+			if err != nil {
+				return nil, fmt.Errorf("additionalProperties: %v", err)
+			}
+			s.additionalProperties = as
+		}
+	}
+	if i, ok := obj["items"]; ok {
+		is, err := parseRecordSchema(i)
+		if err != nil {
+			return nil, fmt.Errorf("items: %v", err)
+		}
+		s.items = is
+	}
+	var err error
+	s.minimum, err = schemaNumber(obj, "minimum")
+	if err != nil {
+		return nil, err
+	}
+	s.maximum, err = schemaNumber(obj, "maximum")
+	if err != nil {
+		return nil, err
+	}
+	s.minLength, err = schemaLength(obj, "minLength")
+	if err != nil {
+		return nil, err
+	}
+	s.maxLength, err = schemaLength(obj, "maxLength")
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := obj["pattern"]; ok {
+		text, ok := p.(string)
+		if !ok {
+			return nil, fmt.Errorf("pattern must be a string")
+		}
+		s.pattern, err = regexp.Compile(text)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %v is not valid: %v", text, err)
+		}
+	}
+	return s, nil
+}
+
+// schemaNumber returns the value of a numeric keyword, or nil if it isn't present
+func schemaNumber(obj map[string]interface{}, keyword string) (*float64, error) {
+	v, ok := obj[keyword]
+	if !ok {
+		return nil, nil
+	}
+	n, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%v must be a number", keyword)
+	}
+	return &n, nil
+}
+
+// schemaLength returns the value of a length keyword, or nil if it isn't present
+func schemaLength(obj map[string]interface{}, keyword string) (*int, error) {
+	n, err := schemaNumber(obj, keyword)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	if *n < 0 || *n != float64(int(*n)) {
+		return nil, fmt.Errorf("%v must be a non-negative integer", keyword)
+	}
+	l := int(*n)
+	return &l, nil
+}
+
+// schemaTypeOf returns the JSON Schema type name of a decoded JSON value
+func schemaTypeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if t == float64(int64(t)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// validate returns an error describing the first way in which a decoded JSON value doesn't conform
+// to the schema.  The path names the value, for the error message.
+func (s *recordSchema) validate(v interface{}, path string) error {
+	if len(s.types) > 0 {
+		actual := schemaTypeOf(v)
+		matched := false
+		for _, t := range s.types {
+			if t == actual || (t == "number" && actual == "integer") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%v: expected %v, but found %v", path, strings.Join(s.types, " or "), actual)
+		}
+	}
+	if s.enum != nil {
+		matched := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(e, v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%v: value %v is not one of the allowed values", path, v)
+		}
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constValue, v) {
+		return fmt.Errorf("%v: value %v is not %v", path, v, s.constValue)
+	}
+	switch t := v.(type) {
+	case float64:
+		if s.minimum != nil && t < *s.minimum {
+			return fmt.Errorf("%v: value %v is less than %v", path, t, *s.minimum)
+		}
+		if s.maximum != nil && t > *s.maximum {
+			return fmt.Errorf("%v: value %v is greater than %v", path, t, *s.maximum)
+		}
+	case string:
+		length := utf8.RuneCountInString(t)
+		if s.minLength != nil && length < *s.minLength {
+			return fmt.Errorf("%v: length %v is less than %v", path, length, *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			return fmt.Errorf("%v: length %v is greater than %v", path, length, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(t) {
+			return fmt.Errorf("%v: value %q does not match pattern %v", path, t, s.pattern)
+		}
+	case []interface{}:
+		if s.items != nil {
+			for i, item := range t {
+				err := s.items.validate(item, fmt.Sprintf("%v[%v]", path, i))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := t[name]; !ok {
+				return fmt.Errorf("%v: missing required field %v", path, name)
+			}
+		}
+		// Check the fields in a consistent order, so that the same error is always reported
+		names := make([]string, 0, len(t))
+		for name := range t {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			ps, ok := s.properties[name]
+			if !ok {
+				if s.noAdditional {
+					return fmt.Errorf("%v: unexpected field %v", path, name)
+				}
+				ps = s.additionalProperties
+			}
+			if ps != nil {
+				err := ps.validate(t[name], path+"."+name)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// conformsToSchema returns true if a mirrored JSON log line conforms to the schema.  A line which
+// doesn't is written to the runmqserver log instead, as a diagnostic, and counted.
+func conformsToSchema(s *recordSchema, line string) bool {
+	var v interface{}
+	err := json.Unmarshal([]byte(line), &v)
+	if err == nil {
+		err = s.validate(v, "$")
+	}
+	if err == nil {
+		return true
+	}
+	count := atomic.AddUint64(&schemaViolations, 1)
+	log.Printf("Log record %v does not conform to MQ_LOGGING_JSON_SCHEMA: %v - %v", count, err, line)
+	return false
+}
@@ -0,0 +1,123 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testRecordSchema = `{
+	"type": "object",
+	"required": ["ibm_messageId", "message"],
+	"properties": {
+		"ibm_messageId": {"type": "string", "pattern": "^AMQ[0-9]{4}[IWE]$"},
+		"loglevel": {"enum": ["INFO", "WARNING", "ERROR"]},
+		"message": {"type": "string", "minLength": 1}
+	},
+	"additionalProperties": false
+}`
+
+func TestSchemaValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(path, []byte(testRecordSchema), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MQ_LOGGING_JSON_SCHEMA", path)
+	opts, err := getMirrorOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		name      string
+		msg       string
+		conforms  bool
+		violation string
+	}{
+		{"Conforming", "{\"ibm_messageId\":\"AMQ5051I\",\"loglevel\":\"INFO\",\"message\":\"AMQ5051I: Started\"}", true, ""},
+		{"Missing field", "{\"ibm_messageId\":\"AMQ5051I\",\"loglevel\":\"INFO\"}", false, "missing required field message"},
+		{"Wrong type", "{\"ibm_messageId\":5051,\"message\":\"AMQ5051I: Started\"}", false, "$.ibm_messageId: expected string, but found integer"},
+		{"Pattern", "{\"ibm_messageId\":\"CWWKF0011I\",\"message\":\"Ready\"}", false, "does not match pattern"},
+		{"Enum", "{\"ibm_messageId\":\"AMQ5051I\",\"loglevel\":\"DEBUG\",\"message\":\"AMQ5051I: Started\"}", false, "not one of the allowed values"},
+		{"Additional field", "{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"AMQ5051I: Started\",\"host\":\"mq1\"}", false, "unexpected field host"},
+		{"Wrapped line", "Not a JSON message", false, "missing required field ibm_messageId"},
+	}
+	for _, table := range tests {
+		t.Run(table.name, func(t *testing.T) {
+			before := atomic.LoadUint64(&schemaViolations)
+			logBuf := captureLog(t)
+			buf := captureConsole(t)
+			mirrored := newJSONMirrorFunc(opts)(table.msg, false)
+			if mirrored != table.conforms {
+				t.Errorf("Expected mirrored=%v; got %v", table.conforms, mirrored)
+			}
+			violations := atomic.LoadUint64(&schemaViolations) - before
+			if table.conforms {
+				if buf.String() != table.msg+"\n" {
+					t.Errorf("Expected %q; got %q", table.msg+"\n", buf.String())
+				}
+				if violations != 0 {
+					t.Errorf("Expected no violations; got %v", violations)
+				}
+				return
+			}
+			if buf.Len() != 0 {
+				t.Errorf("Expected nothing to be mirrored; got %q", buf.String())
+			}
+			if violations != 1 {
+				t.Errorf("Expected 1 violation; got %v", violations)
+			}
+			if !strings.Contains(logBuf.String(), table.violation) {
+				t.Errorf("Expected diagnostic to contain %q; got %q", table.violation, logBuf.String())
+			}
+		})
+	}
+}
+
+func TestGetRecordSchemaInvalid(t *testing.T) {
+	var tests = []struct {
+		name   string
+		schema string
+	}{
+		{"Not JSON", "{"},
+		{"Not an object", "[]"},
+		{"Bad type", "{\"type\": 1}"},
+		{"Bad pattern", "{\"properties\": {\"message\": {\"pattern\": \"(\"}}}"},
+		{"Bad length", "{\"maxLength\": -1}"},
+		{"Reference", "{\"$ref\": \"#/definitions/record\"}"},
+		{"Combinator", "{\"properties\": {\"loglevel\": {\"oneOf\": [{\"const\": \"INFO\"}]}}}"},
+		{"Format", "{\"properties\": {\"ibm_datetime\": {\"format\": \"date-time\"}}}"},
+		{"Pattern properties", "{\"patternProperties\": {\"^ibm_\": {\"type\": \"string\"}}}"},
+	}
+	for _, table := range tests {
+		t.Run(table.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schema.json")
+			err := os.WriteFile(path, []byte(table.schema), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("MQ_LOGGING_JSON_SCHEMA", path)
+			_, err = getRecordSchema()
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
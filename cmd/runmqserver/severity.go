@@ -0,0 +1,186 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// logLevel is the canonical, ordered severity of a log message.  MQ and Liberty describe severity
+// in different ways, so all severity-aware processing should use normalizeSeverity to get a logLevel.
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarning
+	levelError
+	levelFatal
+)
+
+func (l logLevel) String() string {
+	switch l {
+	case levelDebug:
+		return "debug"
+	case levelInfo:
+		return "info"
+	case levelWarning:
+		return "warning"
+	case levelError:
+		return "error"
+	case levelFatal:
+		return "fatal"
+	}
+	return fmt.Sprintf("logLevel(%d)", int(l))
+}
+
+// mqSeverity returns the single letter used for a severity in the "severity" field of MQ messages.
+// MQ doesn't have a debug severity, so debug messages are informational.
+func (l logLevel) mqSeverity() string {
+	switch l {
+	case levelWarning:
+		return "W"
+	case levelError:
+		return "E"
+	case levelFatal:
+		return "T"
+	}
+	return "I"
+}
+
+// parseLogLevel parses the name of a log level, as used in environment variables
+func parseLogLevel(s string) (logLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug", "all":
+		return levelDebug, nil
+	case "info":
+		return levelInfo, nil
+	case "warning", "warn":
+		return levelWarning, nil
+	case "error":
+		return levelError, nil
+	case "fatal":
+		return levelFatal, nil
+	}
+	return levelDebug, fmt.Errorf("invalid log level: %v", s)
+}
+
+// getConsoleLogLevel returns the minimum level of message to mirror, from MQ_LOGGING_CONSOLE_LOG_LEVEL.
+// All messages are mirrored by default.
+func getConsoleLogLevel() (logLevel, error) {
+	l := os.Getenv("MQ_LOGGING_CONSOLE_LOG_LEVEL")
+	if strings.TrimSpace(l) == "" {
+		return levelDebug, nil
+	}
+	level, err := parseLogLevel(l)
+	if err != nil {
+		return levelDebug, fmt.Errorf("invalid value for MQ_LOGGING_CONSOLE_LOG_LEVEL: %v", err)
+	}
+	return level, nil
+}
+
+// severityFromName maps a severity or level name used by MQ or Liberty onto a logLevel
+func severityFromName(name string) (logLevel, bool) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "FATAL", "TERMINATION":
+		return levelFatal, true
+	case "ERROR", "SEVERE", "E", "S":
+		return levelError, true
+	case "WARNING", "WARN", "W":
+		return levelWarning, true
+	case "INFO", "AUDIT", "I":
+		return levelInfo, true
+	case "DEBUG", "FINE", "FINER", "FINEST", "ENTRY", "EXIT", "EVENT":
+		return levelDebug, true
+	}
+	return levelInfo, false
+}
+
+// normalizeSeverity returns the canonical severity of a parsed JSON log message.  MQ messages
+// may have a "severity" field, and both MQ and Liberty use "loglevel".  If neither is present,
+// the severity is taken from the last character of an MQ message ID (e.g. AMQ9999E).  Messages
+// with a missing or unknown severity are treated as informational.
+func normalizeSeverity(obj map[string]interface{}) logLevel {
+	for _, field := range []string{"severity", "loglevel"} {
+		if name, ok := obj[field].(string); ok {
+			if level, ok := severityFromName(name); ok {
+				return level
+			}
+		}
+	}
+	if id, ok := obj["ibm_messageId"].(string); ok && len(id) > 1 {
+		switch id[len(id)-1] {
+		case 'T':
+			return levelFatal
+		case 'E', 'S':
+			return levelError
+		case 'W':
+			return levelWarning
+		}
+	}
+	return levelInfo
+}
+
+// severityNumberScale is the numbering used for the numeric severity added to JSON log messages
+type severityNumberScale string
+
+const (
+	severityNumberNone severityNumberScale = ""
+	// severityNumberSyslog uses the syslog severities, from 0 (emergency) to 7 (debug)
+	severityNumberSyslog severityNumberScale = "syslog"
+	// severityNumberOTel uses the OpenTelemetry SeverityNumber, from 1 (trace) to 24 (fatal)
+	severityNumberOTel severityNumberScale = "otel"
+)
+
+// getSeverityNumberScale returns the numbering to use for the numeric severity, from MQ_LOGGING_SEVERITY_NUMBER
+func getSeverityNumberScale() (severityNumberScale, error) {
+	scale := severityNumberScale(strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_SEVERITY_NUMBER"))))
+	switch scale {
+	case severityNumberNone, severityNumberSyslog, severityNumberOTel:
+		return scale, nil
+	}
+	return severityNumberNone, fmt.Errorf("invalid value for MQ_LOGGING_SEVERITY_NUMBER: %v", scale)
+}
+
+// severityNumber returns the number for a log level, on the given scale
+func severityNumber(level logLevel, scale severityNumberScale) int {
+	if scale == severityNumberSyslog {
+		switch level {
+		case levelDebug:
+			return 7
+		case levelWarning:
+			return 4
+		case levelError:
+			return 3
+		case levelFatal:
+			return 2
+		}
+		return 6
+	}
+	switch level {
+	case levelDebug:
+		return 5
+	case levelWarning:
+		return 13
+	case levelError:
+		return 17
+	case levelFatal:
+		return 21
+	}
+	return 9
+}
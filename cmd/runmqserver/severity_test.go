@@ -0,0 +1,139 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+var normalizeSeverityTests = []struct {
+	obj      map[string]interface{}
+	expected logLevel
+}{
+	// MQ messages
+	{map[string]interface{}{"ibm_messageId": "AMQ5051I", "loglevel": "INFO"}, levelInfo},
+	{map[string]interface{}{"ibm_messageId": "AMQ9999E", "loglevel": "ERROR"}, levelError},
+	{map[string]interface{}{"ibm_messageId": "AMQ7234W", "severity": "W"}, levelWarning},
+	{map[string]interface{}{"ibm_messageId": "AMQ6119S"}, levelError},
+	{map[string]interface{}{"ibm_messageId": "AMQ8004T"}, levelFatal},
+	{map[string]interface{}{"ibm_messageId": "AMQ5051I"}, levelInfo},
+	// Liberty messages
+	{map[string]interface{}{"type": "liberty_message", "loglevel": "AUDIT"}, levelInfo},
+	{map[string]interface{}{"type": "liberty_message", "loglevel": "WARNING"}, levelWarning},
+	{map[string]interface{}{"type": "liberty_message", "loglevel": "SEVERE"}, levelError},
+	{map[string]interface{}{"type": "liberty_message", "loglevel": "FATAL"}, levelFatal},
+	{map[string]interface{}{"type": "liberty_trace", "loglevel": "FINEST"}, levelDebug},
+	{map[string]interface{}{"type": "liberty_trace", "loglevel": "ENTRY"}, levelDebug},
+	// Missing or unknown severity
+	{map[string]interface{}{}, levelInfo},
+	{map[string]interface{}{"loglevel": 3}, levelInfo},
+	{map[string]interface{}{"loglevel": "UNKNOWN"}, levelInfo},
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	for _, table := range normalizeSeverityTests {
+		level := normalizeSeverity(table.obj)
+		if level != table.expected {
+			t.Errorf("normalizeSeverity(%v) - expected %v, got %v", table.obj, table.expected, level)
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for s, expected := range map[string]logLevel{"all": levelDebug, "INFO": levelInfo, " warning ": levelWarning, "error": levelError, "fatal": levelFatal} {
+		level, err := parseLogLevel(s)
+		if err != nil || level != expected {
+			t.Errorf("parseLogLevel(%q) - expected %v, got %v (%v)", s, expected, level, err)
+		}
+	}
+	_, err := parseLogLevel("verbose")
+	if err == nil {
+		t.Errorf("Expected error parsing invalid log level")
+	}
+}
+
+func TestSeverityNumber(t *testing.T) {
+	var tests = []struct {
+		loglevel string
+		syslog   int
+		otel     int
+	}{
+		{"FINE", 7, 5},
+		{"INFO", 6, 9},
+		{"AUDIT", 6, 9},
+		{"WARNING", 4, 13},
+		{"ERROR", 3, 17},
+		{"SEVERE", 3, 17},
+		{"FATAL", 2, 21},
+	}
+	for _, table := range tests {
+		t.Run(table.loglevel, func(t *testing.T) {
+			for scale, expected := range map[string]int{"syslog": table.syslog, "otel": table.otel} {
+				t.Setenv("MQ_LOGGING_SEVERITY_NUMBER", scale)
+				opts, err := getMirrorOptions()
+				if err != nil {
+					t.Fatal(err)
+				}
+				obj := map[string]interface{}{"loglevel": table.loglevel, "message": "A"}
+				line := addJSONFields(obj, "{}", opts)
+				var out map[string]interface{}
+				err = json.Unmarshal([]byte(line), &out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if out["ibm_severityNumber"] != float64(expected) {
+					t.Errorf("Expected %v severity number %v; got %v", scale, expected, out["ibm_severityNumber"])
+				}
+			}
+		})
+	}
+}
+
+func TestConsoleLogLevel(t *testing.T) {
+	t.Setenv("MQ_LOGGING_CONSOLE_LOG_LEVEL", "warning")
+	opts, err := getMirrorOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		msg      string
+		mirrored bool
+	}{
+		{"{\"ibm_messageId\":\"AMQ5975I\",\"loglevel\":\"INFO\",\"message\":\"AMQ5975I: Starting\"}", false},
+		{"{\"ibm_messageId\":\"AMQ7234W\",\"loglevel\":\"WARNING\",\"message\":\"AMQ7234W: Warning\"}", true},
+		{"{\"ibm_messageId\":\"AMQ6119S\",\"message\":\"AMQ6119S: Error\"}", true},
+		{"{\"loglevel\":42,\"message\":\"Unknown severity\"}", false},
+		{"{\"message\":\"No severity\"}", false},
+		{"Not JSON", true},
+	}
+	for _, format := range []string{"json", "basic"} {
+		for i, table := range tests {
+			t.Run(fmt.Sprintf("%v-%v", format, i), func(t *testing.T) {
+				buf := captureConsole(t)
+				mirrored := newMirrorFunc(format, opts)(table.msg, true)
+				if mirrored != table.mirrored || (buf.Len() > 0) != table.mirrored {
+					t.Errorf("Expected mirrored to be %v for %v; got %v with %q", table.mirrored, table.msg, mirrored, buf.String())
+				}
+			})
+		}
+	}
+	t.Setenv("MQ_LOGGING_CONSOLE_LOG_LEVEL", "verbose")
+	if _, err := getMirrorOptions(); err == nil {
+		t.Error("Expected an error for an invalid level")
+	}
+}
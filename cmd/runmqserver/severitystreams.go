@@ -0,0 +1,104 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// severityStreams holds the writers for log messages of each severity which are sent to their own file
+// descriptor, instead of the console.  It is empty unless MQ_LOGGING_SEVERITY_FDS is set.
+var severityStreams = map[logLevel]*consoleWriter{}
+
+// severityFDFiles holds the files opened for each file descriptor.  The files are kept open for the life
+// of the process, because an *os.File closes its descriptor when it is garbage collected.
+var severityFDFiles = struct {
+	sync.Mutex
+	files map[int]*os.File
+}{files: make(map[int]*os.File)}
+
+// openSeverityFD returns a file for an inherited file descriptor, checking that it is open
+func openSeverityFD(fd int) (*os.File, error) {
+	severityFDFiles.Lock()
+	defer severityFDFiles.Unlock()
+	if f, ok := severityFDFiles.files[fd]; ok {
+		return f, nil
+	}
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+	if f == nil {
+		return nil, fmt.Errorf("file descriptor %v is not valid", fd)
+	}
+	_, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("file descriptor %v is not open: %v", fd, err)
+	}
+	severityFDFiles.files[fd] = f
+	return f, nil
+}
+
+// getSeverityStreams reads the mapping of severities to file descriptors from MQ_LOGGING_SEVERITY_FDS,
+// for example "error=3,warning=4", and checks that each file descriptor is open
+func getSeverityStreams() (map[logLevel]*consoleWriter, error) {
+	streams := make(map[logLevel]*consoleWriter)
+	value := strings.TrimSpace(os.Getenv("MQ_LOGGING_SEVERITY_FDS"))
+	if value == "" {
+		return streams, nil
+	}
+	writers := make(map[int]*consoleWriter)
+	for _, token := range strings.Split(value, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid value for MQ_LOGGING_SEVERITY_FDS: %v", token)
+		}
+		level, err := parseLogLevel(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for MQ_LOGGING_SEVERITY_FDS: %v", err)
+		}
+		fd, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || fd < 0 {
+			return nil, fmt.Errorf("invalid value for MQ_LOGGING_SEVERITY_FDS: %v is not a file descriptor", parts[1])
+		}
+		// Several severities can share a file descriptor, so share the writer, to keep lines whole
+		w, ok := writers[fd]
+		if !ok {
+			f, err := openSeverityFD(fd)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for MQ_LOGGING_SEVERITY_FDS: %v", err)
+			}
+			w = newConsoleWriter(f, 0, nil)
+			writers[fd] = w
+		}
+		streams[level] = w
+	}
+	return streams, nil
+}
+
+// severityStream returns the writer for a log message, if its severity is sent to its own file
+// descriptor, or nil if it should be written to the console
+func severityStream(obj map[string]interface{}) *consoleWriter {
+	if len(severityStreams) == 0 || obj == nil {
+		return nil
+	}
+	return severityStreams[newMQLogRecord(obj).Severity()]
+}
@@ -0,0 +1,119 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// newSeverityPipe returns a new file descriptor for the write end of a pipe, and the read end.  The
+// descriptor is a duplicate, owned by the file opened for it by getSeverityStreams.
+func newSeverityPipe(t *testing.T) (int, *os.File) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := syscall.Dup(int(w.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	t.Cleanup(func() {
+		severityFDFiles.Lock()
+		if f, ok := severityFDFiles.files[fd]; ok {
+			f.Close()
+			delete(severityFDFiles.files, fd)
+		}
+		severityFDFiles.Unlock()
+		r.Close()
+	})
+	return fd, r
+}
+
+func TestSeverityStreams(t *testing.T) {
+	errorFD, errorPipe := newSeverityPipe(t)
+	warningFD, warningPipe := newSeverityPipe(t)
+	t.Setenv("MQ_LOGGING_SEVERITY_FDS", fmt.Sprintf("error=%v,fatal=%v,warning=%v", errorFD, errorFD, warningFD))
+	streams, err := getSeverityStreams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStreams := severityStreams
+	severityStreams = streams
+	t.Cleanup(func() { severityStreams = oldStreams })
+	buf := captureConsole(t)
+
+	mf := newJSONMirrorFunc(mirrorOptions{})
+	mf("{\"loglevel\":\"ERROR\",\"message\":\"E1\"}", false)
+	mf("{\"loglevel\":\"INFO\",\"message\":\"I1\"}", false)
+	mf("{\"loglevel\":\"WARNING\",\"message\":\"W1\"}", false)
+	mf("{\"loglevel\":\"FATAL\",\"message\":\"F1\"}", false)
+	mf("Not JSON", false)
+
+	// Close the write ends, so that the pipes can be read to the end
+	severityFDFiles.Lock()
+	severityFDFiles.files[errorFD].Close()
+	severityFDFiles.files[warningFD].Close()
+	delete(severityFDFiles.files, errorFD)
+	delete(severityFDFiles.files, warningFD)
+	severityFDFiles.Unlock()
+
+	var tests = []struct {
+		name     string
+		r        io.Reader
+		expected string
+	}{
+		{"error", errorPipe, "{\"loglevel\":\"ERROR\",\"message\":\"E1\"}\n{\"loglevel\":\"FATAL\",\"message\":\"F1\"}\n"},
+		{"warning", warningPipe, "{\"loglevel\":\"WARNING\",\"message\":\"W1\"}\n"},
+	}
+	for _, table := range tests {
+		b, err := io.ReadAll(table.r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != table.expected {
+			t.Errorf("Expected %v stream to have %q; got %q", table.name, table.expected, string(b))
+		}
+	}
+	expected := "{\"loglevel\":\"INFO\",\"message\":\"I1\"}\n{\"message\":\"Not JSON\"}\n"
+	if buf.String() != expected {
+		t.Errorf("Expected console to have %q; got %q", expected, buf.String())
+	}
+}
+
+func TestSeverityStreamsInvalid(t *testing.T) {
+	var tests = []string{
+		"error",
+		"loud=3",
+		"error=x",
+		"error=-1",
+		// A file descriptor which isn't open
+		"error=987",
+	}
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_SEVERITY_FDS", value)
+			_, err := getSeverityStreams()
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
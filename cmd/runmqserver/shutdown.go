@@ -0,0 +1,90 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// shutdownMode controls what happens to log messages once the queue manager is shutting down
+type shutdownMode string
+
+const (
+	// shutdownModeNone mirrors messages as normal
+	shutdownModeNone shutdownMode = ""
+	// shutdownModeTag adds an ibm_shuttingDown field to messages
+	shutdownModeTag shutdownMode = "tag"
+	// shutdownModeSuppress doesn't mirror messages
+	shutdownModeSuppress shutdownMode = "suppress"
+)
+
+// shuttingDown is set to 1 once a message indicating that the queue manager is shutting down has been mirrored
+var shuttingDown int32
+
+// markShuttingDown records that the queue manager is shutting down
+func markShuttingDown() {
+	atomic.StoreInt32(&shuttingDown, 1)
+}
+
+// isShuttingDown returns true if the queue manager is shutting down
+func isShuttingDown() bool {
+	return atomic.LoadInt32(&shuttingDown) == 1
+}
+
+// getShutdownOptions returns the message IDs in MQ_LOGGING_SHUTDOWN_ID, which indicate the queue
+// manager is shutting down, and what MQ_LOGGING_SHUTDOWN_MODE says to do with later messages.
+// Messages are tagged by default, if any message IDs are set.
+func getShutdownOptions() ([]string, shutdownMode, error) {
+	ids := make([]string, 0)
+	for _, id := range strings.Split(strings.ToUpper(os.Getenv("MQ_LOGGING_SHUTDOWN_ID")), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	mode := shutdownMode(strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_SHUTDOWN_MODE"))))
+	switch mode {
+	case shutdownModeNone:
+		mode = shutdownModeTag
+	case shutdownModeTag, shutdownModeSuppress:
+	default:
+		return nil, shutdownModeNone, fmt.Errorf("invalid value for MQ_LOGGING_SHUTDOWN_MODE: %v", mode)
+	}
+	if len(ids) == 0 {
+		return ids, shutdownModeNone, nil
+	}
+	return ids, mode, nil
+}
+
+// isShutdownMessage returns true if the log message has one of the shutdown message IDs
+func isShutdownMessage(obj map[string]interface{}, shutdownIDs []string) bool {
+	id := newMQLogRecord(obj).MessageID()
+	for _, shutdown := range shutdownIDs {
+		if id != "" && id == shutdown {
+			return true
+		}
+	}
+	return false
+}
+
+// isShutdownAffected returns true if a log message is affected by the shutdown mode.  Only messages
+// logged after shutdown has started are affected, and errors are always mirrored as normal.
+func isShutdownAffected(obj map[string]interface{}, mode shutdownMode) bool {
+	return mode != shutdownModeNone && isShuttingDown() && newMQLogRecord(obj).Severity() < levelError
+}
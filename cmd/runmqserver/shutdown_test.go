@@ -0,0 +1,94 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+)
+
+var shutdownTests = []struct {
+	format   string
+	mode     string
+	expected string
+}{
+	{
+		"json", "tag",
+		"{\"ibm_messageId\":\"AMQ7001I\",\"message\":\"Before\"}\n" +
+			"{\"ibm_messageId\":\"AMQ8004I\",\"message\":\"Ending\"}\n" +
+			"{\"ibm_messageId\":\"AMQ7002I\",\"ibm_shuttingDown\":true,\"message\":\"After\"}\n" +
+			"{\"ibm_messageId\":\"AMQ7003E\",\"message\":\"Error\"}\n",
+	},
+	{
+		"json", "suppress",
+		"{\"ibm_messageId\":\"AMQ7001I\",\"message\":\"Before\"}\n" +
+			"{\"ibm_messageId\":\"AMQ8004I\",\"message\":\"Ending\"}\n" +
+			"{\"ibm_messageId\":\"AMQ7003E\",\"message\":\"Error\"}\n",
+	},
+	{
+		"basic", "tag",
+		" Before\n" +
+			" Ending\n" +
+			" After [ibm_shuttingDown=true]\n" +
+			" Error\n",
+	},
+}
+
+func TestShutdownMessages(t *testing.T) {
+	oldLog := log
+	defer func() {
+		log = oldLog
+		shuttingDown = 0
+	}()
+	for _, table := range shutdownTests {
+		t.Run(table.format+"/"+table.mode, func(t *testing.T) {
+			shuttingDown = 0
+			t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", table.format)
+			t.Setenv("MQ_LOGGING_SHUTDOWN_ID", "AMQ8004I")
+			t.Setenv("MQ_LOGGING_SHUTDOWN_MODE", table.mode)
+			mf, err := configureLogger("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := captureConsole(t)
+			for _, msg := range []string{
+				"{\"ibm_messageId\":\"AMQ7001I\",\"message\":\"Before\"}",
+				"{\"ibm_messageId\":\"AMQ8004I\",\"message\":\"Ending\"}",
+				"{\"ibm_messageId\":\"AMQ7002I\",\"message\":\"After\"}",
+				"{\"ibm_messageId\":\"AMQ7003E\",\"message\":\"Error\"}",
+			} {
+				mf(msg, false)
+			}
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestShutdownOptions(t *testing.T) {
+	t.Setenv("MQ_LOGGING_SHUTDOWN_ID", "")
+	t.Setenv("MQ_LOGGING_SHUTDOWN_MODE", "suppress")
+	_, mode, err := getShutdownOptions()
+	if err != nil || mode != shutdownModeNone {
+		t.Errorf("Expected shutdown handling to be disabled without message IDs; got %v, %v", mode, err)
+	}
+	t.Setenv("MQ_LOGGING_SHUTDOWN_ID", "AMQ8004I")
+	t.Setenv("MQ_LOGGING_SHUTDOWN_MODE", "hide")
+	_, _, err = getShutdownOptions()
+	if err == nil {
+		t.Error("Expected an error for an invalid shutdown mode")
+	}
+}
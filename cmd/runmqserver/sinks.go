@@ -0,0 +1,593 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// declaredSinks are the destinations configured with MQ_LOGGING_SINKS.  If there are any, they
+// replace the default output to the console.
+var declaredSinks []*declaredSink
+
+// declaredSink is a destination for mirrored log messages, which uses its own format
+type declaredSink struct {
+	kind   string
+	format string
+	sink   logSink
+	// globalFormat is the format of the lines passed to write, which don't need to be reformatted
+	globalFormat string
+	opts         mirrorOptions
+	// source is the only source whose messages are written to the sink, or empty for all sources
+	source string
+}
+
+// recordSource returns the source category ("qmgr" or "web") of a parsed JSON log message.  The
+// ibm_sourceCategory field is used if it has been added, and otherwise Liberty messages are from the
+// web server, and all others are from the queue manager.
+func recordSource(obj map[string]interface{}) string {
+	r := newMQLogRecord(obj)
+	if category := r.Field("ibm_sourceCategory"); category != "" {
+		return category
+	}
+	if strings.HasPrefix(r.Field("type"), "liberty_") {
+		return "web"
+	}
+	return "qmgr"
+}
+
+// write sends a log message to the sink, reformatting it if the sink uses a different format.
+// The obj parameter is the parsed JSON log message, or nil if the message wasn't JSON, in which
+// case source is the category of the log it came from, or empty if it isn't known.
+func (d *declaredSink) write(source string, obj map[string]interface{}, line string) {
+	if obj != nil {
+		source = recordSource(obj)
+	}
+	if d.source != "" && source != d.source {
+		return
+	}
+	out, ok := d.formatLine(obj, line)
+	if !ok {
+		return
+	}
+	out = terminateRecord(out)
+	if _, isConsole := d.sink.(consoleSink); isConsole {
+		// Write directly, so that the message ID can be used to decide when to flush the console
+		writeConsole(out, newMQLogRecord(obj).MessageID())
+		return
+	}
+	if f, isFile := d.sink.(*fileSink); isFile {
+		// Write directly, so that the message ID can be indexed
+		f.writeRecord(out, newMQLogRecord(obj).MessageID())
+		return
+	}
+	d.sink.Write(out)
+}
+
+// formatLine returns a log message in the sink's format
+func (d *declaredSink) formatLine(obj map[string]interface{}, line string) (string, bool) {
+	if d.format == d.globalFormat {
+		return line, true
+	}
+	if obj == nil {
+		// The message wasn't JSON, so there's nothing to reformat
+		if d.format == "basic" {
+			// In JSON format, the message was wrapped in a simple JSON message
+			if wrapped, err := processLogMessage(strings.TrimSpace(line)); err == nil {
+				line = newMQLogRecord(wrapped).Message() + "\n"
+			}
+			return addLabelsBasic(line, d.opts.labels), true
+		}
+		obj = map[string]interface{}{"message": strings.TrimSuffix(line, "\n")}
+	}
+	if d.format == "basic" {
+		return addLabelsBasic(formatBasic(obj), d.opts.labels), true
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		log.Debugf("Unable to encode log message for %v sink: %v", d.kind, err)
+		return "", false
+	}
+	line = addJSONFields(obj, string(b), d.opts)
+	if convert, ok := jsonConverters[d.format]; ok {
+		line = convert(line)
+	}
+	return line + "\n", true
+}
+
+// consoleSink writes mirrored log messages to the console
+type consoleSink struct{}
+
+func (consoleSink) Write(line string) {
+	writeConsole(line, "")
+}
+
+func (consoleSink) Close() error {
+	return nil
+}
+
+// defaultFileSinkIndexLimit is the number of offsets kept in the index for each message ID
+const defaultFileSinkIndexLimit = 1000
+
+// defaultFileSinkMaxFiles is the number of rotated files kept, when a file sink has a maximum size
+const defaultFileSinkMaxFiles = 5
+
+// fileSinkFlushInterval is how often the messages buffered by the gzip writer of a compressed file sink
+// are written to the file
+var fileSinkFlushInterval = 5 * time.Second
+
+// fileSinkPruneInterval is how often a file sink checks for rotated files older than its maximum age
+var fileSinkPruneInterval = time.Minute
+
+// fileSinkRetryInterval is how long a file sink waits before trying to write again, after the disk was full
+var fileSinkRetryInterval = 30 * time.Second
+
+// fileSink appends mirrored log messages to a file.  Optionally, it keeps an index of the byte offsets
+// in the file where each message ID appears, which is written to a companion file when the sink is closed.
+// It can also compress the file with gzip, and rotate it when it reaches a maximum size.
+type fileSink struct {
+	mutex sync.Mutex
+	path  string
+	f     *os.File
+	// w is where messages are written, which is either the file, or a gzip writer for the file
+	w  io.Writer
+	gz *gzip.Writer
+	// offset is the position in the file where the next message will be written.  If the file is
+	// compressed, this is the number of uncompressed bytes written since it was opened.
+	offset int64
+	// index maps message IDs to their offsets, or is nil if there is no index
+	index      map[string][]int64
+	indexPath  string
+	indexLimit int
+	// maxSize is the size at which the file is rotated, or zero if it is never rotated
+	maxSize  int64
+	maxFiles int
+	// maxAge is the age at which rotated files are removed, or zero if they are kept until there are maxFiles
+	maxAge time.Duration
+	// prunedAt is the last time rotated files older than maxAge were removed
+	prunedAt time.Time
+	// diskFull is set when a write fails because the disk is full, until a write succeeds again
+	diskFull bool
+	// retryAt is the time to try writing again, after the disk was full
+	retryAt time.Time
+	// dropped is the number of messages not written while the disk was full
+	dropped int
+	// lastError is the last error writing to the file, for reporting the health of the sink
+	lastError string
+	// done stops the goroutine which periodically flushes a compressed file
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	s := &fileSink{path: path, done: make(chan struct{})}
+	err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the file for appending.  If the sink is compressed, a new gzip stream is started, which
+// is added to the end of any existing file as a separate gzip member.
+func (s *fileSink) open() error {
+	// #nosec G302 G304 - the path is configured by the operator, and the file is only readable by the owner and group
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		// #nosec G104 - the error from Stat is more useful
+		f.Close()
+		return err
+	}
+	s.f = f
+	s.w = f
+	s.offset = fi.Size()
+	if s.gz != nil {
+		s.gz.Reset(f)
+		s.w = s.gz
+		s.offset = 0
+	}
+	return nil
+}
+
+// enableCompression compresses the messages written to the file with gzip
+func (s *fileSink) enableCompression() {
+	s.gz = gzip.NewWriter(s.f)
+	s.w = s.gz
+	s.offset = 0
+}
+
+// startFlushing starts a goroutine which regularly writes the messages buffered by the gzip writer to
+// the file, so that they aren't held in memory while few messages are being written
+func (s *fileSink) startFlushing(interval time.Duration) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.mutex.Lock()
+				err := s.gz.Flush()
+				s.mutex.Unlock()
+				if err != nil {
+					log.Debugf("Unable to flush %v: %v", s.path, err)
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// enableRotation rotates the file before it grows beyond maxSize bytes, keeping up to maxFiles old files
+// with ".1", ".2" and so on added to the path.  For a compressed file, the size is before compression.
+// If maxAge is set, rotated files last modified longer ago than that are removed.
+func (s *fileSink) enableRotation(maxSize int64, maxFiles int, maxAge time.Duration) {
+	s.maxSize = maxSize
+	s.maxFiles = maxFiles
+	s.maxAge = maxAge
+	s.prune()
+}
+
+// prune removes the rotated files which are older than the maximum age
+func (s *fileSink) prune() {
+	if s.maxAge <= 0 {
+		return
+	}
+	s.prunedAt = timeNow()
+	cutoff := s.prunedAt.Add(-s.maxAge)
+	for i := 1; i <= s.maxFiles; i++ {
+		p := fmt.Sprintf("%v.%v", s.path, i)
+		fi, err := os.Stat(p)
+		if err != nil || !fi.ModTime().Before(cutoff) {
+			continue
+		}
+		err = os.Remove(p)
+		if err != nil {
+			log.Debugf("Unable to remove old log file %v: %v", p, err)
+		}
+	}
+}
+
+// closeFile finishes any gzip stream, so that the file is complete, and closes it
+func (s *fileSink) closeFile() error {
+	if s.gz != nil {
+		err := s.gz.Close()
+		if err != nil {
+			// #nosec G104 - the error from finishing the gzip stream is more useful
+			s.f.Close()
+			return err
+		}
+	}
+	return s.f.Close()
+}
+
+// rotate closes the file, renames it and the older files, and opens a new file
+func (s *fileSink) rotate() error {
+	err := s.closeFile()
+	if err != nil {
+		return err
+	}
+	// #nosec G104 - the oldest file might not exist
+	os.Remove(fmt.Sprintf("%v.%v", s.path, s.maxFiles))
+	for i := s.maxFiles - 1; i > 0; i-- {
+		// #nosec G104 - older files might not exist yet
+		os.Rename(fmt.Sprintf("%v.%v", s.path, i), fmt.Sprintf("%v.%v", s.path, i+1))
+	}
+	err = os.Rename(s.path, s.path+".1")
+	if err != nil {
+		return err
+	}
+	s.prune()
+	return s.open()
+}
+
+// enableIndex starts indexing the message IDs written to the file, keeping up to limit offsets for each
+// message ID.  If the index file already exists, new offsets are added to it.
+func (s *fileSink) enableIndex(path string, limit int) {
+	s.index = make(map[string][]int64)
+	s.indexPath = path
+	s.indexLimit = limit
+	// #nosec G304 - the path is derived from the operator's configuration
+	b, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(b, &s.index)
+		if err != nil {
+			log.Debugf("Ignoring invalid index file %v: %v", path, err)
+			s.index = make(map[string][]int64)
+		}
+	}
+}
+
+func (s *fileSink) Write(line string) {
+	s.writeRecord(line, "")
+}
+
+// writeRecord appends a log message to the file, and adds it to the index if it has a message ID
+func (s *fileSink) writeRecord(line string, messageID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.diskFull && timeNow().Before(s.retryAt) {
+		s.dropped++
+		return
+	}
+	if s.maxAge > 0 && timeNow().Sub(s.prunedAt) >= fileSinkPruneInterval {
+		// Rotated files can pass the maximum age between rotations, so check them as messages are written
+		s.prune()
+	}
+	if s.maxSize > 0 && s.offset > 0 && s.offset+int64(len(line)) > s.maxSize {
+		err := s.rotate()
+		if err != nil {
+			log.Errorf("Unable to rotate %v: %v", s.path, err)
+			return
+		}
+	}
+	n, err := io.WriteString(s.w, line)
+	if errors.Is(err, syscall.ENOSPC) {
+		// Stop writing until the retry interval has passed, rather than failing on every message
+		if !s.diskFull {
+			log.Printf("Warning: The disk is full, so log messages will not be written to %v until space is available.  Log messages are still mirrored to other destinations.", s.path)
+			s.diskFull = true
+		}
+		s.dropped++
+		s.retryAt = timeNow().Add(fileSinkRetryInterval)
+		s.offset += int64(n)
+		s.lastError = err.Error()
+		return
+	}
+	if err != nil {
+		log.Debugf("Unable to write to %v: %v", s.path, err)
+		s.lastError = err.Error()
+	} else if s.diskFull {
+		log.Printf("Resumed writing log messages to %v, after %v messages were not written because the disk was full", s.path, s.dropped)
+		s.diskFull = false
+		s.dropped = 0
+	}
+	if s.index != nil && messageID != "" && err == nil {
+		offsets := append(s.index[messageID], s.offset)
+		// Keep the most recent offsets, so that the index doesn't grow without limit
+		if len(offsets) > s.indexLimit {
+			offsets = offsets[len(offsets)-s.indexLimit:]
+		}
+		s.index[messageID] = offsets
+	}
+	s.offset += int64(n)
+}
+
+// health returns the state of the sink.  It is connected unless the disk is full.
+func (s *fileSink) health() sinkHealth {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return sinkHealth{
+		Sink:      "file " + s.path,
+		Connected: !s.diskFull,
+		Dropped:   uint64(s.dropped),
+		LastError: s.lastError,
+	}
+}
+
+// writeIndex writes the index to its file, replacing the file in one step so that it is never partly written
+func (s *fileSink) writeIndex() error {
+	b, err := json.Marshal(s.index)
+	if err != nil {
+		return err
+	}
+	tmp := s.indexPath + ".tmp"
+	// #nosec G306 - the index is only readable by the owner and group, like the file it indexes
+	err = os.WriteFile(tmp, b, 0640)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.indexPath)
+}
+
+func (s *fileSink) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.index != nil {
+		err := s.writeIndex()
+		if err != nil {
+			log.Errorf("Unable to write index file %v: %v", s.indexPath, err)
+		}
+	}
+	return s.closeFile()
+}
+
+// parseSinkOptions parses a single sink declaration, such as "type=file,format=json,path=/tmp/mq.log"
+func parseSinkOptions(declaration string) (map[string]string, error) {
+	options := make(map[string]string)
+	for _, pair := range strings.Split(declaration, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid sink option %q in MQ_LOGGING_SINKS", pair)
+		}
+		options[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+	}
+	return options, nil
+}
+
+// newDeclaredSink creates a sink from its parsed options
+func newDeclaredSink(options map[string]string, globalFormat string, opts mirrorOptions) (*declaredSink, error) {
+	d := &declaredSink{
+		kind:         strings.ToLower(options["type"]),
+		format:       strings.ToLower(options["format"]),
+		globalFormat: globalFormat,
+		opts:         opts,
+	}
+	if d.format == "" {
+		d.format = globalFormat
+	}
+	if !isValidLogFormat(d.format) {
+		return nil, fmt.Errorf("invalid format for %v sink in MQ_LOGGING_SINKS: %v", d.kind, d.format)
+	}
+	switch d.source = strings.ToLower(options["source"]); d.source {
+	case "", "qmgr", "web":
+	default:
+		return nil, fmt.Errorf("invalid source for %v sink in MQ_LOGGING_SINKS: %v", d.kind, d.source)
+	}
+	switch d.kind {
+	case "console":
+		d.sink = consoleSink{}
+	case "file":
+		if options["path"] == "" {
+			return nil, fmt.Errorf("file sink in MQ_LOGGING_SINKS has no path")
+		}
+		f, err := newFileSink(options["path"])
+		if err != nil {
+			return nil, err
+		}
+		err = configureFileSink(f, options)
+		if err != nil {
+			// #nosec G104 - the configuration error is more useful, and nothing has been written to the file
+			f.f.Close()
+			return nil, err
+		}
+		d.sink = f
+	case "http":
+		if options["url"] == "" {
+			return nil, fmt.Errorf("http sink in MQ_LOGGING_SINKS has no url")
+		}
+		d.sink = newHTTPSink(options["url"], defaultHTTPSinkBatchSize, defaultHTTPSinkFlushInterval, defaultHTTPSinkQueueSize)
+	default:
+		return nil, fmt.Errorf("invalid sink type in MQ_LOGGING_SINKS: %v", d.kind)
+	}
+	return d, nil
+}
+
+// configureFileSink applies the options for compressing, rotating and indexing a file sink
+func configureFileSink(f *fileSink, options map[string]string) error {
+	var err error
+	switch strings.ToLower(options["compress"]) {
+	case "", "none":
+	case "gzip":
+		f.enableCompression()
+	default:
+		return fmt.Errorf("invalid compress for file sink in MQ_LOGGING_SINKS: %v", options["compress"])
+	}
+	if options["max_size"] != "" {
+		maxSize, err := strconv.ParseInt(options["max_size"], 10, 64)
+		if err != nil || maxSize <= 0 {
+			return fmt.Errorf("invalid max_size for file sink in MQ_LOGGING_SINKS: %v", options["max_size"])
+		}
+		maxFiles := defaultFileSinkMaxFiles
+		if options["max_files"] != "" {
+			maxFiles, err = strconv.Atoi(options["max_files"])
+			if err != nil || maxFiles <= 0 {
+				return fmt.Errorf("invalid max_files for file sink in MQ_LOGGING_SINKS: %v", options["max_files"])
+			}
+		}
+		var maxAge time.Duration
+		if options["max_age"] != "" {
+			maxAge, err = time.ParseDuration(options["max_age"])
+			if err != nil || maxAge <= 0 {
+				return fmt.Errorf("invalid max_age for file sink in MQ_LOGGING_SINKS: %v", options["max_age"])
+			}
+		}
+		f.enableRotation(maxSize, maxFiles, maxAge)
+	} else if options["max_files"] != "" || options["max_age"] != "" {
+		return fmt.Errorf("file sink in MQ_LOGGING_SINKS can only have max_files or max_age if it has max_size")
+	}
+	switch strings.ToLower(options["index"]) {
+	case "", "false":
+	case "true":
+		if f.gz != nil || f.maxSize > 0 {
+			return fmt.Errorf("file sink in MQ_LOGGING_SINKS can't have an index if it is compressed or rotated")
+		}
+		limit := defaultFileSinkIndexLimit
+		if options["index_limit"] != "" {
+			limit, err = strconv.Atoi(options["index_limit"])
+			if err != nil || limit <= 0 {
+				return fmt.Errorf("invalid index_limit for file sink in MQ_LOGGING_SINKS: %v", options["index_limit"])
+			}
+		}
+		f.enableIndex(options["path"]+".index.json", limit)
+	default:
+		return fmt.Errorf("invalid index for file sink in MQ_LOGGING_SINKS: %v", options["index"])
+	}
+	if f.gz != nil {
+		f.startFlushing(fileSinkFlushInterval)
+	}
+	return nil
+}
+
+// configureDeclaredSinks creates the sinks listed in MQ_LOGGING_SINKS, which holds sink declarations
+// separated by semi-colons.  Each declaration is a comma-separated list of options, including the
+// type of sink ("console", "file" or "http"), its format ("json", "basic", "ecs", "gelf" or "syslog",
+// defaulting to the console format), and a "path" or "url" for file and HTTP sinks.  A file sink can also have
+// "index=true", to keep an index of where each message ID appears in the file, in a companion
+// file with ".index.json" added to the path.  The number of offsets kept for each message ID
+// can be set with "index_limit".  A file sink can be compressed with "compress=gzip", and rotated
+// with "max_size", which is the number of bytes to write to each file before compression,
+// "max_files", which is the number of rotated files to keep, and "max_age", after which rotated files
+// are removed.  Any sink can have "source=qmgr" or "source=web", so that it only receives messages
+// from that source, which allows each source to have its own retention.
+func configureDeclaredSinks(globalFormat string, opts mirrorOptions) error {
+	closeDeclaredSinks()
+	declaredSinks = nil
+	for _, declaration := range strings.Split(os.Getenv("MQ_LOGGING_SINKS"), ";") {
+		if strings.TrimSpace(declaration) == "" {
+			continue
+		}
+		options, err := parseSinkOptions(declaration)
+		if err != nil {
+			closeDeclaredSinks()
+			declaredSinks = nil
+			return err
+		}
+		d, err := newDeclaredSink(options, globalFormat, opts)
+		if err != nil {
+			closeDeclaredSinks()
+			declaredSinks = nil
+			return err
+		}
+		declaredSinks = append(declaredSinks, d)
+	}
+	return nil
+}
+
+// closeDeclaredSinks flushes and closes the sinks listed in MQ_LOGGING_SINKS
+func closeDeclaredSinks() {
+	for _, d := range declaredSinks {
+		err := d.sink.Close()
+		if err != nil {
+			log.Errorf("Error closing %v log sink: %v", d.kind, err)
+		}
+	}
+}
@@ -0,0 +1,491 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDeclaredSinks(t *testing.T) {
+	oldLog := log
+	defer func() {
+		log = oldLog
+		declaredSinks = nil
+	}()
+	path := filepath.Join(t.TempDir(), "mirror.json")
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json")
+	t.Setenv("MQ_LOGGING_SINKS", "type=console,format=basic; type=file,format=json,path="+path)
+	mf, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := captureConsole(t)
+	msg := "{\"ibm_datetime\":\"2024-01-01T10:00:00.000Z\",\"ibm_messageId\":\"AMQ5051I\",\"message\":\"Hello\"}"
+	mf(msg, false)
+	mf("Not JSON", false)
+	closeLogSinks()
+
+	expectedConsole := "2024-01-01T10:00:00.000Z Hello\nNot JSON\n"
+	if buf.String() != expectedConsole {
+		t.Errorf("Expected console output %q; got %q", expectedConsole, buf.String())
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFile := msg + "\n{\"message\":\"Not JSON\"}\n"
+	if string(b) != expectedFile {
+		t.Errorf("Expected file output %q; got %q", expectedFile, string(b))
+	}
+}
+
+var invalidSinkTests = []string{
+	"type=syslog",
+	"type=file",
+	"type=console,format=xml",
+	"type=http",
+	"console",
+}
+
+func TestConfigureDeclaredSinksInvalid(t *testing.T) {
+	defer func() { declaredSinks = nil }()
+	for _, value := range invalidSinkTests {
+		t.Setenv("MQ_LOGGING_SINKS", value)
+		err := configureDeclaredSinks("basic", mirrorOptions{})
+		if err == nil {
+			t.Errorf("Expected an error for MQ_LOGGING_SINKS=%v", value)
+		}
+	}
+}
+
+func TestFileSinkIndex(t *testing.T) {
+	oldLog := log
+	defer func() {
+		log = oldLog
+		declaredSinks = nil
+	}()
+	path := filepath.Join(t.TempDir(), "mirror.json")
+	// Existing content in the file is not indexed, but the offsets take it into account
+	os.WriteFile(path, []byte("{\"message\":\"Before\"}\n"), 0600)
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json")
+	t.Setenv("MQ_LOGGING_SINKS", "type=file,format=json,index=true,index_limit=2,path="+path)
+	mf, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []string{
+		"{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"A\"}",
+		"{\"ibm_messageId\":\"AMQ9209E\",\"message\":\"B\"}",
+		"Not JSON",
+		"{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"C\"}",
+		"{\"ibm_messageId\":\"AMQ5051I\",\"message\":\"D\"}",
+	}
+	for _, msg := range msgs {
+		mf(msg, false)
+	}
+	closeLogSinks()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := os.ReadFile(path + ".index.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index map[string][]int64
+	err = json.Unmarshal(b2, &index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the two most recent offsets are kept for AMQ5051I
+	expected := map[string][]string{
+		"AMQ5051I": {msgs[3], msgs[4]},
+		"AMQ9209E": {msgs[1]},
+	}
+	if len(index) != len(expected) {
+		t.Errorf("Expected %v message IDs in the index; got %v", len(expected), index)
+	}
+	for id, lines := range expected {
+		if len(index[id]) != len(lines) {
+			t.Errorf("Expected %v offsets for %v; got %v", len(lines), id, index[id])
+			continue
+		}
+		for i, offset := range index[id] {
+			if !strings.HasPrefix(string(b[offset:]), lines[i]+"\n") {
+				t.Errorf("Expected offset %v to hold %v; got %q", offset, lines[i], string(b[offset:]))
+			}
+		}
+	}
+}
+
+func TestFileSinkCompressedRotation(t *testing.T) {
+	oldLog := log
+	defer func() {
+		log = oldLog
+		declaredSinks = nil
+	}()
+	path := filepath.Join(t.TempDir(), "mirror.json.gz")
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json")
+	// Each message is 16 bytes, so two fit in each file
+	t.Setenv("MQ_LOGGING_SINKS", "type=file,format=json,compress=gzip,max_size=40,max_files=2,path="+path)
+	mf, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+		mf("{\"message\":\""+msg+"\"}", false)
+	}
+	closeLogSinks()
+
+	// The oldest file, holding "A" and "B", is removed
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only two rotated files; got %v", err)
+	}
+	expected := map[string][]string{
+		path + ".2": {"C", "D"},
+		path + ".1": {"E", "F"},
+		path:        {"G"},
+	}
+	for p, msgs := range expected {
+		f, err := os.Open(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("Expected %v to be a gzip file: %v", p, err)
+		}
+		// Reading to the end checks the gzip footer, so fails if the stream isn't complete
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("Expected %v to be a complete gzip stream: %v", p, err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		if len(lines) != len(msgs) {
+			t.Fatalf("Expected %v messages in %v; got %v", len(msgs), p, string(b))
+		}
+		for i, line := range lines {
+			var obj map[string]interface{}
+			err = json.Unmarshal([]byte(line), &obj)
+			if err != nil || obj["message"] != msgs[i] {
+				t.Errorf("Expected message %v in %v; got %v", msgs[i], p, line)
+			}
+		}
+	}
+}
+
+func TestFileSinkSourceRetention(t *testing.T) {
+	oldLog := log
+	defer func() {
+		log = oldLog
+		declaredSinks = nil
+	}()
+	dir := t.TempDir()
+	qmgrPath := filepath.Join(dir, "qmgr.json")
+	webPath := filepath.Join(dir, "web.json")
+	// Rotated files left from before a restart, which are pruned if they are older than their source's limit
+	now := time.Now()
+	for p, age := range map[string]time.Duration{
+		qmgrPath + ".1": 2 * 24 * time.Hour,
+		qmgrPath + ".2": 8 * 24 * time.Hour,
+		webPath + ".1":  2 * 24 * time.Hour,
+	} {
+		err := os.WriteFile(p, []byte("{\"message\":\"Old\"}\n"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(p, now.Add(-age), now.Add(-age))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "json")
+	t.Setenv("MQ_LOGGING_SINKS", "type=file,source=qmgr,max_size=1000,max_files=7,max_age=168h,path="+qmgrPath+
+		";type=file,source=web,max_size=20,max_files=1,max_age=24h,path="+webPath)
+	mf, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, exists := range map[string]bool{qmgrPath + ".1": true, qmgrPath + ".2": false, webPath + ".1": false} {
+		if _, err := os.Stat(p); os.IsNotExist(err) == exists {
+			t.Errorf("Expected %v to exist=%v after pruning; got %v", p, exists, err)
+		}
+	}
+
+	mf("{\"ibm_messageId\":\"AMQ9209E\",\"message\":\"Q1\"}", false)
+	for _, msg := range []string{"W1", "W2", "W3"} {
+		mf("{\"type\":\"liberty_message\",\"message\":\""+msg+"\"}", false)
+	}
+	closeLogSinks()
+
+	// Each source is written to its own file, and the web log is rotated at its own size, keeping one file
+	expected := map[string]string{
+		qmgrPath:        "{\"ibm_messageId\":\"AMQ9209E\",\"message\":\"Q1\"}\n",
+		qmgrPath + ".1": "{\"message\":\"Old\"}\n",
+		webPath:         "{\"type\":\"liberty_message\",\"message\":\"W3\"}\n",
+		webPath + ".1":  "{\"type\":\"liberty_message\",\"message\":\"W2\"}\n",
+	}
+	for p, content := range expected {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("Expected %v to hold %q; got %q", p, content, string(b))
+		}
+	}
+	if _, err := os.Stat(webPath + ".2"); !os.IsNotExist(err) {
+		t.Errorf("Expected only one rotated web file; got %v", err)
+	}
+}
+
+func TestFileSinkSourceNotJSON(t *testing.T) {
+	oldLog := log
+	defer func() {
+		log = oldLog
+		declaredSinks = nil
+		sourceMirrorFuncs = map[string]mirrorFunc{}
+	}()
+	dir := t.TempDir()
+	qmgrPath := filepath.Join(dir, "qmgr.log")
+	webPath := filepath.Join(dir, "web.log")
+	t.Setenv("MQ_LOGGING_CONSOLE_FORMAT", "basic")
+	t.Setenv("MQ_LOGGING_SINKS", "type=file,source=qmgr,path="+qmgrPath+";type=file,source=web,path="+webPath)
+	mf, err := configureLogger("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Messages which aren't JSON are routed by the log they came from
+	mf("Queue manager line", true)
+	mirrorFuncForSource("web", mf)("Web server line", true)
+	closeLogSinks()
+	for p, content := range map[string]string{qmgrPath: "Queue manager line\n", webPath: "Web server line\n"} {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("Expected %v to hold %q; got %q", p, content, string(b))
+		}
+	}
+}
+
+func TestFileSinkPruneOnWrite(t *testing.T) {
+	oldNow := timeNow
+	defer func() { timeNow = oldNow }()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	path := filepath.Join(t.TempDir(), "mirror.json")
+	err := os.WriteFile(path+".1", []byte("{\"message\":\"Old\"}\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.enableRotation(1000, 2, time.Hour)
+	s.Write("{\"message\":\"A\"}\n")
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("Expected the rotated file to be kept until it is older than the maximum age; got %v", err)
+	}
+	// The rotated file passes the maximum age without the file being rotated again
+	now = now.Add(2 * time.Hour)
+	s.Write("{\"message\":\"B\"}\n")
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("Expected the rotated file to be removed once it is older than the maximum age; got %v", err)
+	}
+}
+
+func TestFileSinkRetentionInvalid(t *testing.T) {
+	var tests = []string{
+		"type=file,source=system,path=",
+		"type=file,max_age=24h,path=",
+		"type=file,max_size=10,max_age=soon,path=",
+	}
+	for i, declaration := range tests {
+		t.Run(fmt.Sprintf("%v", i), func(t *testing.T) {
+			// The temporary directory is named after the test, so mustn't include a comma
+			options, err := parseSinkOptions(declaration + filepath.Join(t.TempDir(), "mirror.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = newDeclaredSink(options, "json", mirrorOptions{})
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+// fullDiskWriter fails every write, as if the disk was full
+type fullDiskWriter struct {
+	writes int
+}
+
+func (w *fullDiskWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, &os.PathError{Op: "write", Path: "mirror.json", Err: syscall.ENOSPC}
+}
+
+func TestFileSinkInvalidOptionsClosesFile(t *testing.T) {
+	countOpenFiles := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skipf("Unable to count open files: %v", err)
+		}
+		return len(entries)
+	}
+	path := filepath.Join(t.TempDir(), "mirror.json")
+	before := countOpenFiles()
+	for _, declaration := range []string{
+		"type=file,compress=zip",
+		"type=file,compress=gzip,max_size=0",
+		"type=file,compress=gzip,index=true",
+		"type=file,index=true,index_limit=none",
+	} {
+		options, err := parseSinkOptions(declaration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		options["path"] = path
+		_, err = newDeclaredSink(options, "json", mirrorOptions{})
+		if err == nil {
+			t.Errorf("Expected an error for %v", declaration)
+		}
+	}
+	if after := countOpenFiles(); after != before {
+		t.Errorf("Expected the file to be closed after each error; %v files were open before, and %v after", before, after)
+	}
+}
+
+func TestConfigureDeclaredSinksInvalidClosesSinks(t *testing.T) {
+	countOpenFiles := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skipf("Unable to count open files: %v", err)
+		}
+		return len(entries)
+	}
+	path := filepath.Join(t.TempDir(), "mirror.json")
+	before := countOpenFiles()
+	for _, declarations := range []string{
+		"type=file,path=" + path + ";console",
+		"type=file,path=" + path + ";type=file",
+	} {
+		t.Setenv("MQ_LOGGING_SINKS", declarations)
+		err := configureDeclaredSinks("json", mirrorOptions{})
+		if err == nil {
+			t.Errorf("Expected an error for %v", declarations)
+		}
+		if len(declaredSinks) != 0 {
+			t.Errorf("Expected no sinks after an error for %v; got %v", declarations, len(declaredSinks))
+		}
+	}
+	if after := countOpenFiles(); after != before {
+		t.Errorf("Expected the sinks declared before an invalid one to be closed; %v files were open before, and %v after", before, after)
+	}
+}
+
+func TestFileSinkCompressedFlush(t *testing.T) {
+	oldInterval := fileSinkFlushInterval
+	fileSinkFlushInterval = 50 * time.Millisecond
+	t.Cleanup(func() { fileSinkFlushInterval = oldInterval })
+	path := filepath.Join(t.TempDir(), "mirror.json.gz")
+	s, err := newFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	err = configureFileSink(s, map[string]string{"compress": "gzip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write("{\"message\":\"A\"}\n")
+	// The message is written to the file without waiting for the sink to be closed
+	for i := 0; i < 50; i++ {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b []byte
+		if r, err := gzip.NewReader(f); err == nil {
+			// The stream isn't finished until the sink is closed, so an unexpected EOF is expected
+			b, _ = io.ReadAll(r)
+		}
+		f.Close()
+		if string(b) == "{\"message\":\"A\"}\n" {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Error("Timed out waiting for the compressed message to be written")
+}
+
+func TestFileSinkDiskFull(t *testing.T) {
+	oldNow := timeNow
+	defer func() { timeNow = oldNow }()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	out := captureLog(t)
+	path := filepath.Join(t.TempDir(), "mirror.json")
+	s, err := newFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := &fullDiskWriter{}
+	s.w = full
+	s.Write("A\n")
+	s.Write("B\n")
+	s.Write("C\n")
+	// The first write fails, and the others aren't attempted until the retry interval has passed
+	if full.writes != 1 {
+		t.Errorf("Expected 1 write while the disk is full; got %v", full.writes)
+	}
+	if strings.Count(out.String(), "The disk is full") != 1 {
+		t.Errorf("Expected a single warning; got %v", out.String())
+	}
+	// Space is freed, so writing resumes after the retry interval
+	s.w = s.f
+	now = now.Add(fileSinkRetryInterval)
+	s.Write("D\n")
+	err = s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "D\n" {
+		t.Errorf("Expected only the message after the disk was freed; got %q", string(b))
+	}
+	if !strings.Contains(out.String(), "after 3 messages were not written") {
+		t.Errorf("Expected writing to resume; got %v", out.String())
+	}
+}
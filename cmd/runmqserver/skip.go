@@ -0,0 +1,88 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// startupSkip drops the first lines mirrored after the process starts, or is nil if no lines are skipped
+var startupSkip *startupSkipper
+
+// startupSkipper counts down the lines to skip, either over all sources, or separately for each source
+type startupSkipper struct {
+	mutex     sync.Mutex
+	lines     int
+	perSource bool
+	// countReplay is true if lines replayed from the start of an existing file are skipped too
+	countReplay bool
+	// skipped is the number of lines skipped so far, keyed by source, or by "" if counting over all sources
+	skipped map[string]int
+}
+
+// skip returns true if the next line from a source should be skipped
+func (s *startupSkipper) skip(source string) bool {
+	key := ""
+	if s.perSource {
+		key = source
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.skipped[key] >= s.lines {
+		return false
+	}
+	s.skipped[key]++
+	return true
+}
+
+// wrap returns a mirrorFunc which skips lines from a source.  The replaying function reports whether
+// lines are currently being replayed from the start of an existing file.
+func (s *startupSkipper) wrap(source string, mf mirrorFunc, replaying func() bool) mirrorFunc {
+	return func(msg string, isQMLog bool) bool {
+		if (s.countReplay || !replaying()) && s.skip(source) {
+			log.Debugf("Skipped startup line from %v", source)
+			return false
+		}
+		return mf(msg, isQMLog)
+	}
+}
+
+// configureStartupSkip skips the number of lines in MQ_LOGGING_SKIP_STARTUP_LINES, if it is set.
+// MQ_LOGGING_SKIP_STARTUP_LINES_SCOPE controls whether the lines are counted over all sources ("all", the
+// default) or for each source ("source").  Lines replayed from the start of an existing file are only counted
+// if MQ_LOGGING_SKIP_STARTUP_LINES_REPLAY is set.
+func configureStartupSkip() error {
+	startupSkip = nil
+	lines, err := getPositiveIntEnv("MQ_LOGGING_SKIP_STARTUP_LINES", 0)
+	if err != nil || lines == 0 {
+		return err
+	}
+	s := &startupSkipper{lines: lines, skipped: make(map[string]int)}
+	switch scope := strings.ToLower(strings.TrimSpace(os.Getenv("MQ_LOGGING_SKIP_STARTUP_LINES_SCOPE"))); scope {
+	case "", "all":
+	case "source":
+		s.perSource = true
+	default:
+		return fmt.Errorf("invalid value for MQ_LOGGING_SKIP_STARTUP_LINES_SCOPE: %v", scope)
+	}
+	countReplay := os.Getenv("MQ_LOGGING_SKIP_STARTUP_LINES_REPLAY")
+	s.countReplay = countReplay == "true" || countReplay == "1"
+	startupSkip = s
+	return nil
+}
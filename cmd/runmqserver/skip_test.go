@@ -0,0 +1,108 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStartupSkipperScope(t *testing.T) {
+	defer func() { startupSkip = nil }()
+	for _, scope := range []string{"all", "source"} {
+		t.Run(scope, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_SKIP_STARTUP_LINES", "2")
+			t.Setenv("MQ_LOGGING_SKIP_STARTUP_LINES_SCOPE", scope)
+			err := configureStartupSkip()
+			if err != nil {
+				t.Fatal(err)
+			}
+			skipped := 0
+			for _, source := range []string{"qmgr", "web", "qmgr", "web", "qmgr", "web"} {
+				if startupSkip.skip(source) {
+					skipped++
+				}
+			}
+			expected := 2
+			if scope == "source" {
+				expected = 4
+			}
+			if skipped != expected {
+				t.Errorf("Expected %v lines to be skipped; got %v", expected, skipped)
+			}
+		})
+	}
+}
+
+var startupSkipTests = []struct {
+	name        string
+	fromStart   bool
+	countReplay string
+	expected    int
+}{
+	{"NewLines", false, "", 3},
+	{"ReplayNotCounted", true, "", 5},
+	{"ReplayCounted", true, "true", 3},
+}
+
+func TestMirrorLogSkipStartupLines(t *testing.T) {
+	defer func() { startupSkip = nil }()
+	for _, table := range startupSkipTests {
+		t.Run(table.name, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_SKIP_STARTUP_LINES", "2")
+			t.Setenv("MQ_LOGGING_SKIP_STARTUP_LINES_REPLAY", table.countReplay)
+			err := configureStartupSkip()
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "AMQERR01.json")
+			if table.fromStart {
+				os.WriteFile(path, []byte("{\"message\":\"A\"}\n{\"message\":\"B\"}\n{\"message\":\"C\"}\n{\"message\":\"D\"}\n{\"message\":\"E\"}\n"), 0600)
+			} else {
+				os.WriteFile(path, []byte{}, 0600)
+			}
+			count := 0
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			_, err = mirrorLog(ctx, &wg, "qmgr", path, table.fromStart, func(msg string, isQMLog bool) bool {
+				count++
+				return true
+			}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !table.fromStart {
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, m := range []string{"A", "B", "C", "D", "E"} {
+					fmt.Fprintf(f, "{\"message\":\"%v\"}\n", m)
+				}
+				f.Close()
+			}
+			cancel()
+			wg.Wait()
+			if count != table.expected {
+				t.Errorf("Expected %v lines to be mirrored; got %v", table.expected, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// lineStats counts the lines read from a log, by what happened to them.  Each line is only counted once.
+type lineStats struct {
+	// Emitted is the number of lines mirrored, apart from those which failed to parse
+	Emitted uint64 `json:"emitted"`
+	// Filtered is the number of lines which were not mirrored, for example because of their message ID
+	Filtered uint64 `json:"filtered"`
+	// ParseFailed is the number of lines which looked like JSON, but couldn't be parsed
+	ParseFailed uint64 `json:"parseFailed"`
+}
+
+// mirrorStats holds the line counts for each source, such as "qmgr" or "web"
+var mirrorStats = struct {
+	sync.Mutex
+	sources map[string]*lineStats
+}{sources: make(map[string]*lineStats)}
+
+// countLine records what happened to a line read from a source
+func countLine(source string, emitted bool, parseFailed bool) {
+	mirrorStats.Lock()
+	defer mirrorStats.Unlock()
+	s, ok := mirrorStats.sources[source]
+	if !ok {
+		s = &lineStats{}
+		mirrorStats.sources[source] = s
+	}
+	switch {
+	case parseFailed:
+		s.ParseFailed++
+	case emitted:
+		s.Emitted++
+	default:
+		s.Filtered++
+	}
+}
+
+// getMirrorStats returns a copy of the line counts for each source, so that they can be exposed as metrics
+func getMirrorStats() map[string]lineStats {
+	mirrorStats.Lock()
+	defer mirrorStats.Unlock()
+	stats := make(map[string]lineStats, len(mirrorStats.sources))
+	for source, s := range mirrorStats.sources {
+		stats[source] = *s
+	}
+	return stats
+}
+
+// getMirrorStatsTotal returns the line counts for all sources added together
+func getMirrorStatsTotal() lineStats {
+	var total lineStats
+	for _, s := range getMirrorStats() {
+		total.Emitted += s.Emitted
+		total.Filtered += s.Filtered
+		total.ParseFailed += s.ParseFailed
+	}
+	return total
+}
+
+// countLines wraps a mirrorFunc, so that each line read from the source is counted
+func countLines(mf mirrorFunc, source string) mirrorFunc {
+	return func(msg string, isQMLog bool) bool {
+		trimmed := trimRecordPrefix(msg)
+		parseFailed := len(trimmed) > 0 && trimmed[0] == '{' && !json.Valid([]byte(trimmed))
+		emitted := mf(msg, isQMLog)
+		countLine(source, emitted, parseFailed)
+		return emitted
+	}
+}
@@ -0,0 +1,64 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMirrorStatsPerSource(t *testing.T) {
+	mirrorStats.sources = make(map[string]*lineStats)
+	defer func() { mirrorStats.sources = make(map[string]*lineStats) }()
+	// Lines mentioning "drop" are filtered
+	mf := func(msg string, isQMLog bool) bool {
+		return !strings.Contains(msg, "drop")
+	}
+	lines := map[string][]string{
+		"qmgr": {"{\"message\":\"A\"}", "{\"message\":\"drop\"}", "{\"message\":"},
+		"web":  {"Not JSON", "drop"},
+	}
+	var wg sync.WaitGroup
+	for source, msgs := range lines {
+		counted := countLines(mf, source)
+		for _, msg := range msgs {
+			// Count from several goroutines at once, to check that the counters are safe to update concurrently
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(msg string) {
+					defer wg.Done()
+					counted(msg, false)
+				}(msg)
+			}
+		}
+	}
+	wg.Wait()
+	stats := getMirrorStats()
+	expected := map[string]lineStats{
+		"qmgr": {Emitted: 10, Filtered: 10, ParseFailed: 10},
+		"web":  {Emitted: 10, Filtered: 10},
+	}
+	for source, e := range expected {
+		if stats[source] != e {
+			t.Errorf("Expected %+v for %v; got %+v", e, source, stats[source])
+		}
+	}
+	total := getMirrorStatsTotal()
+	if total != (lineStats{Emitted: 20, Filtered: 20, ParseFailed: 10}) {
+		t.Errorf("Unexpected total %+v", total)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// syslogFacility is the facility used for the priority of messages in "syslog" format, which is local0
+	syslogFacility = 16
+	// syslogStructuredDataID is the SD-ID of the structured data element which holds the message inserts,
+	// using the IBM private enterprise number
+	syslogStructuredDataID = "inserts@2"
+)
+
+// syslogPriority returns the RFC 5424 priority for a log level
+func syslogPriority(level logLevel) int {
+	return syslogFacility*8 + severityNumber(level, severityNumberSyslog)
+}
+
+// syslogHeaderField returns a value for a field in the RFC 5424 header, which must be printable ASCII
+// without spaces, and no longer than max.  A missing value is written as "-".
+func syslogHeaderField(value string, max int) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(value) > max {
+		value = value[:max]
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// syslogParamEscaper escapes the characters which RFC 5424 requires to be escaped in a parameter value
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogStructuredData returns the structured data for a message, with one parameter for each insert,
+// or "-" if there are no inserts
+func syslogStructuredData(r MQLogRecord) string {
+	inserts := r.Inserts()
+	if len(inserts) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(inserts))
+	for name := range inserts {
+		names = append(names, name)
+	}
+	sortInsertNames(names)
+	var sb strings.Builder
+	sb.WriteString("[" + syslogStructuredDataID)
+	for _, name := range names {
+		fmt.Fprintf(&sb, " %v=\"%v\"", syslogHeaderField(name, 32), syslogParamEscaper.Replace(inserts[name]))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// syslogHostname returns the host name for a message in "syslog" format
+func syslogHostname(r MQLogRecord) string {
+	if host := r.Field("host"); host != "" {
+		return host
+	}
+	host, _ := os.Hostname()
+	return host
+}
+
+// toSyslog formats a parsed JSON log message as an RFC 5424 syslog message.  The process name is used
+// as the APP-NAME, the process ID as the PROCID, and the message ID as the MSGID.
+func toSyslog(obj map[string]interface{}) string {
+	r := newMQLogRecord(obj)
+	dt := r.Datetime()
+	if dt.IsZero() {
+		dt = timeNow()
+	}
+	procID := r.Field("ibm_processId")
+	if n, ok := obj["ibm_processId"].(float64); ok {
+		procID = strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return fmt.Sprintf("<%v>1 %v %v %v %v %v %v %v",
+		syslogPriority(r.Severity()),
+		dt.UTC().Format(eventTimestampFormat),
+		syslogHeaderField(syslogHostname(r), 255),
+		syslogHeaderField(r.Field("ibm_processName"), 48),
+		syslogHeaderField(procID, 128),
+		syslogHeaderField(r.MessageID(), 32),
+		syslogStructuredData(r),
+		r.Message())
+}
+
+// formatSyslogLine converts a JSON log line to an RFC 5424 syslog message.  If the line can't be parsed,
+// it is written as the message, with the priority used for informational messages.
+func formatSyslogLine(line string) string {
+	obj, err := processLogMessage(line)
+	if err != nil {
+		obj = map[string]interface{}{"message": line}
+	}
+	return toSyslog(obj)
+}
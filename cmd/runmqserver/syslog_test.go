@@ -0,0 +1,86 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSyslogFormat(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	oldTimeNow := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = oldTimeNow })
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "-"
+	}
+	var tests = []struct {
+		name     string
+		msg      string
+		expected string
+	}{
+		{
+			name:     "MQ",
+			msg:      "{\"ibm_datetime\":\"2024-02-29T10:15:00.123Z\",\"loglevel\":\"WARNING\",\"host\":\"mq1\",\"ibm_messageId\":\"AMQ9209E\",\"ibm_processName\":\"amqrmppa\",\"ibm_processId\":1234,\"ibm_commentInsert1\":\"APP \\\"1\\\"\",\"ibm_commentInsert2\":\"x]y\",\"ibm_arithInsert1\":0,\"ibm_arithInsert2\":10,\"message\":\"AMQ9209E: Connection closed.\"}",
+			expected: "<132>1 2024-02-29T10:15:00.123Z mq1 amqrmppa 1234 AMQ9209E [inserts@2 ArithInsert2=\"10\" CommentInsert1=\"APP \\\"1\\\"\" CommentInsert2=\"x\\]y\"] AMQ9209E: Connection closed.\n",
+		},
+		{
+			name:     "No inserts",
+			msg:      "{\"ibm_datetime\":\"2024-02-29T10:15:00.123Z\",\"loglevel\":\"ERROR\",\"host\":\"mq1\",\"ibm_messageId\":\"AMQ6119S\",\"message\":\"AMQ6119S: An internal error occurred.\"}",
+			expected: "<131>1 2024-02-29T10:15:00.123Z mq1 - - AMQ6119S - AMQ6119S: An internal error occurred.\n",
+		},
+		{
+			name:     "Liberty",
+			msg:      "{\"type\":\"liberty_message\",\"ibm_datetime\":\"2024-02-29T10:15:00.123+0000\",\"loglevel\":\"AUDIT\",\"host\":\"mq1\",\"ibm_messageId\":\"CWWKF0011I\",\"message\":\"CWWKF0011I: Ready\"}",
+			expected: "<134>1 2024-02-29T10:15:00.123Z mq1 - - CWWKF0011I - CWWKF0011I: Ready\n",
+		},
+		{
+			name:     "Not JSON",
+			msg:      "Plain text message",
+			expected: "<134>1 2024-03-01T12:00:00.000Z " + host + " - - - - Plain text message\n",
+		},
+	}
+	for _, table := range tests {
+		t.Run(table.name, func(t *testing.T) {
+			buf := captureConsole(t)
+			newMirrorFunc("syslog", mirrorOptions{})(table.msg, false)
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestSyslogHeaderField(t *testing.T) {
+	var tests = []struct {
+		value    string
+		max      int
+		expected string
+	}{
+		{"amqzxma0", 48, "amqzxma0"},
+		{"", 48, "-"},
+		{"my host", 255, "my_host"},
+		{"AMQ9209E-too-long", 8, "AMQ9209E"},
+	}
+	for _, table := range tests {
+		if s := syslogHeaderField(table.value, table.max); s != table.expected {
+			t.Errorf("Expected %q for %q; got %q", table.expected, table.value, s)
+		}
+	}
+}
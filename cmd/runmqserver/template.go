@@ -0,0 +1,89 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the helper functions available to a log record template
+var templateFuncs = template.FuncMap{
+	// severity returns the canonical severity of a record, such as "error"
+	"severity": func(obj map[string]interface{}) string {
+		return normalizeSeverity(obj).String()
+	},
+	// timestamp returns the time of a record in the given layout, or an empty string if it has no valid time
+	"timestamp": func(obj map[string]interface{}, layout string) string {
+		t := newMQLogRecord(obj).Datetime()
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(layout)
+	},
+	// json encodes a value as JSON, so that it can be safely included in the output
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// getLogTemplate returns the template for rendering each log message in JSON format, or nil if
+// MQ_LOGGING_TEMPLATE isn't set.  The value is either the template itself, or the absolute path of a
+// file holding the template.  If the template isn't valid, a warning is logged, and nil is returned
+// so that the built-in format is used.
+func getLogTemplate() *template.Template {
+	text := os.Getenv("MQ_LOGGING_TEMPLATE")
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	if strings.HasPrefix(text, "/") {
+		// #nosec G304 - the path is provided by the operator, and the file is only read
+		b, err := os.ReadFile(text)
+		if err != nil {
+			log.Printf("Warning: Unable to read MQ_LOGGING_TEMPLATE, so using the built-in format: %v", err)
+			return nil
+		}
+		text = string(b)
+	}
+	t, err := template.New("MQ_LOGGING_TEMPLATE").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		log.Printf("Warning: Invalid MQ_LOGGING_TEMPLATE, so using the built-in format: %v", err)
+		return nil
+	}
+	return t
+}
+
+// renderTemplate applies a template to a log message in JSON format, after any fields have been added.
+// If the template fails, the original line is returned, so that the message isn't lost.
+func renderTemplate(t *template.Template, line string) string {
+	var obj map[string]interface{}
+	err := json.Unmarshal([]byte(line), &obj)
+	if err != nil {
+		log.Debugf("Unable to apply MQ_LOGGING_TEMPLATE: %v", err)
+		return line
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, obj)
+	if err != nil {
+		log.Debugf("Unable to apply MQ_LOGGING_TEMPLATE: %v", err)
+		return line
+	}
+	return strings.TrimRight(buf.String(), "\r\n")
+}
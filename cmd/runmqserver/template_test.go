@@ -0,0 +1,80 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogTemplate(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), "template.txt")
+	os.WriteFile(templateFile, []byte("{\"level\":{{json (severity .)}}}\n"), 0600)
+	msg := "{\"ibm_datetime\":\"2024-01-02T03:04:05.000Z\",\"ibm_messageId\":\"AMQ9209E\",\"loglevel\":\"ERROR\",\"message\":\"Connection closed\"}"
+	var tests = []struct {
+		name     string
+		template string
+		msg      string
+		expected string
+	}{
+		{
+			name:     "Sample",
+			template: "{\"when\":\"{{timestamp . \"2006-01-02 15:04:05\"}}\",\"level\":\"{{severity .}}\",\"id\":\"{{.ibm_messageId}}\",\"text\":{{json .message}},\"host\":\"{{.host}}\"}",
+			msg:      msg,
+			expected: "{\"when\":\"2024-01-02 03:04:05\",\"level\":\"error\",\"id\":\"AMQ9209E\",\"text\":\"Connection closed\",\"host\":\"mq1\"}\n",
+		},
+		{
+			name:     "File",
+			template: templateFile,
+			msg:      msg,
+			expected: "{\"level\":\"error\"}\n",
+		},
+		{
+			name:     "NotJSON",
+			template: "{\"text\":{{json .message}}}",
+			msg:      "Plain \"text\"",
+			expected: "{\"text\":\"Plain \\\"text\\\"\"}\n",
+		},
+		{
+			name:     "InvalidTemplate",
+			template: "{{.message",
+			msg:      msg,
+			expected: "{\"host\":\"mq1\",\"ibm_datetime\":\"2024-01-02T03:04:05.000Z\",\"ibm_messageId\":\"AMQ9209E\",\"loglevel\":\"ERROR\",\"message\":\"Connection closed\"}\n",
+		},
+		{
+			name:     "TemplateFails",
+			template: "{{template \"missing\"}}",
+			msg:      msg,
+			expected: "{\"host\":\"mq1\",\"ibm_datetime\":\"2024-01-02T03:04:05.000Z\",\"ibm_messageId\":\"AMQ9209E\",\"loglevel\":\"ERROR\",\"message\":\"Connection closed\"}\n",
+		},
+	}
+	for _, table := range tests {
+		t.Run(table.name, func(t *testing.T) {
+			t.Setenv("MQ_LOGGING_TEMPLATE", table.template)
+			t.Setenv("MQ_LOGGING_LABELS", "host=mq1")
+			opts, err := getMirrorOptions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := captureConsole(t)
+			newJSONMirrorFunc(opts)(table.msg, false)
+			if buf.String() != table.expected {
+				t.Errorf("Expected %q; got %q", table.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// truncatedRecords counts the JSON log records which were found to be truncated
+var truncatedRecords uint64
+
+// isTruncatedJSON returns true if a log record which failed to parse looks like a JSON record which
+// has been cut short, for example because of a limit on the length of a line.  The parser reaches the
+// end of the input while it is still expecting more, rather than finding invalid content.
+func isTruncatedJSON(msg string, err error) bool {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return false
+	}
+	trimmed := strings.TrimSpace(msg)
+	return strings.HasPrefix(trimmed, "{") && !strings.HasSuffix(trimmed, "}") && syntaxErr.Offset >= int64(len(msg))
+}
+
+// reportUnparseableRecord reports a log record which couldn't be parsed as JSON.  Truncated records
+// are emitted as a clearly marked event, including the content which was available.
+func reportUnparseableRecord(msg string, err error) {
+	if !isTruncatedJSON(msg, err) {
+		log.Printf("Failed to unmarshall JSON in log message - %v", msg)
+		return
+	}
+	count := atomic.AddUint64(&truncatedRecords, 1)
+	emitEvent("WARNING", "truncated_record", fmt.Sprintf("Truncated JSON log record (%v bytes): %v", len(msg), msg), map[string]interface{}{
+		"ibm_truncatedRecord": msg,
+		"ibm_truncatedCount":  count,
+	})
+}
@@ -0,0 +1,63 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+var truncatedJSONTests = []struct {
+	msg       string
+	truncated bool
+}{
+	{"{\"ibm_messageId\":\"AMQ9999E\",\"message\":\"AMQ9999E: Channel program ended abnorm", true},
+	{"{\"ibm_messageId\":\"AMQ9999E\",\"arithInsert1\":12", true},
+	{"{\"ibm_messageId\":\"AMQ9999E\" \"message\":\"Missing comma\"}", false},
+	{"{\"message\":\"Bad escape \\q\"}", false},
+}
+
+func TestIsTruncatedJSON(t *testing.T) {
+	for _, table := range truncatedJSONTests {
+		_, err := processLogMessage(table.msg)
+		if err == nil {
+			t.Fatalf("Expected %v to fail to parse", table.msg)
+		}
+		if isTruncatedJSON(table.msg, err) != table.truncated {
+			t.Errorf("isTruncatedJSON(%v) - expected %v", table.msg, table.truncated)
+		}
+	}
+}
+
+func TestReportTruncatedRecord(t *testing.T) {
+	oldJSON := eventsJSON
+	defer func() { eventsJSON = oldJSON }()
+	eventsJSON = true
+	captureLog(t)
+	buf := captureConsole(t)
+	before := atomic.LoadUint64(&truncatedRecords)
+	msg := truncatedJSONTests[0].msg
+	_, err := processLogMessage(msg)
+	reportUnparseableRecord(msg, err)
+	out := buf.String()
+	if !strings.Contains(out, "\"ibm_event\":\"truncated_record\"") || !strings.Contains(out, "Channel program ended abnorm") {
+		t.Errorf("Expected a truncated record event including the content; got %v", out)
+	}
+	if atomic.LoadUint64(&truncatedRecords) != before+1 {
+		t.Errorf("Expected truncated records to be counted")
+	}
+}
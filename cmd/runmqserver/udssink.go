@@ -0,0 +1,206 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultUDSSinkQueueSize = 10000
+
+// udsSinkWriteTimeout is the longest time to wait for the listener to accept a log message.  A listener
+// which has stopped reading would otherwise block the sink, and the shutdown of the container.
+var udsSinkWriteTimeout = 5 * time.Second
+
+// udsSink sends mirrored log messages to a Unix domain socket, as new-line delimited records.  Messages
+// are queued, so that a slow or missing listener doesn't block the mirroring of logs.  If the queue is
+// full, or the socket isn't available, messages are dropped.  The connection is re-established if it fails.
+type udsSink struct {
+	path        string
+	queue       chan string
+	done        chan struct{}
+	wg          sync.WaitGroup
+	closeOnce   sync.Once
+	dropped     uint64
+	conn        net.Conn
+	lastConnect time.Time
+	// failures is the number of consecutive failed attempts to connect
+	failures int
+	backoff  backoffPolicy
+	// statusMutex protects the state of the connection, for reporting the health of the sink.  The sink
+	// is only failing once an attempt to connect or send has failed, as for the HTTP sink.
+	statusMutex sync.Mutex
+	failing     bool
+	lastError   string
+}
+
+// newUDSSink creates a new Unix domain socket sink, and starts a goroutine to send messages to the socket
+func newUDSSink(path string, queueSize int) *udsSink {
+	u := &udsSink{
+		path:    path,
+		queue:   make(chan string, queueSize),
+		done:    make(chan struct{}),
+		backoff: sinkBackoff,
+	}
+	u.wg.Add(1)
+	go u.run()
+	return u
+}
+
+// Write queues a log message to be sent, without blocking
+func (u *udsSink) Write(line string) {
+	select {
+	case u.queue <- line:
+	default:
+		atomic.AddUint64(&u.dropped, 1)
+	}
+}
+
+// Close sends any queued messages, and stops the sink
+func (u *udsSink) Close() error {
+	u.closeOnce.Do(func() {
+		close(u.done)
+		u.wg.Wait()
+	})
+	if dropped := atomic.LoadUint64(&u.dropped); dropped > 0 {
+		log.Printf("Dropped %v log messages which could not be sent to %v", dropped, u.path)
+	}
+	return nil
+}
+
+func (u *udsSink) run() {
+	defer u.wg.Done()
+	defer func() {
+		if u.conn != nil {
+			// #nosec G104 - nothing useful can be done if closing the connection fails
+			u.conn.Close()
+		}
+	}()
+	for {
+		select {
+		case line := <-u.queue:
+			u.send(line)
+		case <-u.done:
+			// Drain anything left in the queue before finishing
+			for {
+				select {
+				case line := <-u.queue:
+					u.send(line)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// connect connects to the socket, if not already connected.  After a failure, attempts are limited by
+// the backoff policy, so that messages are dropped quickly while the socket isn't available.
+func (u *udsSink) connect() bool {
+	if u.conn != nil {
+		return true
+	}
+	if time.Since(u.lastConnect) < u.backoff.delay(u.failures) {
+		return false
+	}
+	u.lastConnect = time.Now()
+	conn, err := net.Dial("unix", u.path)
+	if err != nil {
+		log.Debugf("Unable to connect to log socket %v: %v", u.path, err)
+		u.failures++
+		u.setStatus(err)
+		return false
+	}
+	u.conn = conn
+	u.failures = 0
+	u.setStatus(nil)
+	return true
+}
+
+// setStatus records whether the socket is connected, and the error if it isn't
+func (u *udsSink) setStatus(err error) {
+	u.statusMutex.Lock()
+	defer u.statusMutex.Unlock()
+	u.failing = err != nil
+	if err != nil {
+		u.lastError = err.Error()
+	}
+}
+
+// health returns the state of the sink
+func (u *udsSink) health() sinkHealth {
+	u.statusMutex.Lock()
+	defer u.statusMutex.Unlock()
+	return sinkHealth{
+		Sink:      "uds " + u.path,
+		Connected: !u.failing,
+		Backlog:   len(u.queue),
+		Dropped:   atomic.LoadUint64(&u.dropped),
+		LastError: u.lastError,
+	}
+}
+
+// send writes a log message to the socket, reconnecting if the connection has failed, up to the
+// maximum number of attempts
+func (u *udsSink) send(line string) {
+	for attempt := 1; attempt <= u.backoff.maxAttempts; attempt++ {
+		if !u.connect() {
+			break
+		}
+		err := u.conn.SetWriteDeadline(time.Now().Add(udsSinkWriteTimeout))
+		if err == nil {
+			_, err = u.conn.Write([]byte(line))
+		}
+		if err == nil {
+			return
+		}
+		log.Debugf("Unable to send log message to %v: %v", u.path, err)
+		u.setStatus(err)
+		// #nosec G104 - the connection has already failed
+		u.conn.Close()
+		u.conn = nil
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			// The listener isn't reading, so back off rather than waiting for it again straight away
+			u.failures++
+			u.lastConnect = time.Now()
+			break
+		}
+		u.lastConnect = time.Time{}
+	}
+	atomic.AddUint64(&u.dropped, 1)
+}
+
+// configureUDSSink creates a Unix domain socket sink, if MQ_LOGGING_UDS_PATH is set.  If the socket
+// isn't available yet, a warning is logged, and messages are sent once it is.
+func configureUDSSink() (logSink, error) {
+	path := strings.TrimSpace(os.Getenv("MQ_LOGGING_UDS_PATH"))
+	if path == "" {
+		return nil, nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		log.Printf("Warning: %v is not a socket yet, so log messages will be dropped until it is available", path)
+	}
+	log.Printf("Sending mirrored log messages to %v", path)
+	return newUDSSink(path, defaultUDSSinkQueueSize), nil
+}
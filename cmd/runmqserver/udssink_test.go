@@ -0,0 +1,146 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenUDS creates a listener on a Unix domain socket, returning a channel of the lines received
+func listenUDS(t *testing.T, path string) chan string {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	lines := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+			conn.Close()
+		}
+	}()
+	return lines
+}
+
+// receiveLine waits for a line to be received by a listener
+func receiveLine(t *testing.T, lines chan string) string {
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for log message")
+	}
+	return ""
+}
+
+func TestUDSSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.socket")
+	lines := listenUDS(t, path)
+	u := newUDSSink(path, 10)
+	u.Write("{\"message\":\"A\"}\n")
+	u.Write("{\"message\":\"B\"}\n")
+	u.Close()
+	for _, expected := range []string{"{\"message\":\"A\"}", "{\"message\":\"B\"}"} {
+		if line := receiveLine(t, lines); line != expected {
+			t.Errorf("Expected %v; got %v", expected, line)
+		}
+	}
+}
+
+func TestUDSSinkHealthBeforeFirstAttempt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.socket")
+	u := newUDSSink(path, 10)
+	defer u.Close()
+	// Nothing has been sent yet, so the sink isn't reported as failing
+	if h := u.health(); !h.Connected || h.LastError != "" {
+		t.Errorf("Expected a sink which hasn't been used yet to be reported as connected; got %+v", h)
+	}
+}
+
+func TestUDSSinkReconnect(t *testing.T) {
+	oldBackoff := sinkBackoff
+	defer func() { sinkBackoff = oldBackoff }()
+	sinkBackoff = backoffPolicy{initialDelay: 10 * time.Millisecond, maxDelay: 10 * time.Millisecond, maxAttempts: 2}
+	path := filepath.Join(t.TempDir(), "log.socket")
+	u := newUDSSink(path, 10)
+	defer u.Close()
+	// The socket isn't available yet, so the message is dropped
+	u.Write("{\"message\":\"A\"}\n")
+	time.Sleep(50 * time.Millisecond)
+	lines := listenUDS(t, path)
+	// Keep writing until a message gets through, after reconnecting
+	deadline := time.Now().Add(5 * time.Second)
+	for len(lines) == 0 && time.Now().Before(deadline) {
+		u.Write("{\"message\":\"B\"}\n")
+		time.Sleep(20 * time.Millisecond)
+	}
+	if line := receiveLine(t, lines); line != "{\"message\":\"B\"}" {
+		t.Errorf("Expected a message after the socket became available; got %v", line)
+	}
+}
+
+func TestUDSSinkListenerNotReading(t *testing.T) {
+	oldTimeout := udsSinkWriteTimeout
+	udsSinkWriteTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { udsSinkWriteTimeout = oldTimeout })
+	path := filepath.Join(t.TempDir(), "log.socket")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		// Accept connections, but never read from them
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	u := newUDSSink(path, 100)
+	line := strings.Repeat("x", 64*1024) + "\n"
+	for i := 0; i < 100; i++ {
+		u.Write(line)
+	}
+	closed := make(chan struct{})
+	go func() {
+		u.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the sink to close")
+	}
+	if h := u.health(); h.Dropped == 0 || h.Connected {
+		t.Errorf("Expected messages to be dropped, and the sink to be disconnected; got %+v", h)
+	}
+}
@@ -32,6 +32,8 @@ var (
 	ImageSource = "Not specified"
 	// ImageTag is the tag of the image
 	ImageTag = "Not specified"
+	// BuildVersion is the version of runmqserver, including the source control commit it was built from
+	BuildVersion = "unknown"
 )
 
 func logDateStamp() {
@@ -50,6 +52,10 @@ func logImageTag() {
 	log.Printf("Image tag: %v", ImageTag)
 }
 
+func logBuildVersion() {
+	log.Printf("runmqserver build: %v", BuildVersion)
+}
+
 func logMQVersion() {
 	mqVersion, err := mqversion.Get()
 	if err != nil {
@@ -75,5 +81,6 @@ func logVersionInfo() {
 	logGitRepo()
 	logGitCommit()
 	logImageTag()
+	logBuildVersion()
 	logMQVersion()
 }
@@ -0,0 +1,38 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogBuildVersion(t *testing.T) {
+	buf := captureLog(t)
+	logBuildVersion()
+	if !strings.Contains(buf.String(), "runmqserver build: unknown") {
+		t.Errorf("Expected default build version in banner, got %v", buf.String())
+	}
+
+	oldVersion := BuildVersion
+	defer func() { BuildVersion = oldVersion }()
+	BuildVersion = "9.3.5.0-1-gabc1234"
+	buf.Reset()
+	logBuildVersion()
+	if !strings.Contains(buf.String(), "runmqserver build: 9.3.5.0-1-gabc1234") {
+		t.Errorf("Expected build version in banner, got %v", buf.String())
+	}
+}
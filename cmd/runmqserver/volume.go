@@ -0,0 +1,76 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// logVolumeWindow is the period over which the rate of mirrored log data is averaged
+const logVolumeWindow = 60
+
+// mirroredVolume tracks the rate at which log data is being mirrored
+var mirroredVolume = newVolumeEstimator(logVolumeWindow)
+
+// volumeEstimator keeps a rolling estimate of bytes per second, using one bucket per second of the window
+type volumeEstimator struct {
+	mutex   sync.Mutex
+	bytes   []int64
+	seconds []int64
+}
+
+func newVolumeEstimator(windowSeconds int) *volumeEstimator {
+	return &volumeEstimator{
+		bytes:   make([]int64, windowSeconds),
+		seconds: make([]int64, windowSeconds),
+	}
+}
+
+// record adds a number of bytes emitted at the given time
+func (v *volumeEstimator) record(now time.Time, n int) {
+	sec := now.Unix()
+	i := int(sec % int64(len(v.bytes)))
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.seconds[i] != sec {
+		// This bucket was last used for an earlier second, which is now outside the window
+		v.seconds[i] = sec
+		v.bytes[i] = 0
+	}
+	v.bytes[i] += int64(n)
+}
+
+// rate returns the average number of bytes per second emitted over the window ending at the given time
+func (v *volumeEstimator) rate(now time.Time) float64 {
+	sec := now.Unix()
+	window := int64(len(v.bytes))
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	var total int64
+	for i, s := range v.seconds {
+		if age := sec - s; age >= 0 && age < window {
+			total += v.bytes[i]
+		}
+	}
+	return float64(total) / float64(window)
+}
+
+// getLogVolumeRate returns the estimated number of bytes of log data mirrored per second, averaged
+// over the last minute.  This can be used to size log collection infrastructure.
+func getLogVolumeRate() float64 {
+	return mirroredVolume.rate(timeNow())
+}
@@ -0,0 +1,63 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVolumeEstimator(t *testing.T) {
+	v := newVolumeEstimator(10)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if r := v.rate(start); r != 0 {
+		t.Errorf("Expected no volume before anything is recorded; got %v", r)
+	}
+	// 100 bytes per second, for 10 seconds
+	for i := 0; i < 10; i++ {
+		v.record(start.Add(time.Duration(i)*time.Second), 60)
+		v.record(start.Add(time.Duration(i)*time.Second+500*time.Millisecond), 40)
+	}
+	if r := v.rate(start.Add(9 * time.Second)); r != 100 {
+		t.Errorf("Expected 100 bytes/sec; got %v", r)
+	}
+	// After another 5 quiet seconds, only half of the window has data
+	if r := v.rate(start.Add(14 * time.Second)); r != 50 {
+		t.Errorf("Expected 50 bytes/sec; got %v", r)
+	}
+	// Old buckets are reused for new data
+	v.record(start.Add(14*time.Second), 1000)
+	if r := v.rate(start.Add(14 * time.Second)); r != 150 {
+		t.Errorf("Expected 150 bytes/sec; got %v", r)
+	}
+	if r := v.rate(start.Add(time.Minute)); r != 0 {
+		t.Errorf("Expected no volume once the window has passed; got %v", r)
+	}
+}
+
+func TestLogVolumeRate(t *testing.T) {
+	oldNow, oldVolume := timeNow, mirroredVolume
+	defer func() { timeNow, mirroredVolume = oldNow, oldVolume }()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	mirroredVolume = newVolumeEstimator(logVolumeWindow)
+	captureConsole(t)
+	emitMirroredLine("", nil, "{\"message\":\"A\"}\n")
+	expected := float64(len("{\"message\":\"A\"}\n")) / logVolumeWindow
+	if r := getLogVolumeRate(); r != expected {
+		t.Errorf("Expected %v bytes/sec; got %v", expected, r)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// timeWindow is a daily time-of-day window, in minutes since midnight (local time).  If end is
+// before start, the window spans midnight.
+type timeWindow struct {
+	start int
+	end   int
+}
+
+// parseTimeOfDay parses a time of day in the form "HH:MM", returning minutes since midnight
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseTimeWindow parses a time-of-day window in the form "HH:MM-HH:MM"
+func parseTimeWindow(s string) (*timeWindow, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("time window %q is not in the form HH:MM-HH:MM", s)
+	}
+	start, err := parseTimeOfDay(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("time window %q is not in the form HH:MM-HH:MM", s)
+	}
+	end, err := parseTimeOfDay(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("time window %q is not in the form HH:MM-HH:MM", s)
+	}
+	return &timeWindow{start: start, end: end}, nil
+}
+
+// contains returns true if the time falls within the window.  The start of the window is
+// inclusive, and the end is exclusive.
+func (w *timeWindow) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.start <= w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// getVerboseWindow reads the verbose mirroring window, and the minimum level to mirror outside it
+func getVerboseWindow() (*timeWindow, logLevel, error) {
+	s := strings.TrimSpace(os.Getenv("MQ_LOGGING_VERBOSE_WINDOW"))
+	if s == "" {
+		return nil, levelDebug, nil
+	}
+	w, err := parseTimeWindow(s)
+	if err != nil {
+		return nil, levelDebug, fmt.Errorf("invalid value for MQ_LOGGING_VERBOSE_WINDOW: %v", err)
+	}
+	quietLevel := levelWarning
+	if l := os.Getenv("MQ_LOGGING_QUIET_LOG_LEVEL"); l != "" {
+		quietLevel, err = parseLogLevel(l)
+		if err != nil {
+			return nil, levelDebug, fmt.Errorf("invalid value for MQ_LOGGING_QUIET_LOG_LEVEL: %v", err)
+		}
+	}
+	return w, quietLevel, nil
+}
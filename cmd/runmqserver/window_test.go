@@ -0,0 +1,81 @@
+/*
+© Copyright IBM Corporation 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+var timeWindowTests = []struct {
+	window   string
+	at       string
+	expected bool
+}{
+	{"08:00-18:00", "07:59", false},
+	{"08:00-18:00", "08:00", true},
+	{"08:00-18:00", "17:59", true},
+	{"08:00-18:00", "18:00", false},
+	{"22:00-06:00", "23:30", true},
+	{"22:00-06:00", "05:59", true},
+	{"22:00-06:00", "12:00", false},
+}
+
+func TestTimeWindowContains(t *testing.T) {
+	for _, table := range timeWindowTests {
+		w, err := parseTimeWindow(table.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		at, _ := time.Parse("15:04", table.at)
+		if w.contains(at) != table.expected {
+			t.Errorf("Expected window %v to contain %v: %v", table.window, table.at, table.expected)
+		}
+	}
+}
+
+func TestParseTimeWindowInvalid(t *testing.T) {
+	for _, s := range []string{"08:00", "8am-6pm", "08:00-25:00", ""} {
+		_, err := parseTimeWindow(s)
+		if err == nil {
+			t.Errorf("Expected error parsing time window %q", s)
+		}
+	}
+}
+
+func TestVerboseWindowBoundary(t *testing.T) {
+	oldNow := timeNow
+	defer func() { timeNow = oldNow }()
+	t.Setenv("MQ_LOGGING_VERBOSE_WINDOW", "08:00-18:00")
+	opts, err := getMirrorOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := map[string]interface{}{"loglevel": "INFO", "message": "AMQ5051I"}
+	warning := map[string]interface{}{"loglevel": "WARNING", "message": "AMQ9999W"}
+
+	timeNow = func() time.Time { return time.Date(2024, 1, 1, 17, 59, 0, 0, time.Local) }
+	if isFilteredRecord(info, opts) || isFilteredRecord(warning, opts) {
+		t.Errorf("Expected all messages to be mirrored inside the window")
+	}
+	timeNow = func() time.Time { return time.Date(2024, 1, 1, 18, 0, 0, 0, time.Local) }
+	if !isFilteredRecord(info, opts) {
+		t.Errorf("Expected informational message to be filtered outside the window")
+	}
+	if isFilteredRecord(warning, opts) {
+		t.Errorf("Expected warning message to be mirrored outside the window")
+	}
+}